@@ -0,0 +1,158 @@
+package handler
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/fahmilukis/go-product-svc/domain"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+type AttributeHandler struct {
+	AttributeUC domain.AttributeUsecase
+}
+
+func AttributeRoute(a *fiber.App, auc domain.AttributeUsecase) {
+	handler := &AttributeHandler{
+		AttributeUC: auc,
+	}
+
+	route := a.Group("/api/v1")
+
+	route.Post("/attribute", handler.CreateAttribute)
+	route.Get("/attribute", handler.GetListAttributes)
+	route.Get("/attribute/:id", handler.GetAttributeDetail)
+	route.Put("/attribute/:id", handler.UpdateAttribute)
+	route.Delete("/attribute/:id", handler.DeleteAttribute)
+	route.Get("/product/:id/attributes", handler.GetProductAttributes)
+}
+
+// CreateAttribute attaches a definition to a category with category_id or to
+// a product type with product_type.
+func (h *AttributeHandler) CreateAttribute(c *fiber.Ctx) error {
+	d := &domain.AttributeDefinition{}
+	if err := c.BodyParser(d); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"status": false,
+			"msg":    err.Error(),
+		})
+	}
+	now := time.Now()
+
+	d.ID = uuid.NewString()
+	d.CreatedAt = now
+	d.UpdatedAt = now
+
+	if err := h.AttributeUC.Store(c.Context(), d); err != nil {
+		return c.Status(domain.StatusCode(err)).JSON(fiber.Map{
+			"status": false,
+			"msg":    err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"status": true,
+		"msg":    "success add attribute",
+		"data":   d,
+	})
+}
+
+// GetListAttributes lists the definitions, ?category_id= and ?product_type=
+// narrow them down.
+func (h *AttributeHandler) GetListAttributes(c *fiber.Ctx) error {
+	scope := domain.AttributeScope{}
+	if err := c.QueryParser(&scope); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"status": false,
+			"msg":    err.Error(),
+		})
+	}
+
+	data, err := h.AttributeUC.Fetch(c.Context(), scope)
+	if err != nil {
+		return c.Status(domain.StatusCode(err)).JSON(fiber.Map{
+			"status": false,
+			"msg":    err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"status": true,
+		"msg":    "success get attribute lists",
+		"data":   data,
+	})
+}
+
+func (h *AttributeHandler) GetAttributeDetail(c *fiber.Ctx) error {
+	data, err := h.AttributeUC.GetByID(c.Context(), c.Params("id"))
+	if err != nil {
+		return c.Status(domain.StatusCode(err)).JSON(fiber.Map{
+			"status": false,
+			"msg":    err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"status": true,
+		"msg":    "success get attribute",
+		"data":   data,
+	})
+}
+
+func (h *AttributeHandler) UpdateAttribute(c *fiber.Ctx) error {
+	d := &domain.AttributeDefinition{}
+	if err := c.BodyParser(d); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"status": false,
+			"msg":    err.Error(),
+		})
+	}
+	d.ID = c.Params("id")
+	d.UpdatedAt = time.Now()
+
+	if err := h.AttributeUC.Update(c.Context(), d); err != nil {
+		return c.Status(domain.StatusCode(err)).JSON(fiber.Map{
+			"status": false,
+			"msg":    err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"status": true,
+		"msg":    "success update attribute",
+		"data":   d,
+	})
+}
+
+func (h *AttributeHandler) DeleteAttribute(c *fiber.Ctx) error {
+	if err := h.AttributeUC.Delete(c.Context(), c.Params("id")); err != nil {
+		return c.Status(domain.StatusCode(err)).JSON(fiber.Map{
+			"status": false,
+			"msg":    err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"status": true,
+		"msg":    "success delete attribute",
+	})
+}
+
+// GetProductAttributes lists the definitions the attributes of the product
+// are validated against.
+func (h *AttributeHandler) GetProductAttributes(c *fiber.Ctx) error {
+	data, err := h.AttributeUC.FetchForProduct(c.Context(), c.Params("id"))
+	if err != nil {
+		return c.Status(domain.StatusCode(err)).JSON(fiber.Map{
+			"status": false,
+			"msg":    err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"status": true,
+		"msg":    "success get product attributes",
+		"data":   data,
+	})
+}
@@ -0,0 +1,160 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/fahmilukis/go-product-svc/domain"
+	pkg "github.com/fahmilukis/go-product-svc/pkg/utils"
+	"github.com/lib/pq"
+	"github.com/sirupsen/logrus"
+)
+
+const attributeColumns = `d.id,d.category_id,d.product_type,d.name,d.type,d.required,d.options,d.units,d.created_at,d.updated_at`
+
+type attributeDBRepositories struct {
+	Conn *sql.DB
+}
+
+func NewAttributeDBRepository(conn *sql.DB) *attributeDBRepositories {
+	return &attributeDBRepositories{Conn: conn}
+}
+
+// fetch to DB
+func (p *attributeDBRepositories) fetch(ctx context.Context, query string, args ...interface{}) (res []domain.AttributeDefinition, err error) {
+	rows, err := p.Conn.QueryContext(ctx, query, args...)
+	if err != nil {
+		logrus.Error(err)
+		return nil, err
+	}
+
+	defer func() {
+		errRow := rows.Close()
+		if errRow != nil {
+			logrus.Error(err)
+		}
+	}()
+
+	res = make([]domain.AttributeDefinition, 0)
+	for rows.Next() {
+		d := domain.AttributeDefinition{}
+		err = rows.Scan(
+			&d.ID,
+			&d.CategoryID,
+			&d.ProductType,
+			&d.Name,
+			&d.Type,
+			&d.Required,
+			pq.Array(&d.Options),
+			pq.Array(&d.Units),
+			&d.CreatedAt,
+			&d.UpdatedAt,
+		)
+		if err != nil {
+			logrus.Error(err)
+			return nil, err
+		}
+		res = append(res, d)
+	}
+
+	return res, nil
+}
+
+func (p *attributeDBRepositories) Fetch(ctx context.Context, scope domain.AttributeScope) (res []domain.AttributeDefinition, err error) {
+	query := `SELECT ` + attributeColumns + ` FROM attribute_definitions d
+	WHERE ($1 = '' OR d.category_id::text = $1) AND ($2 = '' OR d.product_type = $2)
+	ORDER BY d.product_type NULLS LAST, d.category_id, d.name`
+	return p.fetch(ctx, query, scope.CategoryID, scope.ProductType)
+}
+
+func (p *attributeDBRepositories) GetByID(ctx context.Context, id string) (res domain.AttributeDefinition, err error) {
+	query := `SELECT ` + attributeColumns + ` FROM attribute_definitions d WHERE d.id=$1`
+	list, err := p.fetch(ctx, query, id)
+	if err != nil {
+		return domain.AttributeDefinition{}, err
+	}
+
+	if len(list) > 0 {
+		res = list[0]
+	} else {
+		return res, domain.ErrNotFound
+	}
+
+	return
+}
+
+// FetchApplicable matches the categories of the product against the
+// definition categories by path prefix, so a definition also covers the
+// categories below its own.
+func (p *attributeDBRepositories) FetchApplicable(ctx context.Context, productID, productType string) (res []domain.AttributeDefinition, err error) {
+	if productID == "" {
+		query := `SELECT ` + attributeColumns + ` FROM attribute_definitions d WHERE d.product_type = $1 ORDER BY d.name`
+		return p.fetch(ctx, query, productType)
+	}
+
+	query := `SELECT ` + attributeColumns + ` FROM attribute_definitions d
+	WHERE d.product_type = $1 OR d.category_id IN (
+		SELECT a.id FROM categories a
+		JOIN categories c ON c.path LIKE a.path || '%'
+		JOIN product_categories pc ON pc.category_id = c.id
+		WHERE pc.product_id = $2
+	) ORDER BY d.name`
+	return p.fetch(ctx, query, productType, productID)
+}
+
+func (p *attributeDBRepositories) Store(ctx context.Context, d *domain.AttributeDefinition) (err error) {
+	query := `INSERT INTO attribute_definitions (id,category_id,product_type,name,type,required,options,units,created_at,updated_at)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)`
+	_, err = p.Conn.ExecContext(ctx, query, d.ID, d.CategoryID, d.ProductType, d.Name, d.Type, d.Required,
+		pq.Array(d.Options), pq.Array(d.Units), d.CreatedAt, d.UpdatedAt)
+	return attributeWriteError(err)
+}
+
+// Update changes the definition but not what it is attached to.
+func (p *attributeDBRepositories) Update(ctx context.Context, d *domain.AttributeDefinition) (err error) {
+	query := `UPDATE attribute_definitions SET name=$1 , type=$2 , required=$3 , options=$4 , units=$5 , updated_at=$6 WHERE id=$7`
+	res, err := p.Conn.ExecContext(ctx, query, d.Name, d.Type, d.Required, pq.Array(d.Options), pq.Array(d.Units), d.UpdatedAt, d.ID)
+	if err != nil {
+		return attributeWriteError(err)
+	}
+
+	rowsAfected, err := res.RowsAffected()
+	if err != nil {
+		return
+	}
+	if rowsAfected == 0 {
+		return domain.ErrNotFound
+	}
+
+	return
+}
+
+func (p *attributeDBRepositories) Delete(ctx context.Context, id string) (err error) {
+	res, err := p.Conn.ExecContext(ctx, `DELETE FROM attribute_definitions WHERE id = $1`, id)
+	if err != nil {
+		return
+	}
+
+	rowsAfected, err := res.RowsAffected()
+	if err != nil {
+		return
+	}
+	if rowsAfected == 0 {
+		return domain.ErrNotFound
+	}
+
+	return
+}
+
+// attributeWriteError maps a name already taken in the same scope to
+// ErrConflict and an unknown category to ErrNotFound.
+func attributeWriteError(err error) error {
+	switch {
+	case pkg.IsUniqueViolation(err):
+		return domain.ErrConflict
+	case pkg.IsForeignKeyViolation(err):
+		return domain.ErrNotFound
+	default:
+		return err
+	}
+}
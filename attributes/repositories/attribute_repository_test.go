@@ -0,0 +1,105 @@
+package repositories_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/fahmilukis/go-product-svc/attributes/repositories"
+	"github.com/fahmilukis/go-product-svc/domain"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/DATA-DOG/go-sqlmock.v1"
+)
+
+var attributeRows = []string{"id", "category_id", "product_type", "name", "type", "required", "options", "units", "created_at", "updated_at"}
+
+func TestFetchApplicableRepositoryAttribute(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	now := time.Now()
+
+	rows := sqlmock.NewRows(attributeRows).
+		AddRow("a1", "c1", nil, "color", "enum", true, "{red,blue}", "{}", now, now).
+		AddRow("a2", nil, "shirt", "weight", "unit", false, "{}", "{kg,g}", now, now)
+
+	mock.ExpectQuery(`FROM attribute_definitions d\s+WHERE d.product_type = \$1 OR d.category_id IN \(`).
+		WithArgs("shirt", "p1").WillReturnRows(rows)
+
+	a := repositories.NewAttributeDBRepository(db)
+	list, err := a.FetchApplicable(context.TODO(), "p1", "shirt")
+	assert.NoError(t, err)
+	assert.Len(t, list, 2)
+	assert.Equal(t, []string{"red", "blue"}, list[0].Options)
+	assert.Equal(t, []string{"kg", "g"}, list[1].Units)
+	assert.Equal(t, "shirt", *list[1].ProductType)
+	assert.NoError(t, mock.ExpectationsWereMet())
+
+	assert.NoError(t, domain.ValidateAttributes(list, map[string]interface{}{
+		"color":  "blue",
+		"weight": map[string]interface{}{"value": 1.5, "unit": "kg"},
+		"fabric": "cotton",
+	}))
+	assert.Equal(t, domain.ErrBadParamInput, domain.ValidateAttributes(list, map[string]interface{}{"color": "green"}))
+	assert.Equal(t, domain.ErrBadParamInput, domain.ValidateAttributes(list, map[string]interface{}{"weight": 2.0}))
+}
+
+func TestFetchApplicableRepositoryAttributeNewProduct(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	mock.ExpectQuery(`FROM attribute_definitions d WHERE d.product_type = \$1 ORDER BY d.name`).
+		WithArgs("shirt").WillReturnRows(sqlmock.NewRows(attributeRows))
+
+	a := repositories.NewAttributeDBRepository(db)
+	list, err := a.FetchApplicable(context.TODO(), "", "shirt")
+	assert.NoError(t, err)
+	assert.Len(t, list, 0)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestStoreRepositoryAttributeDuplicateName(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	now := time.Now()
+	productType := "shirt"
+	d := &domain.AttributeDefinition{
+		ID:          "a1",
+		ProductType: &productType,
+		Name:        "color",
+		Type:        domain.AttributeString,
+		Options:     []string{},
+		Units:       []string{},
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+
+	mock.ExpectExec(`INSERT INTO attribute_definitions`).
+		WillReturnError(&pq.Error{Code: "23505"})
+
+	a := repositories.NewAttributeDBRepository(db)
+	err = a.Store(context.TODO(), d)
+	assert.Equal(t, domain.ErrConflict, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDeleteRepositoryAttributeNotFound(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	mock.ExpectExec(`DELETE FROM attribute_definitions WHERE id = \$1`).
+		WithArgs("a1").WillReturnResult(sqlmock.NewResult(0, 0))
+
+	a := repositories.NewAttributeDBRepository(db)
+	err = a.Delete(context.TODO(), "a1")
+	assert.Equal(t, domain.ErrNotFound, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
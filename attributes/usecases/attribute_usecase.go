@@ -0,0 +1,121 @@
+package usecases
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/fahmilukis/go-product-svc/domain"
+)
+
+type attributeUsecase struct {
+	productRepository   domain.ProductRepository
+	attributeRepository domain.AttributeRepository
+	ctxTimeout          time.Duration
+}
+
+func NewAttributeUsecase(p domain.ProductRepository, a domain.AttributeRepository, to time.Duration) domain.AttributeUsecase {
+	return &attributeUsecase{
+		productRepository:   p,
+		attributeRepository: a,
+		ctxTimeout:          to,
+	}
+}
+
+func (u *attributeUsecase) Fetch(c context.Context, scope domain.AttributeScope) (res []domain.AttributeDefinition, err error) {
+	ctx, cancel := context.WithTimeout(c, u.ctxTimeout)
+	defer cancel()
+
+	return u.attributeRepository.Fetch(ctx, scope)
+}
+
+func (u *attributeUsecase) GetByID(c context.Context, id string) (res domain.AttributeDefinition, err error) {
+	ctx, cancel := context.WithTimeout(c, u.ctxTimeout)
+	defer cancel()
+
+	return u.attributeRepository.GetByID(ctx, id)
+}
+
+func (u *attributeUsecase) FetchForProduct(c context.Context, productID string) (res []domain.AttributeDefinition, err error) {
+	ctx, cancel := context.WithTimeout(c, u.ctxTimeout)
+	defer cancel()
+
+	prd, err := u.productRepository.GetByID(ctx, productID, domain.ProductFilter{})
+	if err != nil {
+		return nil, err
+	}
+
+	return u.attributeRepository.FetchApplicable(ctx, prd.ID, prd.ProductType)
+}
+
+func (u *attributeUsecase) Store(c context.Context, d *domain.AttributeDefinition) (err error) {
+	ctx, cancel := context.WithTimeout(c, u.ctxTimeout)
+	defer cancel()
+
+	d.CategoryID = normalizeScope(d.CategoryID)
+	d.ProductType = normalizeScope(d.ProductType)
+	if (d.CategoryID == nil) == (d.ProductType == nil) {
+		return domain.ErrBadParamInput
+	}
+	if err = validateDefinition(d); err != nil {
+		return
+	}
+
+	return u.attributeRepository.Store(ctx, d)
+}
+
+// Update keeps the scope of the definition, the values already stored on the
+// products are only checked against it on their next write.
+func (u *attributeUsecase) Update(c context.Context, d *domain.AttributeDefinition) (err error) {
+	ctx, cancel := context.WithTimeout(c, u.ctxTimeout)
+	defer cancel()
+
+	if err = validateDefinition(d); err != nil {
+		return
+	}
+	cur, err := u.attributeRepository.GetByID(ctx, d.ID)
+	if err != nil {
+		return
+	}
+	d.CategoryID, d.ProductType, d.CreatedAt = cur.CategoryID, cur.ProductType, cur.CreatedAt
+
+	return u.attributeRepository.Update(ctx, d)
+}
+
+func (u *attributeUsecase) Delete(c context.Context, id string) (err error) {
+	ctx, cancel := context.WithTimeout(c, u.ctxTimeout)
+	defer cancel()
+
+	return u.attributeRepository.Delete(ctx, id)
+}
+
+// validateDefinition wants a name, a known type and the options of an enum
+// or the units of a unit attribute.
+func validateDefinition(d *domain.AttributeDefinition) error {
+	d.Name = strings.TrimSpace(d.Name)
+	if d.Name == "" || len(d.Name) > 64 || !d.Type.Valid() {
+		return domain.ErrBadParamInput
+	}
+	if d.Type == domain.AttributeEnum && len(d.Options) == 0 {
+		return domain.ErrBadParamInput
+	}
+	if d.Type == domain.AttributeUnit && len(d.Units) == 0 {
+		return domain.ErrBadParamInput
+	}
+	if d.Options == nil {
+		d.Options = []string{}
+	}
+	if d.Units == nil {
+		d.Units = []string{}
+	}
+	return nil
+}
+
+// normalizeScope treats a blank category or product type as not set.
+func normalizeScope(v *string) *string {
+	if v == nil || strings.TrimSpace(*v) == "" {
+		return nil
+	}
+	trimmed := strings.TrimSpace(*v)
+	return &trimmed
+}
@@ -0,0 +1,247 @@
+package handler
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/fahmilukis/go-product-svc/domain"
+	pkg "github.com/fahmilukis/go-product-svc/pkg/utils"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+type CategoryHandler struct {
+	CategoryUC domain.CategoryUsecase
+	ProductUC  domain.ProductUsecase
+}
+
+type assignProductsRequest struct {
+	ProductIDs []string `json:"product_ids"`
+}
+
+func CategoryRoute(a *fiber.App, cuc domain.CategoryUsecase, puc domain.ProductUsecase) {
+	handler := &CategoryHandler{
+		CategoryUC: cuc,
+		ProductUC:  puc,
+	}
+
+	route := a.Group("/api/v1")
+
+	route.Post("/category", handler.CreateCategory)
+	route.Get("/category", handler.GetListCategories)
+	route.Get("/category/:id", handler.GetCategoryDetail)
+	route.Put("/category/:id", handler.UpdateCategory)
+	route.Delete("/category/:id", handler.DeleteCategory)
+	route.Get("/category/:id/children", handler.GetCategoryChildren)
+	route.Get("/category/:id/products", handler.GetCategoryProducts)
+	route.Post("/category/:id/products", handler.AssignProducts)
+	route.Delete("/category/:id/products/:product_id", handler.UnassignProduct)
+	route.Get("/product/:id/categories", handler.GetProductCategories)
+}
+
+func (h *CategoryHandler) CreateCategory(c *fiber.Ctx) error {
+	cat := &domain.Category{}
+	if err := c.BodyParser(cat); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"status": false,
+			"msg":    err.Error(),
+		})
+	}
+	now := time.Now()
+
+	cat.ID = uuid.NewString()
+	cat.CreatedAt = now
+	cat.UpdatedAt = now
+
+	if err := h.CategoryUC.Store(c.Context(), cat); err != nil {
+		return c.Status(domain.StatusCode(err)).JSON(fiber.Map{
+			"status": false,
+			"msg":    err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"status": true,
+		"msg":    "success add category",
+		"data":   cat,
+	})
+}
+
+// GetListCategories returns the whole tree flattened, every category comes
+// right after its parent.
+func (h *CategoryHandler) GetListCategories(c *fiber.Ctx) error {
+	data, err := h.CategoryUC.Fetch(c.Context())
+	if err != nil {
+		return c.Status(domain.StatusCode(err)).JSON(fiber.Map{
+			"status": false,
+			"msg":    err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"status": true,
+		"msg":    "success get category lists",
+		"data":   data,
+	})
+}
+
+func (h *CategoryHandler) GetCategoryDetail(c *fiber.Ctx) error {
+	data, err := h.CategoryUC.GetByID(c.Context(), c.Params("id"))
+	if err != nil {
+		return c.Status(domain.StatusCode(err)).JSON(fiber.Map{
+			"status": false,
+			"msg":    err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"status": true,
+		"msg":    "success get category",
+		"data":   data,
+	})
+}
+
+// UpdateCategory renames a category, a different parent_id moves it together
+// with its subtree.
+func (h *CategoryHandler) UpdateCategory(c *fiber.Ctx) error {
+	cat := &domain.Category{}
+	if err := c.BodyParser(cat); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"status": false,
+			"msg":    err.Error(),
+		})
+	}
+	cat.ID = c.Params("id")
+	cat.UpdatedAt = time.Now()
+
+	if err := h.CategoryUC.Update(c.Context(), cat); err != nil {
+		return c.Status(domain.StatusCode(err)).JSON(fiber.Map{
+			"status": false,
+			"msg":    err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"status": true,
+		"msg":    "success update category",
+		"data":   cat,
+	})
+}
+
+func (h *CategoryHandler) DeleteCategory(c *fiber.Ctx) error {
+	if err := h.CategoryUC.Delete(c.Context(), c.Params("id")); err != nil {
+		return c.Status(domain.StatusCode(err)).JSON(fiber.Map{
+			"status": false,
+			"msg":    err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"status": true,
+		"msg":    "success delete category",
+	})
+}
+
+func (h *CategoryHandler) GetCategoryChildren(c *fiber.Ctx) error {
+	data, err := h.CategoryUC.GetChildren(c.Context(), c.Params("id"))
+	if err != nil {
+		return c.Status(domain.StatusCode(err)).JSON(fiber.Map{
+			"status": false,
+			"msg":    err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"status": true,
+		"msg":    "success get category children",
+		"data":   data,
+	})
+}
+
+// GetCategoryProducts lists the products of the category and of every
+// category below it. Like the product list it only shows the published
+// products, ?admin=true shows them in every status.
+func (h *CategoryHandler) GetCategoryProducts(c *fiber.Ctx) error {
+	params := &pkg.Pagination{}
+	if err := c.QueryParser(params); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"status": false,
+			"msg":    err.Error(),
+		})
+	}
+	if _, err := h.CategoryUC.GetByID(c.Context(), c.Params("id")); err != nil {
+		return c.Status(domain.StatusCode(err)).JSON(fiber.Map{
+			"status": false,
+			"msg":    err.Error(),
+		})
+	}
+
+	filter := domain.ProductFilter{CategoryID: c.Params("id")}
+	filter.PublishedOnly = c.Query("admin") != "true"
+	data, nextPagination, err := h.ProductUC.Fetch(c.Context(), filter, *params)
+	if err != nil {
+		return c.Status(domain.StatusCode(err)).JSON(fiber.Map{
+			"status": false,
+			"msg":    err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"status": true,
+		"msg":    "success get category products",
+		"data":   data,
+		"meta":   nextPagination,
+	})
+}
+
+func (h *CategoryHandler) AssignProducts(c *fiber.Ctx) error {
+	req := &assignProductsRequest{}
+	if err := c.BodyParser(req); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"status": false,
+			"msg":    err.Error(),
+		})
+	}
+
+	if err := h.CategoryUC.AssignProducts(c.Context(), c.Params("id"), req.ProductIDs); err != nil {
+		return c.Status(domain.StatusCode(err)).JSON(fiber.Map{
+			"status": false,
+			"msg":    err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"status": true,
+		"msg":    "success assign products",
+	})
+}
+
+func (h *CategoryHandler) UnassignProduct(c *fiber.Ctx) error {
+	if err := h.CategoryUC.UnassignProduct(c.Context(), c.Params("id"), c.Params("product_id")); err != nil {
+		return c.Status(domain.StatusCode(err)).JSON(fiber.Map{
+			"status": false,
+			"msg":    err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"status": true,
+		"msg":    "success unassign product",
+	})
+}
+
+func (h *CategoryHandler) GetProductCategories(c *fiber.Ctx) error {
+	data, err := h.CategoryUC.GetByProduct(c.Context(), c.Params("id"))
+	if err != nil {
+		return c.Status(domain.StatusCode(err)).JSON(fiber.Map{
+			"status": false,
+			"msg":    err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"status": true,
+		"msg":    "success get product categories",
+		"data":   data,
+	})
+}
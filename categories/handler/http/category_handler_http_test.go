@@ -0,0 +1,53 @@
+package handler_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	handler "github.com/fahmilukis/go-product-svc/categories/handler/http"
+	"github.com/fahmilukis/go-product-svc/domain"
+	pkg "github.com/fahmilukis/go-product-svc/pkg/utils"
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+type categoryUsecase struct {
+	domain.CategoryUsecase
+}
+
+func (c *categoryUsecase) GetByID(ctx context.Context, id string) (domain.Category, error) {
+	return domain.Category{ID: id}, nil
+}
+
+// fetchUsecase records the filter of the product lists.
+type fetchUsecase struct {
+	domain.ProductUsecase
+	filters []domain.ProductFilter
+}
+
+func (f *fetchUsecase) Fetch(ctx context.Context, filter domain.ProductFilter, pagination pkg.Pagination) ([]domain.Products, pkg.Pagination, error) {
+	f.filters = append(f.filters, filter)
+	return []domain.Products{}, pkg.Pagination{}, nil
+}
+
+func TestGetCategoryProductsPublishedOnly(t *testing.T) {
+	puc := &fetchUsecase{}
+	app := fiber.New()
+	handler.CategoryRoute(app, &categoryUsecase{}, puc)
+
+	res, err := app.Test(httptest.NewRequest(http.MethodGet, "/api/v1/category/c1/products", nil))
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+
+	// the admins see the drafts and the archived products too
+	res, err = app.Test(httptest.NewRequest(http.MethodGet, "/api/v1/category/c1/products?admin=true", nil))
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+
+	assert.Len(t, puc.filters, 2)
+	assert.Equal(t, "c1", puc.filters[0].CategoryID)
+	assert.True(t, puc.filters[0].PublishedOnly)
+	assert.False(t, puc.filters[1].PublishedOnly)
+}
@@ -0,0 +1,184 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/fahmilukis/go-product-svc/domain"
+	pkg "github.com/fahmilukis/go-product-svc/pkg/utils"
+	"github.com/lib/pq"
+	"github.com/sirupsen/logrus"
+)
+
+const categoryColumns = `id,parent_id,name,path,created_at,updated_at`
+
+type categoryDBRepositories struct {
+	Conn *sql.DB
+}
+
+func NewCategoryDBRepository(conn *sql.DB) *categoryDBRepositories {
+	return &categoryDBRepositories{Conn: conn}
+}
+
+// fetch to DB
+func (p *categoryDBRepositories) fetch(ctx context.Context, query string, args ...interface{}) (res []domain.Category, err error) {
+	rows, err := p.Conn.QueryContext(ctx, query, args...)
+	if err != nil {
+		logrus.Error(err)
+		return nil, err
+	}
+
+	defer func() {
+		errRow := rows.Close()
+		if errRow != nil {
+			logrus.Error(err)
+		}
+	}()
+
+	res = make([]domain.Category, 0)
+	for rows.Next() {
+		c := domain.Category{}
+		err = rows.Scan(
+			&c.ID,
+			&c.ParentID,
+			&c.Name,
+			&c.Path,
+			&c.CreatedAt,
+			&c.UpdatedAt,
+		)
+		if err != nil {
+			logrus.Error(err)
+			return nil, err
+		}
+		res = append(res, c)
+	}
+
+	return res, nil
+}
+
+// Fetch returns the whole tree, ordered by path so every category follows
+// its parent.
+func (p *categoryDBRepositories) Fetch(ctx context.Context) (res []domain.Category, err error) {
+	query := `SELECT ` + categoryColumns + ` FROM categories ORDER BY path`
+	return p.fetch(ctx, query)
+}
+
+func (p *categoryDBRepositories) GetByID(ctx context.Context, id string) (res domain.Category, err error) {
+	query := `SELECT ` + categoryColumns + ` FROM categories WHERE id=$1`
+	list, err := p.fetch(ctx, query, id)
+	if err != nil {
+		return domain.Category{}, err
+	}
+
+	if len(list) > 0 {
+		res = list[0]
+	} else {
+		return res, domain.ErrNotFound
+	}
+
+	return
+}
+
+func (p *categoryDBRepositories) GetChildren(ctx context.Context, id string) (res []domain.Category, err error) {
+	query := `SELECT ` + categoryColumns + ` FROM categories WHERE parent_id=$1 ORDER BY name`
+	return p.fetch(ctx, query, id)
+}
+
+func (p *categoryDBRepositories) Store(ctx context.Context, c *domain.Category) (err error) {
+	query := `INSERT INTO categories (id,parent_id,name,path,created_at,updated_at) VALUES ($1, $2, $3, $4, $5, $6)`
+	_, err = p.Conn.ExecContext(ctx, query, c.ID, c.ParentID, c.Name, c.Path, c.CreatedAt, c.UpdatedAt)
+	return
+}
+
+func (p *categoryDBRepositories) Update(ctx context.Context, c *domain.Category) (err error) {
+	query := `UPDATE categories SET name=$1 , updated_at=$2 WHERE id=$3`
+	res, err := p.Conn.ExecContext(ctx, query, c.Name, c.UpdatedAt, c.ID)
+	if err != nil {
+		return
+	}
+
+	return requireAffected(res)
+}
+
+// Move rewrites the path prefix of the whole subtree in one transaction, the
+// product assignments point at category ids and are left untouched.
+func (p *categoryDBRepositories) Move(ctx context.Context, c *domain.Category, oldPath string) (err error) {
+	tx, err := p.Conn.BeginTx(ctx, nil)
+	if err != nil {
+		return
+	}
+	defer func() {
+		if err != nil {
+			if errRollback := tx.Rollback(); errRollback != nil {
+				logrus.Error(errRollback)
+			}
+		}
+	}()
+
+	res, err := tx.ExecContext(ctx, `UPDATE categories SET parent_id=$1 , name=$2 , updated_at=$3 WHERE id=$4`,
+		c.ParentID, c.Name, c.UpdatedAt, c.ID)
+	if err != nil {
+		return
+	}
+	if err = requireAffected(res); err != nil {
+		return
+	}
+
+	_, err = tx.ExecContext(ctx, `UPDATE categories SET path = $1 || substr(path, $2) , updated_at=$3 WHERE path LIKE $4`,
+		c.Path, len(oldPath)+1, c.UpdatedAt, oldPath+"%")
+	if err != nil {
+		return
+	}
+
+	return tx.Commit()
+}
+
+func (p *categoryDBRepositories) Delete(ctx context.Context, id string) (err error) {
+	res, err := p.Conn.ExecContext(ctx, `DELETE FROM categories WHERE id = $1`, id)
+	if err != nil {
+		return
+	}
+
+	return requireAffected(res)
+}
+
+// AssignProducts is idempotent, products already in the category are skipped.
+func (p *categoryDBRepositories) AssignProducts(ctx context.Context, categoryID string, productIDs []string) (err error) {
+	query := `INSERT INTO product_categories (product_id, category_id)
+	SELECT unnest($1::uuid[]), $2 ON CONFLICT DO NOTHING`
+	_, err = p.Conn.ExecContext(ctx, query, pq.Array(productIDs), categoryID)
+
+	// an unknown product or category breaks a foreign key
+	if pkg.IsForeignKeyViolation(err) {
+		return domain.ErrNotFound
+	}
+	return
+}
+
+func (p *categoryDBRepositories) UnassignProduct(ctx context.Context, categoryID, productID string) (err error) {
+	res, err := p.Conn.ExecContext(ctx, `DELETE FROM product_categories WHERE category_id = $1 AND product_id = $2`, categoryID, productID)
+	if err != nil {
+		return
+	}
+
+	return requireAffected(res)
+}
+
+func (p *categoryDBRepositories) GetByProduct(ctx context.Context, productID string) (res []domain.Category, err error) {
+	query := `SELECT c.id,c.parent_id,c.name,c.path,c.created_at,c.updated_at
+	FROM categories c JOIN product_categories pc ON pc.category_id = c.id
+	WHERE pc.product_id = $1 ORDER BY c.path`
+	return p.fetch(ctx, query, productID)
+}
+
+// requireAffected turns a statement that touched no row into ErrNotFound.
+func requireAffected(res sql.Result) error {
+	rowsAfected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAfected == 0 {
+		return domain.ErrNotFound
+	}
+	return nil
+}
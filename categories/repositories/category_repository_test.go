@@ -0,0 +1,133 @@
+package repositories_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/fahmilukis/go-product-svc/categories/repositories"
+	"github.com/fahmilukis/go-product-svc/domain"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/DATA-DOG/go-sqlmock.v1"
+)
+
+func TestFetchRepositoryCategory(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	now := time.Now()
+	parent := "root"
+
+	rows := sqlmock.NewRows([]string{"id", "parent_id", "name", "path", "created_at", "updated_at"}).
+		AddRow("root", nil, "Clothing", "/root/", now, now).
+		AddRow("shirts", parent, "Shirts", "/root/shirts/", now, now)
+
+	mock.ExpectQuery(`SELECT id,parent_id,name,path,created_at,updated_at FROM categories ORDER BY path`).WillReturnRows(rows)
+
+	a := repositories.NewCategoryDBRepository(db)
+	list, err := a.Fetch(context.TODO())
+	assert.NoError(t, err)
+	assert.Len(t, list, 2)
+	assert.Nil(t, list[0].ParentID)
+	assert.Equal(t, "root", *list[1].ParentID)
+}
+
+func TestGetByIdRepositoryCategoryNotFound(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	rows := sqlmock.NewRows([]string{"id", "parent_id", "name", "path", "created_at", "updated_at"})
+	mock.ExpectQuery(`FROM categories WHERE id=\$1`).WithArgs("missing").WillReturnRows(rows)
+
+	a := repositories.NewCategoryDBRepository(db)
+	_, err = a.GetByID(context.TODO(), "missing")
+	assert.Equal(t, domain.ErrNotFound, err)
+}
+
+func TestInsertRepositoryCategory(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	now := time.Now()
+	parent := "root"
+	cat := &domain.Category{
+		ID:        "shirts",
+		ParentID:  &parent,
+		Name:      "Shirts",
+		Path:      "/root/shirts/",
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+
+	mock.ExpectExec(`INSERT INTO categories`).
+		WithArgs(cat.ID, cat.ParentID, cat.Name, cat.Path, cat.CreatedAt, cat.UpdatedAt).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	a := repositories.NewCategoryDBRepository(db)
+	err = a.Store(context.TODO(), cat)
+	assert.NoError(t, err)
+}
+
+func TestMoveRepositoryCategory(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	now := time.Now()
+	parent := "sale"
+	cat := &domain.Category{
+		ID:        "shirts",
+		ParentID:  &parent,
+		Name:      "Shirts",
+		Path:      "/sale/shirts/",
+		UpdatedAt: now,
+	}
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`UPDATE categories SET parent_id=\$1 , name=\$2 , updated_at=\$3 WHERE id=\$4`).
+		WithArgs(cat.ParentID, cat.Name, cat.UpdatedAt, cat.ID).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`UPDATE categories SET path = \$1 \|\| substr\(path, \$2\) , updated_at=\$3 WHERE path LIKE \$4`).
+		WithArgs(cat.Path, len("/root/shirts/")+1, cat.UpdatedAt, "/root/shirts/%").
+		WillReturnResult(sqlmock.NewResult(0, 3))
+	mock.ExpectCommit()
+
+	a := repositories.NewCategoryDBRepository(db)
+	err = a.Move(context.TODO(), cat, "/root/shirts/")
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestMoveRepositoryCategoryNotFound(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	cat := &domain.Category{ID: "missing", Path: "/missing/"}
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`UPDATE categories SET parent_id`).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectRollback()
+
+	a := repositories.NewCategoryDBRepository(db)
+	err = a.Move(context.TODO(), cat, "/missing/")
+	assert.Equal(t, domain.ErrNotFound, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDeleteRepositoryCategory(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	mock.ExpectExec(`DELETE FROM categories WHERE id = \$1`).WithArgs("shirts").WillReturnResult(sqlmock.NewResult(0, 1))
+
+	a := repositories.NewCategoryDBRepository(db)
+	err = a.Delete(context.TODO(), "shirts")
+	assert.NoError(t, err)
+}
@@ -0,0 +1,167 @@
+package usecases
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/fahmilukis/go-product-svc/domain"
+)
+
+type categoryUsecase struct {
+	categoryRepository domain.CategoryRepository
+	ctxTimeout         time.Duration
+}
+
+func NewCategoryUsecase(c domain.CategoryRepository, to time.Duration) domain.CategoryUsecase {
+	return &categoryUsecase{
+		categoryRepository: c,
+		ctxTimeout:         to,
+	}
+}
+
+func (u *categoryUsecase) Fetch(c context.Context) (res []domain.Category, err error) {
+	ctx, cancel := context.WithTimeout(c, u.ctxTimeout)
+	defer cancel()
+
+	return u.categoryRepository.Fetch(ctx)
+}
+
+func (u *categoryUsecase) GetByID(c context.Context, id string) (res domain.Category, err error) {
+	ctx, cancel := context.WithTimeout(c, u.ctxTimeout)
+	defer cancel()
+
+	return u.categoryRepository.GetByID(ctx, id)
+}
+
+func (u *categoryUsecase) GetChildren(c context.Context, id string) (res []domain.Category, err error) {
+	ctx, cancel := context.WithTimeout(c, u.ctxTimeout)
+	defer cancel()
+
+	if _, err = u.categoryRepository.GetByID(ctx, id); err != nil {
+		return nil, err
+	}
+
+	return u.categoryRepository.GetChildren(ctx, id)
+}
+
+func (u *categoryUsecase) Store(c context.Context, cat *domain.Category) (err error) {
+	ctx, cancel := context.WithTimeout(c, u.ctxTimeout)
+	defer cancel()
+
+	cat.ParentID = normalizeParent(cat.ParentID)
+	parentPath, err := u.parentPath(ctx, cat.ParentID)
+	if err != nil {
+		return
+	}
+	cat.Path = domain.CategoryPath(parentPath, cat.ID)
+
+	return u.categoryRepository.Store(ctx, cat)
+}
+
+// Update renames the category and, when its parent changed, moves the whole
+// subtree below the new parent.
+func (u *categoryUsecase) Update(c context.Context, cat *domain.Category) (err error) {
+	ctx, cancel := context.WithTimeout(c, u.ctxTimeout)
+	defer cancel()
+
+	cur, err := u.categoryRepository.GetByID(ctx, cat.ID)
+	if err != nil {
+		return
+	}
+	cat.ParentID = normalizeParent(cat.ParentID)
+
+	if sameParent(cur.ParentID, cat.ParentID) {
+		if err = u.categoryRepository.Update(ctx, cat); err != nil {
+			return
+		}
+		cat.Path = cur.Path
+		return
+	}
+
+	parentPath, err := u.parentPath(ctx, cat.ParentID)
+	if err != nil {
+		return
+	}
+	// a category can't be moved below itself or one of its descendants
+	if strings.HasPrefix(parentPath, cur.Path) {
+		return domain.ErrBadParamInput
+	}
+	cat.Path = domain.CategoryPath(parentPath, cat.ID)
+
+	return u.categoryRepository.Move(ctx, cat, cur.Path)
+}
+
+// Delete only removes leaves, the children have to be moved or deleted first.
+func (u *categoryUsecase) Delete(c context.Context, id string) (err error) {
+	ctx, cancel := context.WithTimeout(c, u.ctxTimeout)
+	defer cancel()
+
+	children, err := u.categoryRepository.GetChildren(ctx, id)
+	if err != nil {
+		return
+	}
+	if len(children) > 0 {
+		return domain.ErrConflict
+	}
+
+	return u.categoryRepository.Delete(ctx, id)
+}
+
+func (u *categoryUsecase) AssignProducts(c context.Context, categoryID string, productIDs []string) (err error) {
+	ctx, cancel := context.WithTimeout(c, u.ctxTimeout)
+	defer cancel()
+
+	if len(productIDs) == 0 {
+		return domain.ErrBadParamInput
+	}
+
+	return u.categoryRepository.AssignProducts(ctx, categoryID, productIDs)
+}
+
+func (u *categoryUsecase) UnassignProduct(c context.Context, categoryID, productID string) (err error) {
+	ctx, cancel := context.WithTimeout(c, u.ctxTimeout)
+	defer cancel()
+
+	return u.categoryRepository.UnassignProduct(ctx, categoryID, productID)
+}
+
+func (u *categoryUsecase) GetByProduct(c context.Context, productID string) (res []domain.Category, err error) {
+	ctx, cancel := context.WithTimeout(c, u.ctxTimeout)
+	defer cancel()
+
+	return u.categoryRepository.GetByProduct(ctx, productID)
+}
+
+// parentPath returns the path of the parent category, or an empty path for a
+// root category.
+func (u *categoryUsecase) parentPath(ctx context.Context, parentID *string) (string, error) {
+	if parentID == nil {
+		return "", nil
+	}
+
+	parent, err := u.categoryRepository.GetByID(ctx, *parentID)
+	if err == domain.ErrNotFound {
+		return "", domain.ErrBadParamInput
+	}
+	if err != nil {
+		return "", err
+	}
+
+	return parent.Path, nil
+}
+
+// normalizeParent treats an empty parent id as no parent.
+func normalizeParent(parentID *string) *string {
+	if parentID != nil && *parentID == "" {
+		return nil
+	}
+	return parentID
+}
+
+func sameParent(a, b *string) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return *a == *b
+}
@@ -0,0 +1,129 @@
+package domain
+
+import (
+	"context"
+	"time"
+)
+
+type AttributeType string
+
+var (
+	AttributeString AttributeType = "string"
+	AttributeNumber AttributeType = "number"
+	AttributeBool   AttributeType = "bool"
+	// AttributeEnum takes one of the Options of its definition.
+	AttributeEnum AttributeType = "enum"
+	// AttributeUnit is a number with one of the Units of its definition,
+	// e.g. {"value": 1.5, "unit": "kg"}.
+	AttributeUnit AttributeType = "unit"
+)
+
+// Valid tells whether t is one of the known types.
+func (t AttributeType) Valid() bool {
+	switch t {
+	case AttributeString, AttributeNumber, AttributeBool, AttributeEnum, AttributeUnit:
+		return true
+	}
+	return false
+}
+
+// AttributeDefinition declares an attribute the products of a category, the
+// categories below it included, or of a product type have. Exactly one of
+// CategoryID and ProductType is set.
+type AttributeDefinition struct {
+	ID          string        `db:"id" json:"id"`
+	CategoryID  *string       `db:"category_id" json:"category_id"`
+	ProductType *string       `db:"product_type" json:"product_type"`
+	Name        string        `db:"name" json:"name" validate:"required,lte=64"`
+	Type        AttributeType `db:"type" json:"type"`
+	Required    bool          `db:"required" json:"required"`
+	Options     []string      `db:"options" json:"options"`
+	Units       []string      `db:"units" json:"units"`
+	CreatedAt   time.Time     `db:"created_at" json:"created_at"`
+	UpdatedAt   time.Time     `db:"updated_at" json:"updated_at"`
+}
+
+// Accepts tells whether v, as decoded from JSON, is a valid value of the
+// attribute.
+func (d AttributeDefinition) Accepts(v interface{}) bool {
+	switch d.Type {
+	case AttributeString:
+		_, ok := v.(string)
+		return ok
+	case AttributeNumber:
+		_, ok := v.(float64)
+		return ok
+	case AttributeBool:
+		_, ok := v.(bool)
+		return ok
+	case AttributeEnum:
+		s, ok := v.(string)
+		return ok && containsString(d.Options, s)
+	case AttributeUnit:
+		m, ok := v.(map[string]interface{})
+		if !ok || len(m) != 2 {
+			return false
+		}
+		_, isNumber := m["value"].(float64)
+		unit, _ := m["unit"].(string)
+		return isNumber && containsString(d.Units, unit)
+	}
+	return false
+}
+
+// ValidateAttributes checks the attribute values of a product against the
+// definitions that apply to it. Attributes without definition are free-form
+// and kept as they are.
+func ValidateAttributes(defs []AttributeDefinition, attrs map[string]interface{}) error {
+	for _, d := range defs {
+		v, ok := attrs[d.Name]
+		if !ok || v == nil {
+			if d.Required {
+				return ErrBadParamInput
+			}
+			continue
+		}
+		if !d.Accepts(v) {
+			return ErrBadParamInput
+		}
+	}
+	return nil
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// AttributeScope narrows the definitions listed, an empty scope lists them
+// all.
+type AttributeScope struct {
+	CategoryID  string `query:"category_id"`
+	ProductType string `query:"product_type"`
+}
+
+type AttributeUsecase interface {
+	Fetch(ctx context.Context, scope AttributeScope) ([]AttributeDefinition, error)
+	GetByID(ctx context.Context, id string) (AttributeDefinition, error)
+	// FetchForProduct lists the definitions the product has to satisfy.
+	FetchForProduct(ctx context.Context, productID string) ([]AttributeDefinition, error)
+	Store(ctx context.Context, d *AttributeDefinition) error
+	Update(ctx context.Context, d *AttributeDefinition) error
+	Delete(ctx context.Context, id string) error
+}
+
+type AttributeRepository interface {
+	Fetch(ctx context.Context, scope AttributeScope) (res []AttributeDefinition, err error)
+	GetByID(ctx context.Context, id string) (res AttributeDefinition, err error)
+	// FetchApplicable lists the definitions of the product type and of the
+	// categories the product is in, or above them. An empty productID only
+	// looks at the product type.
+	FetchApplicable(ctx context.Context, productID, productType string) (res []AttributeDefinition, err error)
+	Store(ctx context.Context, d *AttributeDefinition) (err error)
+	Update(ctx context.Context, d *AttributeDefinition) (err error)
+	Delete(ctx context.Context, id string) (err error)
+}
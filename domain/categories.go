@@ -0,0 +1,54 @@
+package domain
+
+import (
+	"context"
+	"time"
+)
+
+// Category groups products in a tree. Path is the materialized path of the
+// category, the ids from the root down to the category itself such as
+// "/<root id>/<child id>/", so a subtree is every path sharing its prefix.
+type Category struct {
+	ID        string    `db:"id" json:"id"`
+	ParentID  *string   `db:"parent_id" json:"parent_id"`
+	Name      string    `db:"name" json:"name" validate:"required,lte=255"`
+	Path      string    `db:"path" json:"path"`
+	CreatedAt time.Time `db:"created_at" json:"created_at"`
+	UpdatedAt time.Time `db:"updated_at" json:"updated_at"`
+}
+
+// CategoryPath builds the materialized path of the category id placed under
+// a parent with the given path, an empty parent path makes it a root.
+func CategoryPath(parentPath, id string) string {
+	if parentPath == "" {
+		parentPath = "/"
+	}
+	return parentPath + id + "/"
+}
+
+type CategoryUsecase interface {
+	Fetch(ctx context.Context) ([]Category, error)
+	GetByID(ctx context.Context, id string) (Category, error)
+	GetChildren(ctx context.Context, id string) ([]Category, error)
+	Store(ctx context.Context, c *Category) error
+	Update(ctx context.Context, c *Category) error
+	Delete(ctx context.Context, id string) error
+	AssignProducts(ctx context.Context, categoryID string, productIDs []string) error
+	UnassignProduct(ctx context.Context, categoryID, productID string) error
+	GetByProduct(ctx context.Context, productID string) ([]Category, error)
+}
+
+type CategoryRepository interface {
+	Fetch(ctx context.Context) (res []Category, err error)
+	GetByID(ctx context.Context, id string) (res Category, err error)
+	GetChildren(ctx context.Context, id string) (res []Category, err error)
+	Store(ctx context.Context, c *Category) (err error)
+	Update(ctx context.Context, c *Category) (err error)
+	// Move re-parents c and every category below it, c.Path holds the new
+	// path and oldPath the one the subtree is stored under.
+	Move(ctx context.Context, c *Category, oldPath string) (err error)
+	Delete(ctx context.Context, id string) (err error)
+	AssignProducts(ctx context.Context, categoryID string, productIDs []string) (err error)
+	UnassignProduct(ctx context.Context, categoryID, productID string) (err error)
+	GetByProduct(ctx context.Context, productID string) (res []Category, err error)
+}
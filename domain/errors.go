@@ -1,6 +1,9 @@
 package domain
 
-import "errors"
+import (
+	"errors"
+	"net/http"
+)
 
 var (
 	// ErrInternalServerError will throw if any the Internal Server Error happen
@@ -11,4 +14,27 @@ var (
 	ErrConflict = errors.New("your Data already exist")
 	// ErrBadParamInput will throw if the given request-body or params is not valid
 	ErrBadParamInput = errors.New("given Param is not valid")
+	// ErrPreconditionFailed will throw if the Data was modified since the given version
+	ErrPreconditionFailed = errors.New("your Data has been modified by another request")
+	// ErrTooLarge will throw if the given Data is over the size accepted
+	ErrTooLarge = errors.New("your Data is too large")
 )
+
+// StatusCode maps the errors of the usecases to the HTTP status the
+// handlers answer with.
+func StatusCode(err error) int {
+	switch {
+	case errors.Is(err, ErrPreconditionFailed):
+		return http.StatusPreconditionFailed
+	case errors.Is(err, ErrNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, ErrConflict):
+		return http.StatusConflict
+	case errors.Is(err, ErrBadParamInput):
+		return http.StatusBadRequest
+	case errors.Is(err, ErrTooLarge):
+		return http.StatusRequestEntityTooLarge
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -0,0 +1,123 @@
+package domain
+
+import (
+	"context"
+	"math/big"
+	"strings"
+	"time"
+
+	pkg "github.com/fahmilukis/go-product-svc/pkg/utils"
+)
+
+// ExchangeRates is the rate table used to show prices in another currency.
+// Rates are the units of each currency per one unit of Base, Rounding holds
+// the rule applied to the amounts converted into a currency.
+type ExchangeRates struct {
+	Base      string                      `json:"base"`
+	Rates     map[string]pkg.Decimal      `json:"rates"`
+	Rounding  map[string]pkg.RoundingRule `json:"rounding,omitempty"`
+	UpdatedAt time.Time                   `json:"updated_at"`
+}
+
+// Normalize upper-cases the currency codes, the rates are looked up by the
+// ISO 4217 code.
+func (r *ExchangeRates) Normalize() {
+	r.Base = strings.ToUpper(r.Base)
+	rates := make(map[string]pkg.Decimal, len(r.Rates))
+	for code, rate := range r.Rates {
+		rates[strings.ToUpper(code)] = rate
+	}
+	r.Rates = rates
+	rounding := make(map[string]pkg.RoundingRule, len(r.Rounding))
+	for code, rule := range r.Rounding {
+		rounding[strings.ToUpper(code)] = rule
+	}
+	r.Rounding = rounding
+}
+
+// Validate checks every currency is known and every rate is a positive
+// decimal.
+func (r ExchangeRates) Validate() error {
+	if _, ok := pkg.LookupCurrency(r.Base); !ok {
+		return ErrBadParamInput
+	}
+	for code, rate := range r.Rates {
+		if _, ok := pkg.LookupCurrency(code); !ok {
+			return ErrBadParamInput
+		}
+		if _, err := pkg.ParseRate(string(rate)); err != nil {
+			return ErrBadParamInput
+		}
+	}
+	for code, rule := range r.Rounding {
+		if _, ok := pkg.LookupCurrency(code); !ok || !rule.Valid() {
+			return ErrBadParamInput
+		}
+	}
+	return nil
+}
+
+// Rate returns the units of to per unit of from, crossing through Base.
+func (r ExchangeRates) Rate(from, to string) (*big.Rat, error) {
+	fromRate, err := r.baseRate(from)
+	if err != nil {
+		return nil, err
+	}
+	toRate, err := r.baseRate(to)
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Rat).Quo(toRate, fromRate), nil
+}
+
+// Has reports whether amounts in code can be converted.
+func (r ExchangeRates) Has(code string) bool {
+	_, err := r.baseRate(code)
+	return err == nil
+}
+
+func (r ExchangeRates) baseRate(code string) (*big.Rat, error) {
+	if code == r.Base {
+		return big.NewRat(1, 1), nil
+	}
+	raw, ok := r.Rates[code]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return pkg.ParseRate(string(raw))
+}
+
+// Convert converts minor units of from into minor units of to with the
+// rounding rule of to.
+func (r ExchangeRates) Convert(amount int64, from, to string) (int64, *big.Rat, error) {
+	fromCur, ok := pkg.LookupCurrency(from)
+	if !ok {
+		return 0, nil, ErrBadParamInput
+	}
+	toCur, ok := pkg.LookupCurrency(to)
+	if !ok {
+		return 0, nil, ErrBadParamInput
+	}
+	rate, err := r.Rate(fromCur.Code, toCur.Code)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	converted, err := pkg.ConvertAmount(amount, fromCur, toCur, rate, r.Rounding[toCur.Code])
+	if err != nil {
+		return 0, nil, err
+	}
+	return converted, rate, nil
+}
+
+type ExchangeRateUsecase interface {
+	Get(ctx context.Context) (ExchangeRates, error)
+	Replace(ctx context.Context, r *ExchangeRates) error
+}
+
+// ExchangeRateRepository keeps the current rate table, it holds a single
+// table that is replaced as a whole.
+type ExchangeRateRepository interface {
+	Get(ctx context.Context) (res ExchangeRates, err error)
+	Replace(ctx context.Context, r ExchangeRates) (err error)
+}
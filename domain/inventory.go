@@ -0,0 +1,160 @@
+package domain
+
+import (
+	"context"
+	"time"
+)
+
+// DefaultWarehouseCode is the warehouse that holds the stock set without a
+// warehouse, it can't be deleted.
+const DefaultWarehouseCode = "default"
+
+// GeoPoint is a location in decimal degrees.
+type GeoPoint struct {
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+}
+
+// Warehouse is a place stock is held in. A warehouse without coordinates
+// comes last for the nearest allocation.
+type Warehouse struct {
+	ID        string   `db:"id" json:"id"`
+	Code      string   `db:"code" json:"code" validate:"required,lte=64"`
+	Name      string   `db:"name" json:"name" validate:"lte=255"`
+	Latitude  *float64 `db:"latitude" json:"latitude"`
+	Longitude *float64 `db:"longitude" json:"longitude"`
+	// Priority orders the warehouses for the priority allocation, lowest first.
+	Priority  int       `db:"priority" json:"priority"`
+	CreatedAt time.Time `db:"created_at" json:"created_at"`
+	UpdatedAt time.Time `db:"updated_at" json:"updated_at"`
+}
+
+// Location is where the warehouse is, nil when its coordinates aren't known.
+func (w Warehouse) Location() *GeoPoint {
+	if w.Latitude == nil || w.Longitude == nil {
+		return nil
+	}
+	return &GeoPoint{Latitude: *w.Latitude, Longitude: *w.Longitude}
+}
+
+// StockLevel is the stock of a product in a warehouse, or of one of its
+// variants when VariantID is set. Reserved units are held by pending
+// reservations and are not available to new ones.
+type StockLevel struct {
+	ID          string    `db:"id" json:"id"`
+	WarehouseID string    `db:"warehouse_id" json:"warehouse_id"`
+	ProductID   string    `db:"product_id" json:"product_id"`
+	VariantID   *string   `db:"variant_id" json:"variant_id"`
+	OnHand      int64     `db:"on_hand" json:"on_hand"`
+	Reserved    int64     `db:"reserved" json:"reserved"`
+	UpdatedAt   time.Time `db:"updated_at" json:"updated_at"`
+}
+
+// Available is the stock that can still be reserved.
+func (s StockLevel) Available() int64 {
+	return s.OnHand - s.Reserved
+}
+
+type ReservationStatus string
+
+var (
+	ReservationPending   ReservationStatus = "pending"
+	ReservationCommitted ReservationStatus = "committed"
+	ReservationCancelled ReservationStatus = "cancelled"
+	ReservationExpired   ReservationStatus = "expired"
+)
+
+// Reservation holds stock of a single warehouse for an open cart until it is
+// committed, cancelled or expires.
+type Reservation struct {
+	ID           string            `db:"id" json:"id"`
+	ProductID    string            `db:"product_id" json:"product_id"`
+	VariantID    *string           `db:"variant_id" json:"variant_id"`
+	WarehouseID  string            `db:"warehouse_id" json:"warehouse_id"`
+	StockLevelID string            `db:"stock_level_id" json:"-"`
+	Quantity     int64             `db:"quantity" json:"quantity"`
+	Status       ReservationStatus `db:"status" json:"status"`
+	ExpiresAt    time.Time         `db:"expires_at" json:"expires_at"`
+	CreatedAt    time.Time         `db:"created_at" json:"created_at"`
+	UpdatedAt    time.Time         `db:"updated_at" json:"updated_at"`
+	// Destination is where the order ships to, it is only used to allocate
+	// the reservation.
+	Destination *GeoPoint `db:"-" json:"-"`
+}
+
+// AvailableToPromise is the stock of a product, its variants included, that
+// new orders can still be promised.
+type AvailableToPromise struct {
+	Available  int64                   `json:"available"`
+	Warehouses []WarehouseAvailability `json:"warehouses"`
+}
+
+type WarehouseAvailability struct {
+	WarehouseID string `json:"warehouse_id"`
+	Code        string `json:"code"`
+	Available   int64  `json:"available"`
+}
+
+// AllocationCandidate is a stock level a reservation could be taken from.
+type AllocationCandidate struct {
+	StockLevel StockLevel
+	Warehouse  Warehouse
+}
+
+// AllocationStrategy decides which warehouse a reservation takes its stock
+// from.
+type AllocationStrategy interface {
+	// Rank orders the candidates from the most to the least preferred, the
+	// reservation goes to the first one with enough stock available.
+	Rank(r Reservation, candidates []AllocationCandidate) []AllocationCandidate
+}
+
+type InventoryUsecase interface {
+	GetStock(ctx context.Context, productID string) ([]StockLevel, error)
+	// SetStock sets the stock in s.WarehouseID, or in the default warehouse
+	// when it is empty.
+	SetStock(ctx context.Context, s *StockLevel) error
+	AvailableToPromise(ctx context.Context, productID string) (AvailableToPromise, error)
+	// Reserve holds r.Quantity units for ttl, ErrConflict means no warehouse
+	// has enough stock available.
+	Reserve(ctx context.Context, r *Reservation, ttl time.Duration) error
+	GetReservation(ctx context.Context, productID, id string) (Reservation, error)
+	Commit(ctx context.Context, productID, id string) (Reservation, error)
+	Cancel(ctx context.Context, productID, id string) (Reservation, error)
+	ReleaseExpired(ctx context.Context) (int64, error)
+}
+
+type InventoryRepository interface {
+	FetchStock(ctx context.Context, productID string) (res []StockLevel, err error)
+	// SetStock sets the units on hand, ErrConflict means fewer than the
+	// units already reserved.
+	SetStock(ctx context.Context, s *StockLevel) (err error)
+	AvailableToPromise(ctx context.Context, productID string) (res AvailableToPromise, err error)
+	// Reserve takes the stock from the first warehouse ranked by strategy
+	// that has enough of it.
+	Reserve(ctx context.Context, r *Reservation, strategy AllocationStrategy) (err error)
+	GetReservation(ctx context.Context, productID, id string) (res Reservation, err error)
+	// Settle closes a pending reservation with status, a committed one takes
+	// its units off the stock on hand.
+	Settle(ctx context.Context, productID, id string, status ReservationStatus, at time.Time) (res Reservation, err error)
+	ReleaseExpired(ctx context.Context, now time.Time) (released int64, err error)
+}
+
+type WarehouseUsecase interface {
+	Fetch(ctx context.Context) ([]Warehouse, error)
+	GetByID(ctx context.Context, id string) (Warehouse, error)
+	Store(ctx context.Context, w *Warehouse) error
+	Update(ctx context.Context, w *Warehouse) error
+	// Delete fails with ErrConflict for the default warehouse and for one
+	// that still holds stock.
+	Delete(ctx context.Context, id string) error
+}
+
+type WarehouseRepository interface {
+	Fetch(ctx context.Context) (res []Warehouse, err error)
+	GetByID(ctx context.Context, id string) (res Warehouse, err error)
+	GetByCode(ctx context.Context, code string) (res Warehouse, err error)
+	Store(ctx context.Context, w *Warehouse) (err error)
+	Update(ctx context.Context, w *Warehouse) (err error)
+	Delete(ctx context.Context, id string) (err error)
+}
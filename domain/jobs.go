@@ -0,0 +1,147 @@
+package domain
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"time"
+)
+
+var (
+	// ErrJobCanceled is returned to a running job once it was canceled.
+	ErrJobCanceled = errors.New("the job was canceled")
+	// ErrJobFatal is wrapped by the errors of a job a retry would make
+	// worse, the job fails right away.
+	ErrJobFatal = errors.New("the job can't be retried")
+)
+
+type JobStatus string
+
+var (
+	JobQueued    JobStatus = "queued"
+	JobRunning   JobStatus = "running"
+	JobSucceeded JobStatus = "succeeded"
+	JobFailed    JobStatus = "failed"
+	JobCanceled  JobStatus = "canceled"
+)
+
+// Finished tells whether the job won't run anymore.
+func (s JobStatus) Finished() bool {
+	return s == JobSucceeded || s == JobFailed || s == JobCanceled
+}
+
+// The kinds of the catalog jobs.
+const (
+	JobProductImport  = "product.import"
+	JobProductExport  = "product.export"
+	JobProductReindex = "product.reindex"
+)
+
+// The files of a job, the input is there when Job.Input is set and the
+// output when Job.Output is.
+const (
+	JobInputFile  = "input"
+	JobOutputFile = "output"
+)
+
+// JobProgress is how far a running job got, Total is 0 when unknown.
+type JobProgress struct {
+	Done  int64 `json:"done"`
+	Total int64 `json:"total"`
+}
+
+// Job is a run of a long operation in the background. A failed run is
+// retried at RunAt until MaxAttempts runs were made.
+type Job struct {
+	ID      string          `db:"id" json:"id"`
+	Kind    string          `db:"kind" json:"kind"`
+	Status  JobStatus       `db:"status" json:"status"`
+	Payload json.RawMessage `db:"payload" json:"payload"`
+	// Input is JobInputFile when the job has data to work on, e.g. an
+	// uploaded CSV, and empty otherwise. Only the workers read it.
+	Input  string          `db:"input" json:"-"`
+	Result json.RawMessage `db:"result" json:"result,omitempty"`
+	// Error is the error of the last run.
+	Error string `db:"error" json:"error,omitempty"`
+	// Output is the name the file the job produced, if any, is downloaded
+	// as.
+	Output          string      `db:"output" json:"output,omitempty"`
+	Progress        JobProgress `json:"progress"`
+	Attempts        int         `db:"attempts" json:"attempts"`
+	MaxAttempts     int         `db:"max_attempts" json:"max_attempts"`
+	CancelRequested bool        `db:"cancel_requested" json:"cancel_requested"`
+	RunAt           time.Time   `db:"run_at" json:"run_at"`
+	// LockedUntil is when the lease of the worker running the job ends, the
+	// job is taken over by another worker after that.
+	LockedUntil *time.Time `db:"locked_until" json:"-"`
+	StartedAt   *time.Time `db:"started_at" json:"started_at"`
+	FinishedAt  *time.Time `db:"finished_at" json:"finished_at"`
+	CreatedAt   time.Time  `db:"created_at" json:"created_at"`
+	UpdatedAt   time.Time  `db:"updated_at" json:"updated_at"`
+}
+
+// JobRun is a job handed to its JobFunc.
+type JobRun struct {
+	Job
+	// Progress records how far the run got, it fails with ErrJobCanceled
+	// once the job was canceled.
+	Progress func(done, total int64) error
+	// CreateOutput creates the file the job hands back, name is the file
+	// name the output is downloaded as. The output is complete once closed.
+	CreateOutput func(name string) (io.WriteCloser, error)
+	// OpenInput opens the input of the job and tells its size.
+	OpenInput func() (io.ReadCloser, int64, error)
+}
+
+// JobFunc runs the jobs of a kind, the result is shown with the job. The
+// ctx is cancelled when the job is.
+type JobFunc func(ctx context.Context, run JobRun) (result interface{}, err error)
+
+type JobUsecase interface {
+	// Register sets the func running the jobs of the kind, the kinds are
+	// registered before the workers start.
+	Register(kind string, fn JobFunc)
+	// Enqueue saves the input, when there is one, as the input file the job
+	// keeps until it finishes. An input over the limit fails with
+	// ErrTooLarge.
+	Enqueue(ctx context.Context, kind string, payload interface{}, input io.Reader) (Job, error)
+	GetByID(ctx context.Context, id string) (Job, error)
+	// Cancel cancels a queued job right away, a running one is told to stop.
+	Cancel(ctx context.Context, id string) (Job, error)
+	// OpenOutput opens the output of a succeeded job and tells its size and
+	// the name it is downloaded as. The output is read under ctx.
+	OpenOutput(ctx context.Context, id string) (output io.ReadCloser, size int64, name string, err error)
+	// Work runs jobs in the given number of workers until ctx is done.
+	Work(ctx context.Context, workers int)
+}
+
+type JobRepository interface {
+	Store(ctx context.Context, j *Job) (err error)
+	GetByID(ctx context.Context, id string) (res Job, err error)
+	// Claim takes the next job due, or a running job whose lease ended, for
+	// a worker until lockedUntil. It fails with ErrNotFound when none is.
+	Claim(ctx context.Context, now, lockedUntil time.Time) (res Job, err error)
+	// Heartbeat extends the lease of the run, identified by its attempt, and
+	// records its progress. It tells whether the job was canceled meanwhile
+	// and fails with ErrNotFound once the run lost the job.
+	Heartbeat(ctx context.Context, id string, attempt int, now, lockedUntil time.Time, progress JobProgress) (canceled bool, err error)
+	// Finish records the end of the run made at the given attempt, the job is
+	// queued again when its status is JobQueued. It fails with ErrNotFound
+	// once the run lost the job.
+	Finish(ctx context.Context, j *Job, attempt int) (err error)
+	// Cancel fails with ErrConflict when the job is already finished.
+	Cancel(ctx context.Context, id string, now time.Time) (res Job, err error)
+}
+
+// JobFileRepository keeps the files of the jobs where every instance reads
+// them, a job runs on whichever instance claims it.
+type JobFileRepository interface {
+	// Create replaces the file, it is written as it comes and complete once
+	// the writer is closed.
+	Create(ctx context.Context, jobID, file string) (io.WriteCloser, error)
+	// Open reads the file as it goes and tells its size, it fails with
+	// ErrNotFound when there is none.
+	Open(ctx context.Context, jobID, file string) (io.ReadCloser, int64, error)
+	Remove(ctx context.Context, jobID, file string) error
+}
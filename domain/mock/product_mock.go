@@ -12,11 +12,11 @@ type ProductRepository struct {
 }
 
 // Delete mock
-func (_m *ProductRepository) Delete(ctx context.Context, id string) error {
-	ret := _m.Called(ctx, id)
+func (_m *ProductRepository) Delete(ctx context.Context, id string, version int64) error {
+	ret := _m.Called(ctx, id, version)
 	var r0 error
-	if rf, ok := ret.Get(0).(func(context.Context, string) error); ok {
-		r0 = rf(ctx, id)
+	if rf, ok := ret.Get(0).(func(context.Context, string, int64) error); ok {
+		r0 = rf(ctx, id, version)
 	} else {
 		r0 = ret.Error(0)
 	}
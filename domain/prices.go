@@ -0,0 +1,96 @@
+package domain
+
+import (
+	"context"
+	"time"
+
+	pkg "github.com/fahmilukis/go-product-svc/pkg/utils"
+)
+
+// DefaultPriceList is used when no price list is asked for, and as the
+// fallback of a list that has no price in the requested currency.
+const DefaultPriceList = "retail"
+
+// Price is the price of a product in one price list and currency. Amount is
+// in minor units of the currency so it never goes through a float.
+type Price struct {
+	ProductID string    `db:"product_id" json:"product_id"`
+	PriceList string    `db:"price_list" json:"price_list"`
+	Currency  string    `db:"currency" json:"currency"`
+	Amount    int64     `db:"amount" json:"amount"`
+	UpdatedAt time.Time `db:"updated_at" json:"updated_at"`
+}
+
+// PriceInput is a price as sent by a client, the amount is a decimal such as
+// "12.34" in the major unit of the currency.
+type PriceInput struct {
+	PriceList string      `json:"price_list"`
+	Currency  string      `json:"currency"`
+	Amount    pkg.Decimal `json:"amount"`
+}
+
+// PriceQuote is a price ready to be displayed together with the metadata a
+// client needs to format it.
+type PriceQuote struct {
+	PriceList string `json:"price_list"`
+	Currency  string `json:"currency"`
+	Amount    int64  `json:"amount"`
+	Exponent  int    `json:"exponent"`
+	Symbol    string `json:"symbol"`
+	// Decimal is Amount in the major unit, e.g. "12.34".
+	Decimal string `json:"decimal"`
+	// Conversion is set when the amount was converted from a price in
+	// another currency.
+	Conversion *PriceConversion `json:"conversion,omitempty"`
+}
+
+// PriceConversion tells where a converted price comes from and how fresh the
+// rate used was.
+type PriceConversion struct {
+	FromCurrency  string    `json:"from_currency"`
+	FromAmount    int64     `json:"from_amount"`
+	Rate          string    `json:"rate"`
+	RateUpdatedAt time.Time `json:"rate_updated_at"`
+}
+
+// PriceQuery selects the price shown for a product.
+type PriceQuery struct {
+	PriceList string
+	Currency  string
+	// Convert lets a price in another currency be converted with the
+	// exchange rates when the product has none in Currency.
+	Convert bool
+}
+
+// NewPriceQuote formats p for display.
+func NewPriceQuote(p Price) PriceQuote {
+	c, _ := pkg.LookupCurrency(p.Currency)
+	return PriceQuote{
+		PriceList: p.PriceList,
+		Currency:  p.Currency,
+		Amount:    p.Amount,
+		Exponent:  c.Exponent,
+		Symbol:    c.Symbol,
+		Decimal:   pkg.FormatAmount(p.Amount, c),
+	}
+}
+
+type PriceUsecase interface {
+	Fetch(ctx context.Context, productID string) ([]Price, error)
+	// Quote picks the price of the product in the list and currency of q,
+	// falling back to DefaultPriceList.
+	Quote(ctx context.Context, productID string, q PriceQuery) (PriceQuote, error)
+	// QuoteMany quotes several products at once, products without a
+	// matching price are left out of the result.
+	QuoteMany(ctx context.Context, productIDs []string, q PriceQuery) (map[string]PriceQuote, error)
+	Set(ctx context.Context, productID string, in PriceInput) (Price, error)
+	Delete(ctx context.Context, productID, priceList, currency string) error
+}
+
+type PriceRepository interface {
+	Fetch(ctx context.Context, productID string) (res []Price, err error)
+	Get(ctx context.Context, productID, priceList, currency string) (res Price, err error)
+	FetchByProducts(ctx context.Context, productIDs []string, priceLists []string) (res []Price, err error)
+	Upsert(ctx context.Context, p *Price) (err error)
+	Delete(ctx context.Context, productID, priceList, currency string) (err error)
+}
@@ -0,0 +1,52 @@
+package domain
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+)
+
+type ProductEventType string
+
+var (
+	ProductCreated ProductEventType = "product.created"
+	ProductUpdated ProductEventType = "product.updated"
+	ProductDeleted ProductEventType = "product.deleted"
+	// ProductPurged is sent when a trashed product is removed for good, the
+	// payload is the product as it was in the trash.
+	ProductPurged ProductEventType = "product.purged"
+)
+
+// ProductEvent is a product change written to the outbox in the transaction
+// of the change, the payload is the product as written. The IDs are taken
+// before the changes commit so they don't tell the order of the changes,
+// Position does: it is given by the relay in the order it publishes. An
+// event is published again when the relay fails after publishing it, the
+// consumers drop the IDs they already have.
+type ProductEvent struct {
+	ID          int64            `db:"id" json:"id"`
+	ProductID   string           `db:"product_id" json:"product_id"`
+	Type        ProductEventType `db:"type" json:"type"`
+	Payload     json.RawMessage  `db:"payload" json:"payload"`
+	CreatedAt   time.Time        `db:"created_at" json:"created_at"`
+	Position    int64            `db:"position" json:"position"`
+	DeliveredAt *time.Time       `db:"delivered_at" json:"-"`
+}
+
+// EventPublisher hands the product events to the downstream services, in the
+// order given.
+type EventPublisher interface {
+	Publish(ctx context.Context, events []ProductEvent) error
+}
+
+type ProductEventUsecase interface {
+	// Relay publishes the pending events and returns how many there were.
+	Relay(ctx context.Context) (int, error)
+}
+
+type ProductEventRepository interface {
+	// Relay gives the oldest pending events, at most limit, their position
+	// and hands them to publish, they are marked delivered once it succeeded,
+	// in one transaction.
+	Relay(ctx context.Context, limit int, publish func(ctx context.Context, events []ProductEvent) error) (delivered int, err error)
+}
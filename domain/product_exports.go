@@ -0,0 +1,41 @@
+package domain
+
+import "context"
+
+// The formats of an export.
+const (
+	ProductExportCSV = "csv"
+	// ProductExportJSONL writes a JSON object per line, also known as NDJSON.
+	ProductExportJSONL = "jsonl"
+)
+
+// ProductExportRequest is an export as asked for, also the payload of the
+// export jobs.
+type ProductExportRequest struct {
+	Format string        `json:"format"`
+	Gzip   bool          `json:"gzip"`
+	Filter ProductFilter `json:"filter"`
+}
+
+// ProductExportResult sums up an export job, File is the name of its
+// output.
+type ProductExportResult struct {
+	Format string `json:"format"`
+	Rows   int64  `json:"rows"`
+	File   string `json:"file"`
+}
+
+// ProductExportFields are the fields of a CSV export when none are asked
+// for, by JSON name and in column order.
+var ProductExportFields = []string{
+	"id", "name", "slug", "desc", "product_img_src", "status", "publish_at", "unpublish_at",
+	"product_type", "attributes", "tags", "version", "created_at", "updated_at",
+}
+
+// ProductExport reads the products of an export batch by batch. Next returns
+// an empty batch once every product was read, Close must be called either
+// way.
+type ProductExport interface {
+	Next(ctx context.Context) ([]Products, error)
+	Close() error
+}
@@ -0,0 +1,39 @@
+package domain
+
+// ProductImportFields are the product fields a CSV import can fill, by JSON
+// name. tags is a comma separated list and attributes a JSON object.
+var ProductImportFields = []string{
+	"name", "desc", "product_img_src", "status", "publish_at", "unpublish_at",
+	"product_type", "attributes", "tags",
+}
+
+// ProductImportMapping maps a product field to the CSV column it is read
+// from, the fields left out are read from the column of the same name.
+type ProductImportMapping map[string]string
+
+// ProductImportRejection is a CSV row that wasn't imported and why. Line is
+// the line of the row in the file, the header being line 1.
+type ProductImportRejection struct {
+	Line    int      `json:"line"`
+	Reasons []string `json:"reasons"`
+	Record  []string `json:"record"`
+}
+
+// ProductImportResult sums up an import. Imported counts the rows that were
+// stored, or that passed validation on a dry run. Rejected only holds the
+// first rejections of a large import, RejectedTotal counts them all.
+type ProductImportResult struct {
+	DryRun        bool                     `json:"dry_run"`
+	Total         int                      `json:"total"`
+	Imported      int                      `json:"imported"`
+	Header        []string                 `json:"header"`
+	Rejected      []ProductImportRejection `json:"rejected"`
+	RejectedTotal int                      `json:"rejected_total"`
+}
+
+// ProductImportRequest is the payload of the import jobs, the CSV is their
+// input.
+type ProductImportRequest struct {
+	Mapping ProductImportMapping `json:"mapping"`
+	DryRun  bool                 `json:"dry_run"`
+}
@@ -0,0 +1,124 @@
+package domain
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"sort"
+	"time"
+
+	pkg "github.com/fahmilukis/go-product-svc/pkg/utils"
+)
+
+type RevisionAction string
+
+var (
+	RevisionCreate  RevisionAction = "create"
+	RevisionUpdate  RevisionAction = "update"
+	RevisionDelete  RevisionAction = "delete"
+	RevisionRestore RevisionAction = "restore"
+	RevisionRevert  RevisionAction = "revert"
+	// the scheduler publishing or archiving a product
+	RevisionPublish   RevisionAction = "publish"
+	RevisionUnpublish RevisionAction = "unpublish"
+)
+
+type contextKey string
+
+// ActorContextKey holds the identity of whoever issued the request.
+const ActorContextKey contextKey = "actor"
+
+// ActorFromContext returns the actor recorded on the revisions written with ctx.
+func ActorFromContext(ctx context.Context) string {
+	actor, _ := ctx.Value(ActorContextKey).(string)
+	return actor
+}
+
+// ProductRevision is an immutable snapshot of a product taken after a write,
+// the revision number is the product version it captured.
+type ProductRevision struct {
+	ProductID     string         `db:"product_id" json:"product_id"`
+	Revision      int64          `db:"revision" json:"revision"`
+	Action        RevisionAction `db:"action" json:"action"`
+	Actor         string         `db:"actor" json:"actor"`
+	ChangedFields []string       `db:"changed_fields" json:"changed_fields"`
+	Snapshot      Products       `db:"snapshot" json:"snapshot"`
+	CreatedAt     time.Time      `db:"created_at" json:"created_at"`
+}
+
+// FieldChange describes how a single product field differs between two revisions.
+type FieldChange struct {
+	Field string      `json:"field"`
+	From  interface{} `json:"from"`
+	To    interface{} `json:"to"`
+}
+
+// revisionIgnoredFields change on every write and would only add noise to
+// the changed field lists and diffs.
+var revisionIgnoredFields = map[string]bool{
+	"version":    true,
+	"updated_at": true,
+}
+
+// DiffProducts compares two products field by field using their JSON names.
+func DiffProducts(from, to Products) ([]FieldChange, error) {
+	fromFields, err := productFields(from)
+	if err != nil {
+		return nil, err
+	}
+	toFields, err := productFields(to)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make([]string, 0, len(fromFields)+len(toFields))
+	for k := range fromFields {
+		keys = append(keys, k)
+	}
+	for k := range toFields {
+		if _, ok := fromFields[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+
+	changes := make([]FieldChange, 0)
+	for _, k := range keys {
+		if revisionIgnoredFields[k] || reflect.DeepEqual(fromFields[k], toFields[k]) {
+			continue
+		}
+		changes = append(changes, FieldChange{
+			Field: k,
+			From:  fromFields[k],
+			To:    toFields[k],
+		})
+	}
+
+	return changes, nil
+}
+
+func productFields(p Products) (map[string]interface{}, error) {
+	raw, err := json.Marshal(p)
+	if err != nil {
+		return nil, err
+	}
+
+	fields := map[string]interface{}{}
+	if err := json.Unmarshal(raw, &fields); err != nil {
+		return nil, err
+	}
+	return fields, nil
+}
+
+type ProductRevisionUsecase interface {
+	Fetch(ctx context.Context, productID string, pg pkg.Pagination) ([]ProductRevision, pkg.Pagination, error)
+	GetByRevision(ctx context.Context, productID string, rev int64) (ProductRevision, error)
+	Diff(ctx context.Context, productID string, from, to int64) ([]FieldChange, error)
+	Revert(ctx context.Context, productID string, rev int64, version int64) (Products, error)
+}
+
+type ProductRevisionRepository interface {
+	Fetch(ctx context.Context, productID string, pg pkg.Pagination) (res []ProductRevision, nextPg pkg.Pagination, err error)
+	GetByRevision(ctx context.Context, productID string, rev int64) (res ProductRevision, err error)
+	Store(ctx context.Context, r *ProductRevision) (err error)
+}
@@ -0,0 +1,37 @@
+package domain
+
+import (
+	"context"
+	"time"
+)
+
+// ProductTranslation is the name and description of a product in a locale
+// other than the default one, which lives on the product itself.
+type ProductTranslation struct {
+	ProductID   string    `db:"product_id" json:"product_id"`
+	Locale      string    `db:"locale" json:"locale"`
+	Name        string    `db:"name" json:"name" validate:"required,lte=255"`
+	Description string    `db:"description" json:"desc" validate:"required,lte=255"`
+	UpdatedAt   time.Time `db:"updated_at" json:"updated_at"`
+}
+
+type ProductTranslationUsecase interface {
+	Fetch(ctx context.Context, productID string) ([]ProductTranslation, error)
+	Upsert(ctx context.Context, t *ProductTranslation) error
+	Delete(ctx context.Context, productID, locale string) error
+	// Missing lists the supported locales the product has no translation
+	// for.
+	Missing(ctx context.Context, productID string) ([]string, error)
+	// Localize swaps the name and description of the products for the best
+	// translation of the preferred locales, each falling back to its parent
+	// locales, and sets the Locale of the products to the one used.
+	Localize(ctx context.Context, prefs []string, products ...*Products) error
+}
+
+type ProductTranslationRepository interface {
+	Fetch(ctx context.Context, productID string) (res []ProductTranslation, err error)
+	// FetchFor reads the translations of the products in the given locales.
+	FetchFor(ctx context.Context, productIDs []string, locales []string) (res []ProductTranslation, err error)
+	Upsert(ctx context.Context, t *ProductTranslation) (err error)
+	Delete(ctx context.Context, productID, locale string) (err error)
+}
@@ -2,34 +2,234 @@ package domain
 
 import (
 	"context"
+	"io"
+	"strings"
 	"time"
 
 	pkg "github.com/fahmilukis/go-product-svc/pkg/utils"
 )
 
+// ProductStatus is where a product is in its lifecycle, only the published
+// products are listed to the public.
+type ProductStatus string
+
+var (
+	ProductDraft     ProductStatus = "draft"
+	ProductScheduled ProductStatus = "scheduled"
+	ProductPublished ProductStatus = "published"
+	ProductArchived  ProductStatus = "archived"
+)
+
+// Valid tells whether s is one of the known statuses.
+func (s ProductStatus) Valid() bool {
+	switch s {
+	case ProductDraft, ProductScheduled, ProductPublished, ProductArchived:
+		return true
+	}
+	return false
+}
+
 type Products struct {
-	ID          string    `db:"id" json:"id" validate:"required,uuid"`
-	CreatedAt   time.Time `db:"created_at" json:"created_at"`
-	UpdatedAt   time.Time `db:"updated_at" json:"updated_at"`
-	Name        string    `db:"product_name" json:"name" validate:"required,lte=255"`
-	Description string    `db:"product_desc" json:"desc" validate:"required,lte=255"`
-	ImageSrc    string    `json:"product_img_src"`
+	ID        string    `db:"id" json:"id" validate:"required,uuid"`
+	CreatedAt time.Time `db:"created_at" json:"created_at"`
+	UpdatedAt time.Time `db:"updated_at" json:"updated_at"`
+	Name      string    `db:"product_name" json:"name" validate:"required,lte=255"`
+	// Slug is derived from the name, the slugs a product had before keep
+	// pointing to it.
+	Slug        string        `db:"slug" json:"slug"`
+	Description string        `db:"product_desc" json:"desc" validate:"required,lte=255"`
+	ImageSrc    string        `json:"product_img_src"`
+	Version     int64         `db:"version" json:"version"`
+	DeletedAt   *time.Time    `db:"deleted_at" json:"deleted_at,omitempty"`
+	Status      ProductStatus `db:"status" json:"status"`
+	// PublishAt is when a scheduled product gets published, UnpublishAt when
+	// a published one gets archived.
+	PublishAt   *time.Time `db:"publish_at" json:"publish_at"`
+	UnpublishAt *time.Time `db:"unpublish_at" json:"unpublish_at"`
+	// ProductType picks the attribute definitions of the product together
+	// with its categories.
+	ProductType string                 `db:"product_type" json:"product_type"`
+	Attributes  map[string]interface{} `db:"attributes" json:"attributes"`
+	// Tags are free-form, NormalizeTags folds them before they are stored.
+	Tags []string `db:"tags" json:"tags"`
+	// Locale is the locale Name and Description were localized to, the
+	// writes always go to the default locale.
+	Locale string `db:"-" json:"locale,omitempty"`
+}
+
+// NormalizeTags trims and lower-cases the tags and drops the empty and
+// repeated ones, the first occurrence keeps its place.
+func NormalizeTags(tags []string) []string {
+	res := make([]string, 0, len(tags))
+	seen := make(map[string]bool, len(tags))
+	for _, t := range tags {
+		t = strings.ToLower(strings.TrimSpace(t))
+		if t == "" || seen[t] {
+			continue
+		}
+		seen[t] = true
+		res = append(res, t)
+	}
+	return res
+}
+
+// TagsMode tells whether a product needs any or all of the filtered tags.
+type TagsMode string
+
+var (
+	TagsAny TagsMode = "any"
+	TagsAll TagsMode = "all"
+)
+
+// ProductTag is a tag with the number of products carrying it.
+type ProductTag struct {
+	Tag   string `json:"tag"`
+	Count int64  `json:"count"`
+}
+
+// TagRename renames the From tags to To on every product, renaming several
+// tags or to a tag already in use merges them.
+type TagRename struct {
+	From []string `json:"from"`
+	To   string   `json:"to"`
+}
+
+// ProductFilter narrows down the products returned by the read methods.
+type ProductFilter struct {
+	// IncludeDeleted also returns products that are in the trash.
+	IncludeDeleted bool `query:"include_deleted"`
+	// OnlyDeleted returns the trashed products only.
+	OnlyDeleted bool `query:"only_deleted"`
+	// CategoryID keeps the products assigned to the category or to any
+	// category below it.
+	CategoryID string `query:"category_id"`
+	// PublishedOnly keeps the published products, the public lists set it.
+	PublishedOnly bool `query:"-"`
+	// Filters are parsed from the query string with ProductFilterDefs.
+	Filters []pkg.Filter `query:"-"`
+	// Sort is parsed from the query string with ProductSortFields, the
+	// lists are ordered by creation when it is empty.
+	Sort []pkg.SortField `query:"-"`
+	// Attributes match the attribute values, Field is the attribute name and
+	// the value is a string for FilterEq and a float64 for FilterGte and
+	// FilterLte.
+	Attributes []pkg.Filter `query:"-"`
+	// Tags keeps the products with any of the tags, or all of them when
+	// TagsMode is TagsAll.
+	Tags     []string `query:"-"`
+	TagsMode TagsMode `query:"-"`
+	// Fields limits the fields read to the given JSON names, every field is
+	// read when it is empty.
+	Fields []string `query:"-"`
+}
+
+// ProductSortFields are the fields the product lists can be sorted by.
+var ProductSortFields = []string{"name", "created_at", "updated_at", "publish_at"}
+
+// ProductFilterDefs are the query string filters accepted by the product lists.
+var ProductFilterDefs = []pkg.FilterDef{
+	{Param: "name", Field: "name", Op: pkg.FilterEq, Type: pkg.FilterString},
+	{Param: "name_contains", Field: "name", Op: pkg.FilterContains, Type: pkg.FilterString},
+	{Param: "desc_contains", Field: "desc", Op: pkg.FilterContains, Type: pkg.FilterString},
+	{Param: "created_after", Field: "created_at", Op: pkg.FilterAfter, Type: pkg.FilterTime},
+	{Param: "created_before", Field: "created_at", Op: pkg.FilterBefore, Type: pkg.FilterTime},
+	{Param: "updated_after", Field: "updated_at", Op: pkg.FilterAfter, Type: pkg.FilterTime},
+	{Param: "updated_before", Field: "updated_at", Op: pkg.FilterBefore, Type: pkg.FilterTime},
+	{Param: "has_image", Field: "product_img_src", Op: pkg.FilterHas, Type: pkg.FilterBool},
+	{Param: "status", Field: "status", Op: pkg.FilterEq, Type: pkg.FilterString},
+	{Param: "product_type", Field: "product_type", Op: pkg.FilterEq, Type: pkg.FilterString},
+}
+
+// ProductSearchHit is a product matched by a search together with its
+// relevance and the matched terms highlighted.
+type ProductSearchHit struct {
+	Products
+	Rank       float64           `json:"rank"`
+	Highlights ProductHighlights `json:"highlights"`
+}
+
+type ProductHighlights struct {
+	Name        string `json:"name"`
+	Description string `json:"desc"`
+}
+
+// ProductSearchIndex is the backend answering product searches. Writes are
+// mirrored into it so it can live outside of the product table.
+type ProductSearchIndex interface {
+	Index(ctx context.Context, products ...Products) error
+	Remove(ctx context.Context, id string) error
+	Reset(ctx context.Context) error
+	Search(ctx context.Context, query string, f ProductFilter, pg pkg.Pagination) ([]ProductSearchHit, pkg.Pagination, error)
 }
 
 type ProductUsecase interface {
-	Fetch(ctx context.Context, pg pkg.Pagination) ([]Products, pkg.Pagination, error)
-	GetByID(ctx context.Context, id string) (Products, error)
-	GetByName(ctx context.Context, name string) (Products, error)
+	Fetch(ctx context.Context, f ProductFilter, pg pkg.Pagination) ([]Products, pkg.Pagination, error)
+	GetByID(ctx context.Context, id string, f ProductFilter) (Products, error)
+	GetByName(ctx context.Context, name string, f ProductFilter) (Products, error)
+	// GetBySlug also finds a product by a slug it had before, the Slug of
+	// the product returned is its current one then.
+	GetBySlug(ctx context.Context, slug string, f ProductFilter) (Products, error)
+	Search(ctx context.Context, query string, f ProductFilter, pg pkg.Pagination) ([]ProductSearchHit, pkg.Pagination, error)
+	Reindex(ctx context.Context) (int, error)
 	Store(ctx context.Context, p *Products) error
 	Update(ctx context.Context, p *Products) error
-	Delete(ctx context.Context, id string) error
+	Delete(ctx context.Context, id string, version int64) error
+	Restore(ctx context.Context, id string) error
+	PurgeDeleted(ctx context.Context, retention time.Duration) (int64, error)
+	// RunSchedule publishes the scheduled products and archives the published
+	// ones whose time has come, it returns how many changed status.
+	RunSchedule(ctx context.Context) (int64, error)
+	// FetchTags lists the tags of the live products with their usage.
+	FetchTags(ctx context.Context) ([]ProductTag, error)
+	// RenameTags returns how many products changed.
+	RenameTags(ctx context.Context, r TagRename) (int64, error)
+	// Import reads products from a CSV with a header row. The rows are
+	// validated like Store does and the valid ones stored in batches, one
+	// transaction each, unless dryRun is set.
+	Import(ctx context.Context, csv io.Reader, mapping ProductImportMapping, dryRun bool) (ProductImportResult, error)
+	// Export reads every product matching the filter, it lasts as long as
+	// ctx does. The caller closes it, a ctx never done keeps its transaction
+	// open until then.
+	Export(ctx context.Context, f ProductFilter) (ProductExport, error)
+	// WriteExport writes the rest of the export to w in the format, the
+	// fields are the CSV columns or the JSON fields when set. It returns the
+	// number of products written.
+	WriteExport(ctx context.Context, w io.Writer, export ProductExport, format string, fields []string) (int64, error)
 }
 
 type ProductRepository interface {
-	Fetch(ctx context.Context, pg pkg.Pagination) (res []Products, nextPg pkg.Pagination, err error)
-	GetByID(ctx context.Context, id string) (res Products, err error)
-	GetByName(ctx context.Context, name string) (res Products, err error)
+	Fetch(ctx context.Context, f ProductFilter, pg pkg.Pagination) (res []Products, nextPg pkg.Pagination, err error)
+	GetByID(ctx context.Context, id string, f ProductFilter) (res Products, err error)
+	GetByName(ctx context.Context, name string, f ProductFilter) (res Products, err error)
+	GetBySlug(ctx context.Context, slug string, f ProductFilter) (res Products, err error)
+	Search(ctx context.Context, query string, f ProductFilter, pg pkg.Pagination) (res []ProductSearchHit, nextPg pkg.Pagination, err error)
+	// Store and the other writes record the revision of the product in
+	// their transaction and set p to the product as written.
 	Store(ctx context.Context, p *Products) (err error)
-	Update(ctx context.Context, p *Products) (err error)
-	Delete(ctx context.Context, id string) (err error)
+	// StoreBatch stores the products in a single transaction.
+	StoreBatch(ctx context.Context, products []*Products) (err error)
+	// Export reads the products from a server-side cursor, batchSize rows
+	// per round trip.
+	Export(ctx context.Context, f ProductFilter, batchSize int) (res ProductExport, err error)
+	// Update records its revision with the given action, e.g. a revert.
+	Update(ctx context.Context, p *Products, action RevisionAction) (err error)
+	Delete(ctx context.Context, id string, version int64) (res Products, err error)
+	Restore(ctx context.Context, id string) (res Products, err error)
+	// PurgeDeleted removes the products trashed before the given time and
+	// returns them with the images no product or variant links to any more.
+	PurgeDeleted(ctx context.Context, before, now time.Time) (res []Products, images []string, err error)
+	// FetchDue returns up to limit products with a publish or unpublish
+	// time that passed at now.
+	FetchDue(ctx context.Context, now time.Time, limit int) (res []Products, err error)
+	FetchTags(ctx context.Context) (res []ProductTag, err error)
+	// RenameTags rewrites the tags of every product, trashed ones included,
+	// in a single statement and returns the products it changed.
+	RenameTags(ctx context.Context, r TagRename, now time.Time) (res []Products, err error)
+}
+
+// ProductImageStore keeps the images uploaded for the products.
+type ProductImageStore interface {
+	// Remove deletes the uploaded image src links to, links to anything
+	// else are left alone.
+	Remove(ctx context.Context, src string) error
 }
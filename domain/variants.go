@@ -0,0 +1,60 @@
+package domain
+
+import (
+	"context"
+	"time"
+)
+
+// Variant is a sellable version of a product, such as the red T-shirt in
+// size M. The SKU is unique across every product.
+type Variant struct {
+	ID        string            `db:"id" json:"id"`
+	ProductID string            `db:"product_id" json:"product_id"`
+	SKU       string            `db:"sku" json:"sku" validate:"required,lte=64"`
+	Options   map[string]string `db:"options" json:"options"`
+	// Price is in the smallest unit of the currency, e.g. cents.
+	Price    int64  `db:"price" json:"price"`
+	ImageSrc string `db:"img_src" json:"img_src"`
+	// Stock is the initial stock when the variant is created, afterwards it
+	// is read from the inventory and changed through it.
+	Stock     int64     `db:"stock" json:"stock"`
+	CreatedAt time.Time `db:"created_at" json:"created_at"`
+	UpdatedAt time.Time `db:"updated_at" json:"updated_at"`
+}
+
+// OptionAxis declares an option of a product and the values it comes in,
+// e.g. size with S, M and L.
+type OptionAxis struct {
+	Name   string   `json:"name"`
+	Values []string `json:"values"`
+}
+
+// VariantTemplate is what the generated variants start from, their SKU is
+// SKUPrefix followed by their option values.
+type VariantTemplate struct {
+	SKUPrefix string       `json:"sku_prefix"`
+	Axes      []OptionAxis `json:"axes"`
+	Price     int64        `json:"price"`
+	ImageSrc  string       `json:"img_src"`
+	Stock     int64        `json:"stock"`
+}
+
+type VariantUsecase interface {
+	Fetch(ctx context.Context, productID string) ([]Variant, error)
+	GetByID(ctx context.Context, productID, id string) (Variant, error)
+	Store(ctx context.Context, v *Variant) error
+	// Generate creates a variant for every combination of the option axes
+	// the product doesn't have yet and returns the created ones.
+	Generate(ctx context.Context, productID string, t VariantTemplate) ([]Variant, error)
+	Update(ctx context.Context, v *Variant) error
+	Delete(ctx context.Context, productID, id string) error
+}
+
+type VariantRepository interface {
+	Fetch(ctx context.Context, productID string) (res []Variant, err error)
+	GetByID(ctx context.Context, productID, id string) (res Variant, err error)
+	// Store inserts all the variants or none of them.
+	Store(ctx context.Context, variants ...*Variant) (err error)
+	Update(ctx context.Context, v *Variant) (err error)
+	Delete(ctx context.Context, productID, id string) (err error)
+}
@@ -0,0 +1,64 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/fahmilukis/go-product-svc/domain"
+	"github.com/gofiber/fiber/v2"
+)
+
+type ExchangeRateHandler struct {
+	ExchangeRateUC domain.ExchangeRateUsecase
+}
+
+func ExchangeRateRoute(a *fiber.App, ruc domain.ExchangeRateUsecase) {
+	handler := &ExchangeRateHandler{
+		ExchangeRateUC: ruc,
+	}
+
+	route := a.Group("/api/v1")
+
+	route.Get("/exchange-rates", handler.GetExchangeRates)
+	route.Put("/exchange-rates", handler.ReplaceExchangeRates)
+}
+
+func (h *ExchangeRateHandler) GetExchangeRates(c *fiber.Ctx) error {
+	data, err := h.ExchangeRateUC.Get(c.Context())
+	if err != nil {
+		return c.Status(domain.StatusCode(err)).JSON(fiber.Map{
+			"status": false,
+			"msg":    err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"status": true,
+		"msg":    "success get exchange rates",
+		"data":   data,
+	})
+}
+
+// ReplaceExchangeRates uploads a new rate table, it replaces the current one
+// as a whole.
+func (h *ExchangeRateHandler) ReplaceExchangeRates(c *fiber.Ctx) error {
+	rates := &domain.ExchangeRates{}
+	if err := c.BodyParser(rates); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"status": false,
+			"msg":    err.Error(),
+		})
+	}
+
+	if err := h.ExchangeRateUC.Replace(c.Context(), rates); err != nil {
+		return c.Status(domain.StatusCode(err)).JSON(fiber.Map{
+			"status": false,
+			"msg":    err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"status": true,
+		"msg":    "success replace exchange rates",
+		"data":   rates,
+	})
+}
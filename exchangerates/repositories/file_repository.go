@@ -0,0 +1,107 @@
+package repositories
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/fahmilukis/go-product-svc/domain"
+)
+
+// fileExchangeRateRepository keeps the rate table in memory and mirrors it
+// to a JSON file, so rates can be shipped as a file or pushed through the
+// admin endpoint and survive a restart. An empty path keeps it in memory only.
+type fileExchangeRateRepository struct {
+	mu    sync.RWMutex
+	path  string
+	rates *domain.ExchangeRates
+}
+
+func NewFileExchangeRateRepository(path string) (*fileExchangeRateRepository, error) {
+	repo := &fileExchangeRateRepository{path: path}
+	if err := repo.load(); err != nil {
+		return nil, err
+	}
+
+	return repo, nil
+}
+
+func (f *fileExchangeRateRepository) Get(ctx context.Context) (res domain.ExchangeRates, err error) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	if f.rates == nil {
+		return res, domain.ErrNotFound
+	}
+	return *f.rates, nil
+}
+
+func (f *fileExchangeRateRepository) Replace(ctx context.Context, r domain.ExchangeRates) (err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if err = f.save(r); err != nil {
+		return
+	}
+	f.rates = &r
+	return
+}
+
+func (f *fileExchangeRateRepository) load() error {
+	if f.path == "" {
+		return nil
+	}
+
+	file, err := os.Open(f.path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	r := domain.ExchangeRates{}
+	if err := json.NewDecoder(file).Decode(&r); err != nil {
+		return err
+	}
+	// a file written by hand may spell the codes in lower case
+	r.Normalize()
+	if err := r.Validate(); err != nil {
+		return err
+	}
+	f.rates = &r
+
+	return nil
+}
+
+// save writes to a temporary file first so a crash never leaves a half
+// written table behind.
+func (f *fileExchangeRateRepository) save(r domain.ExchangeRates) error {
+	if f.path == "" {
+		return nil
+	}
+
+	dir := filepath.Dir(f.path)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(dir, filepath.Base(f.path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := json.NewEncoder(tmp).Encode(r); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), f.path)
+}
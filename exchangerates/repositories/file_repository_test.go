@@ -0,0 +1,60 @@
+package repositories_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/fahmilukis/go-product-svc/domain"
+	"github.com/fahmilukis/go-product-svc/exchangerates/repositories"
+	pkg "github.com/fahmilukis/go-product-svc/pkg/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFileExchangeRateRepositoryReload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rates.json")
+
+	repo, err := repositories.NewFileExchangeRateRepository(path)
+	assert.NoError(t, err)
+
+	_, err = repo.Get(context.TODO())
+	assert.Equal(t, domain.ErrNotFound, err)
+
+	rates := domain.ExchangeRates{
+		Base:      "USD",
+		Rates:     map[string]pkg.Decimal{"EUR": "0.92", "JPY": "151.25"},
+		Rounding:  map[string]pkg.RoundingRule{"JPY": {Mode: pkg.RoundUp}},
+		UpdatedAt: time.Date(2024, 3, 1, 8, 0, 0, 0, time.UTC),
+	}
+	assert.NoError(t, repo.Replace(context.TODO(), rates))
+
+	reloaded, err := repositories.NewFileExchangeRateRepository(path)
+	assert.NoError(t, err)
+	got, err := reloaded.Get(context.TODO())
+	assert.NoError(t, err)
+	assert.Equal(t, rates, got)
+
+	// 10.00 EUR is 1644.02 JPY at the cross rate, rounded up
+	amount, _, err := got.Convert(1000, "EUR", "JPY")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1645), amount)
+}
+
+func TestFileExchangeRateRepositoryLoadLowerCase(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rates.json")
+	err := os.WriteFile(path, []byte(`{"base":"usd","rates":{"eur":"0.92","jpy":"151.25"},"rounding":{"jpy":{"mode":"up"}}}`), 0o644)
+	assert.NoError(t, err)
+
+	repo, err := repositories.NewFileExchangeRateRepository(path)
+	assert.NoError(t, err)
+	got, err := repo.Get(context.TODO())
+	assert.NoError(t, err)
+	assert.Equal(t, "USD", got.Base)
+	assert.True(t, got.Has("EUR"))
+
+	amount, _, err := got.Convert(1000, "EUR", "JPY")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1645), amount)
+}
@@ -0,0 +1,44 @@
+package usecases
+
+import (
+	"context"
+	"time"
+
+	"github.com/fahmilukis/go-product-svc/domain"
+)
+
+type exchangeRateUsecase struct {
+	rateRepository domain.ExchangeRateRepository
+	ctxTimeout     time.Duration
+}
+
+func NewExchangeRateUsecase(r domain.ExchangeRateRepository, to time.Duration) domain.ExchangeRateUsecase {
+	return &exchangeRateUsecase{
+		rateRepository: r,
+		ctxTimeout:     to,
+	}
+}
+
+func (u *exchangeRateUsecase) Get(c context.Context) (res domain.ExchangeRates, err error) {
+	ctx, cancel := context.WithTimeout(c, u.ctxTimeout)
+	defer cancel()
+
+	return u.rateRepository.Get(ctx)
+}
+
+// Replace swaps the whole rate table, UpdatedAt defaults to now when the
+// table doesn't say when its rates were taken.
+func (u *exchangeRateUsecase) Replace(c context.Context, r *domain.ExchangeRates) (err error) {
+	ctx, cancel := context.WithTimeout(c, u.ctxTimeout)
+	defer cancel()
+
+	r.Normalize()
+	if err = r.Validate(); err != nil {
+		return
+	}
+	if r.UpdatedAt.IsZero() {
+		r.UpdatedAt = time.Now()
+	}
+
+	return u.rateRepository.Replace(ctx, *r)
+}
@@ -1,13 +1,18 @@
 package files
 
 import (
+	"context"
 	"crypto/rand"
 	"encoding/hex"
 	"fmt"
 	"net/http"
+	"net/url"
 	"os"
+	"path"
 	"path/filepath"
+	"strings"
 
+	"github.com/fahmilukis/go-product-svc/domain"
 	"github.com/gofiber/fiber/v2"
 )
 
@@ -20,9 +25,16 @@ func NewUploadImageRoutes(a *fiber.App) {
 	route.Get("/download/:id", GetImage)
 }
 
+// downloadPath is the path the uploaded images are served from.
+const downloadPath = "/api/v1/download/"
+
+func imagePath(fileName string) string {
+	return filepath.Join(os.TempDir(), "image_server", fileName)
+}
+
 func GetImage(c *fiber.Ctx) error {
 	fileName := c.Params("id")
-	filePath := filepath.Join(os.TempDir(), "image_server", fileName)
+	filePath := imagePath(fileName)
 	return c.Download(filePath, fileName)
 }
 
@@ -37,7 +49,7 @@ func UploadImage(c *fiber.Ctx) error {
 	}
 
 	generateFilename := tempFileName(file.Filename)
-	filePath := filepath.Join(os.TempDir(), "image_server", generateFilename)
+	filePath := imagePath(generateFilename)
 
 	if err = c.SaveFile(file, filePath); err != nil {
 		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
@@ -50,7 +62,7 @@ func UploadImage(c *fiber.Ctx) error {
 	return c.JSON(fiber.Map{
 		"status": true,
 		"msg":    "success upload image",
-		"data":   fmt.Sprintf("%s/api/v1/download/%s", c.BaseURL(), generateFilename),
+		"data":   fmt.Sprintf("%s%s%s", c.BaseURL(), downloadPath, generateFilename),
 	})
 }
 
@@ -62,3 +74,28 @@ func tempFileName(fileName string) string {
 	prefix := fileName[:len(fileName)-len(filepath.Ext(fileName))]
 	return fmt.Sprint(prefix + "-" + hex.EncodeToString(randBytes) + suffix)
 }
+
+type imageStore struct{}
+
+// NewImageStore gives the images uploaded through UploadImage.
+func NewImageStore() domain.ProductImageStore {
+	return imageStore{}
+}
+
+// Remove deletes the image of a download link, an image already gone is
+// not an error.
+func (imageStore) Remove(ctx context.Context, src string) error {
+	u, err := url.Parse(src)
+	if err != nil || !strings.HasPrefix(u.Path, downloadPath) {
+		return nil
+	}
+	fileName := path.Base(u.Path)
+	if fileName != strings.TrimPrefix(u.Path, downloadPath) || fileName == "." || fileName == ".." {
+		return nil
+	}
+
+	if err := os.Remove(imagePath(fileName)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
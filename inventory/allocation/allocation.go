@@ -0,0 +1,105 @@
+package allocation
+
+import (
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/fahmilukis/go-product-svc/domain"
+)
+
+const (
+	// Nearest takes the stock from the warehouse closest to the destination
+	// of the reservation.
+	Nearest = "nearest"
+	// MostStock takes the stock from the warehouse that has the most of it.
+	MostStock = "most-stock"
+	// Priority takes the stock from the warehouses in their priority order.
+	Priority = "priority"
+)
+
+const earthRadiusKm = 6371.0
+
+// New returns the strategy called name.
+func New(name string) (domain.AllocationStrategy, error) {
+	switch name {
+	case Nearest:
+		return nearest{}, nil
+	case MostStock:
+		return mostStock{}, nil
+	case Priority:
+		return priority{}, nil
+	default:
+		return nil, fmt.Errorf("unknown allocation strategy %q", name)
+	}
+}
+
+type priority struct{}
+
+func (priority) Rank(r domain.Reservation, candidates []domain.AllocationCandidate) []domain.AllocationCandidate {
+	return rank(candidates, nil)
+}
+
+type mostStock struct{}
+
+func (mostStock) Rank(r domain.Reservation, candidates []domain.AllocationCandidate) []domain.AllocationCandidate {
+	return rank(candidates, func(a, b domain.AllocationCandidate) bool {
+		return a.StockLevel.Available() > b.StockLevel.Available()
+	})
+}
+
+// nearest ranks the warehouses without coordinates last, and all of them by
+// priority when the reservation has no destination.
+type nearest struct{}
+
+func (nearest) Rank(r domain.Reservation, candidates []domain.AllocationCandidate) []domain.AllocationCandidate {
+	if r.Destination == nil {
+		return priority{}.Rank(r, candidates)
+	}
+
+	dist := make(map[string]float64, len(candidates))
+	for _, c := range candidates {
+		dist[c.Warehouse.ID] = math.Inf(1)
+		if loc := c.Warehouse.Location(); loc != nil {
+			dist[c.Warehouse.ID] = distance(*r.Destination, *loc)
+		}
+	}
+
+	return rank(candidates, func(a, b domain.AllocationCandidate) bool {
+		return dist[a.Warehouse.ID] < dist[b.Warehouse.ID]
+	})
+}
+
+// rank sorts a copy of the candidates with less, ties and a nil less go by
+// warehouse priority and then code so the order is always the same.
+func rank(candidates []domain.AllocationCandidate, less func(a, b domain.AllocationCandidate) bool) []domain.AllocationCandidate {
+	res := make([]domain.AllocationCandidate, len(candidates))
+	copy(res, candidates)
+
+	sort.SliceStable(res, func(i, j int) bool {
+		a, b := res[i], res[j]
+		if less != nil {
+			if less(a, b) {
+				return true
+			}
+			if less(b, a) {
+				return false
+			}
+		}
+		if a.Warehouse.Priority != b.Warehouse.Priority {
+			return a.Warehouse.Priority < b.Warehouse.Priority
+		}
+		return a.Warehouse.Code < b.Warehouse.Code
+	})
+	return res
+}
+
+// distance is the great-circle distance between a and b in kilometres.
+func distance(a, b domain.GeoPoint) float64 {
+	lat1, lat2 := a.Latitude*math.Pi/180, b.Latitude*math.Pi/180
+	dLat := lat2 - lat1
+	dLon := (b.Longitude - a.Longitude) * math.Pi / 180
+
+	h := math.Sin(dLat/2)*math.Sin(dLat/2) + math.Cos(lat1)*math.Cos(lat2)*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return 2 * earthRadiusKm * math.Asin(math.Sqrt(h))
+}
@@ -0,0 +1,63 @@
+package allocation_test
+
+import (
+	"testing"
+
+	"github.com/fahmilukis/go-product-svc/domain"
+	"github.com/fahmilukis/go-product-svc/inventory/allocation"
+	"github.com/stretchr/testify/assert"
+)
+
+func candidate(code string, priority int, available int64, lat, lon *float64) domain.AllocationCandidate {
+	return domain.AllocationCandidate{
+		StockLevel: domain.StockLevel{ID: "s-" + code, WarehouseID: code, OnHand: available},
+		Warehouse:  domain.Warehouse{ID: code, Code: code, Priority: priority, Latitude: lat, Longitude: lon},
+	}
+}
+
+func coord(v float64) *float64 {
+	return &v
+}
+
+func codes(candidates []domain.AllocationCandidate) []string {
+	res := make([]string, 0, len(candidates))
+	for _, c := range candidates {
+		res = append(res, c.Warehouse.Code)
+	}
+	return res
+}
+
+func TestAllocationStrategies(t *testing.T) {
+	candidates := []domain.AllocationCandidate{
+		// Jakarta, Surabaya and a warehouse without coordinates
+		candidate("jkt", 2, 5, coord(-6.2), coord(106.8)),
+		candidate("sby", 1, 20, coord(-7.25), coord(112.75)),
+		candidate("online", 0, 10, nil, nil),
+	}
+	// Bandung is closer to Jakarta
+	r := domain.Reservation{Quantity: 1, Destination: &domain.GeoPoint{Latitude: -6.9, Longitude: 107.6}}
+
+	tests := []struct {
+		name string
+		r    domain.Reservation
+		want []string
+	}{
+		{allocation.Priority, r, []string{"online", "sby", "jkt"}},
+		{allocation.MostStock, r, []string{"sby", "online", "jkt"}},
+		{allocation.Nearest, r, []string{"jkt", "sby", "online"}},
+		{allocation.Nearest, domain.Reservation{Quantity: 1}, []string{"online", "sby", "jkt"}},
+	}
+	for _, tt := range tests {
+		strategy, err := allocation.New(tt.name)
+		assert.NoError(t, err)
+		assert.Equal(t, tt.want, codes(strategy.Rank(tt.r, candidates)), tt.name)
+	}
+
+	// the candidates of the caller are left as they were
+	assert.Equal(t, []string{"jkt", "sby", "online"}, codes(candidates))
+}
+
+func TestAllocationUnknownStrategy(t *testing.T) {
+	_, err := allocation.New("random")
+	assert.Error(t, err)
+}
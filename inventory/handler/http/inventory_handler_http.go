@@ -0,0 +1,179 @@
+package handler
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/fahmilukis/go-product-svc/domain"
+	"github.com/gofiber/fiber/v2"
+)
+
+type InventoryHandler struct {
+	InventoryUC domain.InventoryUsecase
+}
+
+type setStockRequest struct {
+	// WarehouseID is the default warehouse when empty.
+	WarehouseID string  `json:"warehouse_id"`
+	VariantID   *string `json:"variant_id"`
+	OnHand      int64   `json:"on_hand"`
+}
+
+type reserveRequest struct {
+	VariantID *string `json:"variant_id"`
+	Quantity  int64   `json:"quantity"`
+	// TTLSeconds is how long the stock is held, the default applies when zero.
+	TTLSeconds int64 `json:"ttl_seconds"`
+	// Destination lets the nearest allocation pick the closest warehouse.
+	Destination *domain.GeoPoint `json:"destination"`
+}
+
+type stockLevelResponse struct {
+	domain.StockLevel
+	Available int64 `json:"available"`
+}
+
+func InventoryRoute(a *fiber.App, iuc domain.InventoryUsecase) {
+	handler := &InventoryHandler{
+		InventoryUC: iuc,
+	}
+
+	route := a.Group("/api/v1")
+
+	route.Get("/product/:id/stock", handler.GetStock)
+	route.Put("/product/:id/stock", handler.SetStock)
+	route.Post("/product/:id/reservations", handler.Reserve)
+	route.Get("/product/:id/reservations/:reservation_id", handler.GetReservation)
+	route.Post("/product/:id/reservations/:reservation_id/commit", handler.CommitReservation)
+	route.Post("/product/:id/reservations/:reservation_id/cancel", handler.CancelReservation)
+}
+
+func (h *InventoryHandler) GetStock(c *fiber.Ctx) error {
+	data, err := h.InventoryUC.GetStock(c.Context(), c.Params("id"))
+	if err != nil {
+		return c.Status(domain.StatusCode(err)).JSON(fiber.Map{
+			"status": false,
+			"msg":    err.Error(),
+		})
+	}
+
+	levels := make([]stockLevelResponse, 0, len(data))
+	for _, s := range data {
+		levels = append(levels, stockLevelResponse{StockLevel: s, Available: s.Available()})
+	}
+
+	return c.JSON(fiber.Map{
+		"status": true,
+		"msg":    "success get stock",
+		"data":   levels,
+	})
+}
+
+// SetStock sets the units on hand of the product, or of one of its variants
+// with variant_id, in a warehouse.
+func (h *InventoryHandler) SetStock(c *fiber.Ctx) error {
+	req := setStockRequest{}
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"status": false,
+			"msg":    err.Error(),
+		})
+	}
+
+	s := &domain.StockLevel{
+		WarehouseID: req.WarehouseID,
+		ProductID:   c.Params("id"),
+		VariantID:   req.VariantID,
+		OnHand:      req.OnHand,
+	}
+	if err := h.InventoryUC.SetStock(c.Context(), s); err != nil {
+		return c.Status(domain.StatusCode(err)).JSON(fiber.Map{
+			"status": false,
+			"msg":    err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"status": true,
+		"msg":    "success set stock",
+		"data":   stockLevelResponse{StockLevel: *s, Available: s.Available()},
+	})
+}
+
+// Reserve holds stock for an open cart, 409 means there isn't enough left.
+func (h *InventoryHandler) Reserve(c *fiber.Ctx) error {
+	req := reserveRequest{}
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"status": false,
+			"msg":    err.Error(),
+		})
+	}
+
+	r := &domain.Reservation{
+		ProductID:   c.Params("id"),
+		VariantID:   req.VariantID,
+		Quantity:    req.Quantity,
+		Destination: req.Destination,
+	}
+	if err := h.InventoryUC.Reserve(c.Context(), r, time.Duration(req.TTLSeconds)*time.Second); err != nil {
+		return c.Status(domain.StatusCode(err)).JSON(fiber.Map{
+			"status": false,
+			"msg":    err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"status": true,
+		"msg":    "success reserve stock",
+		"data":   r,
+	})
+}
+
+func (h *InventoryHandler) GetReservation(c *fiber.Ctx) error {
+	data, err := h.InventoryUC.GetReservation(c.Context(), c.Params("id"), c.Params("reservation_id"))
+	if err != nil {
+		return c.Status(domain.StatusCode(err)).JSON(fiber.Map{
+			"status": false,
+			"msg":    err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"status": true,
+		"msg":    "success get reservation",
+		"data":   data,
+	})
+}
+
+func (h *InventoryHandler) CommitReservation(c *fiber.Ctx) error {
+	data, err := h.InventoryUC.Commit(c.Context(), c.Params("id"), c.Params("reservation_id"))
+	if err != nil {
+		return c.Status(domain.StatusCode(err)).JSON(fiber.Map{
+			"status": false,
+			"msg":    err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"status": true,
+		"msg":    "success commit reservation",
+		"data":   data,
+	})
+}
+
+func (h *InventoryHandler) CancelReservation(c *fiber.Ctx) error {
+	data, err := h.InventoryUC.Cancel(c.Context(), c.Params("id"), c.Params("reservation_id"))
+	if err != nil {
+		return c.Status(domain.StatusCode(err)).JSON(fiber.Map{
+			"status": false,
+			"msg":    err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"status": true,
+		"msg":    "success cancel reservation",
+		"data":   data,
+	})
+}
@@ -0,0 +1,129 @@
+package handler
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/fahmilukis/go-product-svc/domain"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+type WarehouseHandler struct {
+	WarehouseUC domain.WarehouseUsecase
+}
+
+func WarehouseRoute(a *fiber.App, wuc domain.WarehouseUsecase) {
+	handler := &WarehouseHandler{
+		WarehouseUC: wuc,
+	}
+
+	route := a.Group("/api/v1")
+
+	route.Post("/warehouse", handler.CreateWarehouse)
+	route.Get("/warehouse", handler.GetListWarehouses)
+	route.Get("/warehouse/:id", handler.GetWarehouseDetail)
+	route.Put("/warehouse/:id", handler.UpdateWarehouse)
+	route.Delete("/warehouse/:id", handler.DeleteWarehouse)
+}
+
+func (h *WarehouseHandler) CreateWarehouse(c *fiber.Ctx) error {
+	w := &domain.Warehouse{}
+	if err := c.BodyParser(w); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"status": false,
+			"msg":    err.Error(),
+		})
+	}
+	now := time.Now()
+
+	w.ID = uuid.NewString()
+	w.CreatedAt = now
+	w.UpdatedAt = now
+
+	if err := h.WarehouseUC.Store(c.Context(), w); err != nil {
+		return c.Status(domain.StatusCode(err)).JSON(fiber.Map{
+			"status": false,
+			"msg":    err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"status": true,
+		"msg":    "success add warehouse",
+		"data":   w,
+	})
+}
+
+func (h *WarehouseHandler) GetListWarehouses(c *fiber.Ctx) error {
+	data, err := h.WarehouseUC.Fetch(c.Context())
+	if err != nil {
+		return c.Status(domain.StatusCode(err)).JSON(fiber.Map{
+			"status": false,
+			"msg":    err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"status": true,
+		"msg":    "success get warehouse lists",
+		"data":   data,
+	})
+}
+
+func (h *WarehouseHandler) GetWarehouseDetail(c *fiber.Ctx) error {
+	data, err := h.WarehouseUC.GetByID(c.Context(), c.Params("id"))
+	if err != nil {
+		return c.Status(domain.StatusCode(err)).JSON(fiber.Map{
+			"status": false,
+			"msg":    err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"status": true,
+		"msg":    "success get warehouse",
+		"data":   data,
+	})
+}
+
+func (h *WarehouseHandler) UpdateWarehouse(c *fiber.Ctx) error {
+	w := &domain.Warehouse{}
+	if err := c.BodyParser(w); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"status": false,
+			"msg":    err.Error(),
+		})
+	}
+	w.ID = c.Params("id")
+	w.UpdatedAt = time.Now()
+
+	if err := h.WarehouseUC.Update(c.Context(), w); err != nil {
+		return c.Status(domain.StatusCode(err)).JSON(fiber.Map{
+			"status": false,
+			"msg":    err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"status": true,
+		"msg":    "success update warehouse",
+		"data":   w,
+	})
+}
+
+// DeleteWarehouse answers 409 for the default warehouse and for one that
+// still holds stock.
+func (h *WarehouseHandler) DeleteWarehouse(c *fiber.Ctx) error {
+	if err := h.WarehouseUC.Delete(c.Context(), c.Params("id")); err != nil {
+		return c.Status(domain.StatusCode(err)).JSON(fiber.Map{
+			"status": false,
+			"msg":    err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"status": true,
+		"msg":    "success delete warehouse",
+	})
+}
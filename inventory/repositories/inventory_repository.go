@@ -0,0 +1,296 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/fahmilukis/go-product-svc/domain"
+	pkg "github.com/fahmilukis/go-product-svc/pkg/utils"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	stockLevelColumns  = `id,warehouse_id,product_id,variant_id,on_hand,reserved,updated_at`
+	reservationColumns = `id,product_id,variant_id,warehouse_id,stock_level_id,quantity,status,expires_at,created_at,updated_at`
+)
+
+type inventoryDBRepositories struct {
+	Conn *sql.DB
+}
+
+func NewInventoryDBRepository(conn *sql.DB) *inventoryDBRepositories {
+	return &inventoryDBRepositories{Conn: conn}
+}
+
+func (p *inventoryDBRepositories) FetchStock(ctx context.Context, productID string) (res []domain.StockLevel, err error) {
+	query := `SELECT ` + stockLevelColumns + ` FROM stock_levels WHERE product_id=$1 ORDER BY variant_id NULLS FIRST, warehouse_id`
+	rows, err := p.Conn.QueryContext(ctx, query, productID)
+	if err != nil {
+		logrus.Error(err)
+		return nil, err
+	}
+
+	defer func() {
+		errRow := rows.Close()
+		if errRow != nil {
+			logrus.Error(err)
+		}
+	}()
+
+	res = make([]domain.StockLevel, 0)
+	for rows.Next() {
+		s := domain.StockLevel{}
+		err = rows.Scan(
+			&s.ID,
+			&s.WarehouseID,
+			&s.ProductID,
+			&s.VariantID,
+			&s.OnHand,
+			&s.Reserved,
+			&s.UpdatedAt,
+		)
+		if err != nil {
+			logrus.Error(err)
+			return nil, err
+		}
+		res = append(res, s)
+	}
+
+	return res, nil
+}
+
+// SetStock creates the stock level of the product or variant in the
+// warehouse, or replaces its units on hand.
+func (p *inventoryDBRepositories) SetStock(ctx context.Context, s *domain.StockLevel) (err error) {
+	query := `INSERT INTO stock_levels (warehouse_id,product_id,variant_id,on_hand,updated_at) VALUES ($1, $2, $3, $4, $5)
+	ON CONFLICT (warehouse_id, product_id, (COALESCE(variant_id, '00000000-0000-0000-0000-000000000000'::uuid)))
+	DO UPDATE SET on_hand = EXCLUDED.on_hand, updated_at = EXCLUDED.updated_at RETURNING id, reserved`
+
+	err = p.Conn.QueryRowContext(ctx, query, s.WarehouseID, s.ProductID, s.VariantID, s.OnHand, s.UpdatedAt).Scan(&s.ID, &s.Reserved)
+	switch {
+	case pkg.IsCheckViolation(err):
+		return domain.ErrConflict
+	case pkg.IsForeignKeyViolation(err):
+		return domain.ErrNotFound
+	}
+	return
+}
+
+// AvailableToPromise adds up the available stock of the product and its
+// variants per warehouse.
+func (p *inventoryDBRepositories) AvailableToPromise(ctx context.Context, productID string) (res domain.AvailableToPromise, err error) {
+	query := `SELECT w.id, w.code, sum(s.on_hand - s.reserved)
+	FROM stock_levels s JOIN warehouses w ON w.id = s.warehouse_id
+	WHERE s.product_id=$1 GROUP BY w.id, w.code, w.priority ORDER BY w.priority, w.code`
+	rows, err := p.Conn.QueryContext(ctx, query, productID)
+	if err != nil {
+		logrus.Error(err)
+		return res, err
+	}
+
+	defer func() {
+		errRow := rows.Close()
+		if errRow != nil {
+			logrus.Error(err)
+		}
+	}()
+
+	res.Warehouses = make([]domain.WarehouseAvailability, 0)
+	for rows.Next() {
+		w := domain.WarehouseAvailability{}
+		if err = rows.Scan(&w.WarehouseID, &w.Code, &w.Available); err != nil {
+			logrus.Error(err)
+			return domain.AvailableToPromise{}, err
+		}
+		res.Available += w.Available
+		res.Warehouses = append(res.Warehouses, w)
+	}
+
+	return res, nil
+}
+
+// Reserve locks every stock level of the item, in id order, so concurrent
+// reservations of the same item queue up instead of both seeing the same
+// available stock. The strategy then picks the warehouse.
+func (p *inventoryDBRepositories) Reserve(ctx context.Context, r *domain.Reservation, strategy domain.AllocationStrategy) (err error) {
+	tx, err := p.Conn.BeginTx(ctx, nil)
+	if err != nil {
+		return
+	}
+	defer func() {
+		if err != nil {
+			if errRollback := tx.Rollback(); errRollback != nil {
+				logrus.Error(errRollback)
+			}
+		}
+	}()
+
+	candidates, err := lockCandidates(ctx, tx, r)
+	if err != nil {
+		return
+	}
+
+	var picked *domain.AllocationCandidate
+	for _, c := range strategy.Rank(*r, candidates) {
+		if c.StockLevel.Available() >= r.Quantity {
+			picked = &c
+			break
+		}
+	}
+	if picked == nil {
+		// no single warehouse can fill it, an item without stock level
+		// included
+		return domain.ErrConflict
+	}
+	r.StockLevelID = picked.StockLevel.ID
+	r.WarehouseID = picked.Warehouse.ID
+
+	_, err = tx.ExecContext(ctx, `UPDATE stock_levels SET reserved = reserved + $1 , updated_at=$2 WHERE id=$3`,
+		r.Quantity, r.CreatedAt, r.StockLevelID)
+	if err != nil {
+		return
+	}
+
+	err = tx.QueryRowContext(ctx, `INSERT INTO stock_reservations (product_id,variant_id,warehouse_id,stock_level_id,quantity,status,expires_at,created_at,updated_at)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9) RETURNING id`,
+		r.ProductID, r.VariantID, r.WarehouseID, r.StockLevelID, r.Quantity, r.Status, r.ExpiresAt, r.CreatedAt, r.UpdatedAt).Scan(&r.ID)
+	if err != nil {
+		return
+	}
+
+	return tx.Commit()
+}
+
+func (p *inventoryDBRepositories) GetReservation(ctx context.Context, productID, id string) (res domain.Reservation, err error) {
+	query := `SELECT ` + reservationColumns + ` FROM stock_reservations WHERE product_id=$1 AND id=$2`
+	res, err = scanReservation(p.Conn.QueryRowContext(ctx, query, productID, id))
+	if err == sql.ErrNoRows {
+		return res, domain.ErrNotFound
+	}
+	return
+}
+
+// Settle locks the reservation before its stock level, the same order the
+// expiry uses, so the two never deadlock. Only a pending reservation that
+// hasn't expired yet can be settled.
+func (p *inventoryDBRepositories) Settle(ctx context.Context, productID, id string, status domain.ReservationStatus, at time.Time) (res domain.Reservation, err error) {
+	tx, err := p.Conn.BeginTx(ctx, nil)
+	if err != nil {
+		return
+	}
+	defer func() {
+		if err != nil {
+			if errRollback := tx.Rollback(); errRollback != nil {
+				logrus.Error(errRollback)
+			}
+		}
+	}()
+
+	query := `SELECT ` + reservationColumns + ` FROM stock_reservations WHERE product_id=$1 AND id=$2 FOR UPDATE`
+	res, err = scanReservation(tx.QueryRowContext(ctx, query, productID, id))
+	if err == sql.ErrNoRows {
+		return res, domain.ErrNotFound
+	}
+	if err != nil {
+		return
+	}
+	if res.Status != domain.ReservationPending || !res.ExpiresAt.After(at) {
+		return res, domain.ErrConflict
+	}
+
+	var shipped int64
+	if status == domain.ReservationCommitted {
+		shipped = res.Quantity
+	}
+	_, err = tx.ExecContext(ctx, `UPDATE stock_levels SET reserved = reserved - $1 , on_hand = on_hand - $2 , updated_at=$3 WHERE id=$4`,
+		res.Quantity, shipped, at, res.StockLevelID)
+	if err != nil {
+		return
+	}
+
+	_, err = tx.ExecContext(ctx, `UPDATE stock_reservations SET status=$1 , updated_at=$2 WHERE id=$3`, status, at, res.ID)
+	if err != nil {
+		return
+	}
+	res.Status = status
+	res.UpdatedAt = at
+
+	err = tx.Commit()
+	return
+}
+
+// ReleaseExpired expires the pending reservations past their deadline and
+// gives their units back in a single statement.
+func (p *inventoryDBRepositories) ReleaseExpired(ctx context.Context, now time.Time) (released int64, err error) {
+	query := `WITH expired AS (
+		UPDATE stock_reservations SET status = 'expired', updated_at = $1
+		WHERE status = 'pending' AND expires_at <= $1
+		RETURNING stock_level_id, quantity
+	), released AS (
+		UPDATE stock_levels s SET reserved = s.reserved - e.quantity, updated_at = $1
+		FROM (SELECT stock_level_id, sum(quantity) AS quantity FROM expired GROUP BY stock_level_id) e
+		WHERE s.id = e.stock_level_id
+	)
+	SELECT count(*) FROM expired`
+
+	err = p.Conn.QueryRowContext(ctx, query, now).Scan(&released)
+	return
+}
+
+func lockCandidates(ctx context.Context, tx *sql.Tx, r *domain.Reservation) (res []domain.AllocationCandidate, err error) {
+	query := `SELECT s.id, s.on_hand, s.reserved, w.id, w.code, w.name, w.latitude, w.longitude, w.priority
+	FROM stock_levels s JOIN warehouses w ON w.id = s.warehouse_id
+	WHERE s.product_id=$1 AND s.variant_id IS NOT DISTINCT FROM $2 ORDER BY s.id FOR UPDATE OF s`
+	rows, err := tx.QueryContext(ctx, query, r.ProductID, r.VariantID)
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() {
+		errRow := rows.Close()
+		if errRow != nil {
+			logrus.Error(err)
+		}
+	}()
+
+	for rows.Next() {
+		c := domain.AllocationCandidate{}
+		err = rows.Scan(
+			&c.StockLevel.ID,
+			&c.StockLevel.OnHand,
+			&c.StockLevel.Reserved,
+			&c.Warehouse.ID,
+			&c.Warehouse.Code,
+			&c.Warehouse.Name,
+			&c.Warehouse.Latitude,
+			&c.Warehouse.Longitude,
+			&c.Warehouse.Priority,
+		)
+		if err != nil {
+			return nil, err
+		}
+		c.StockLevel.WarehouseID = c.Warehouse.ID
+		c.StockLevel.ProductID = r.ProductID
+		c.StockLevel.VariantID = r.VariantID
+		res = append(res, c)
+	}
+
+	return res, rows.Err()
+}
+
+func scanReservation(row *sql.Row) (res domain.Reservation, err error) {
+	err = row.Scan(
+		&res.ID,
+		&res.ProductID,
+		&res.VariantID,
+		&res.WarehouseID,
+		&res.StockLevelID,
+		&res.Quantity,
+		&res.Status,
+		&res.ExpiresAt,
+		&res.CreatedAt,
+		&res.UpdatedAt,
+	)
+	return
+}
@@ -0,0 +1,167 @@
+package repositories_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/fahmilukis/go-product-svc/domain"
+	"github.com/fahmilukis/go-product-svc/inventory/allocation"
+	"github.com/fahmilukis/go-product-svc/inventory/repositories"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/DATA-DOG/go-sqlmock.v1"
+)
+
+var (
+	candidateColumns   = []string{"id", "on_hand", "reserved", "id", "code", "name", "latitude", "longitude", "priority"}
+	reservationColumns = []string{"id", "product_id", "variant_id", "warehouse_id", "stock_level_id", "quantity", "status", "expires_at", "created_at", "updated_at"}
+)
+
+func newStrategy(t *testing.T, name string) domain.AllocationStrategy {
+	strategy, err := allocation.New(name)
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when creating the strategy", err)
+	}
+	return strategy
+}
+
+func TestReserveRepositoryInventory(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	now := time.Now()
+	r := &domain.Reservation{
+		ProductID: "p1",
+		Quantity:  3,
+		Status:    domain.ReservationPending,
+		ExpiresAt: now.Add(15 * time.Minute),
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+
+	// the first warehouse by priority is short, the reservation goes to the
+	// next one
+	rows := sqlmock.NewRows(candidateColumns).
+		AddRow("s1", 5, 3, "w1", "default", "Default", nil, nil, 0).
+		AddRow("s2", 4, 0, "w2", "sby", "Surabaya", -7.25, 112.75, 1)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`FROM stock_levels s JOIN warehouses w .* FOR UPDATE OF s`).WithArgs("p1", nil).WillReturnRows(rows)
+	mock.ExpectExec(`UPDATE stock_levels SET reserved = reserved \+ \$1`).WithArgs(int64(3), now, "s2").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(`INSERT INTO stock_reservations`).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow("r1"))
+	mock.ExpectCommit()
+
+	a := repositories.NewInventoryDBRepository(db)
+	err = a.Reserve(context.TODO(), r, newStrategy(t, allocation.Priority))
+	assert.NoError(t, err)
+	assert.Equal(t, "r1", r.ID)
+	assert.Equal(t, "s2", r.StockLevelID)
+	assert.Equal(t, "w2", r.WarehouseID)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestReserveRepositoryInventoryOversell(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	r := &domain.Reservation{ProductID: "p1", Quantity: 3}
+
+	// 4 units are left in total but no single warehouse has 3
+	rows := sqlmock.NewRows(candidateColumns).
+		AddRow("s1", 5, 3, "w1", "default", "Default", nil, nil, 0).
+		AddRow("s2", 2, 0, "w2", "sby", "Surabaya", -7.25, 112.75, 1)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`FROM stock_levels s JOIN warehouses w .* FOR UPDATE OF s`).WillReturnRows(rows)
+	mock.ExpectRollback()
+
+	a := repositories.NewInventoryDBRepository(db)
+	err = a.Reserve(context.TODO(), r, newStrategy(t, allocation.MostStock))
+	assert.Equal(t, domain.ErrConflict, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSettleRepositoryInventoryCommit(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	now := time.Now()
+
+	rows := sqlmock.NewRows(reservationColumns).
+		AddRow("r1", "p1", nil, "w1", "s1", 2, "pending", now.Add(time.Minute), now, now)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`FROM stock_reservations WHERE product_id=\$1 AND id=\$2 FOR UPDATE`).WithArgs("p1", "r1").WillReturnRows(rows)
+	mock.ExpectExec(`UPDATE stock_levels SET reserved = reserved - \$1 , on_hand = on_hand - \$2`).WithArgs(int64(2), int64(2), now, "s1").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`UPDATE stock_reservations SET status=\$1`).WithArgs(domain.ReservationCommitted, now, "r1").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	a := repositories.NewInventoryDBRepository(db)
+	res, err := a.Settle(context.TODO(), "p1", "r1", domain.ReservationCommitted, now)
+	assert.NoError(t, err)
+	assert.Equal(t, domain.ReservationCommitted, res.Status)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSettleRepositoryInventoryExpired(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	now := time.Now()
+
+	rows := sqlmock.NewRows(reservationColumns).
+		AddRow("r1", "p1", nil, "w1", "s1", 2, "pending", now.Add(-time.Minute), now, now)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`FROM stock_reservations WHERE product_id=\$1 AND id=\$2 FOR UPDATE`).WillReturnRows(rows)
+	mock.ExpectRollback()
+
+	a := repositories.NewInventoryDBRepository(db)
+	_, err = a.Settle(context.TODO(), "p1", "r1", domain.ReservationCancelled, now)
+	assert.Equal(t, domain.ErrConflict, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestReleaseExpiredRepositoryInventory(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	now := time.Now()
+
+	mock.ExpectQuery(`UPDATE stock_reservations SET status = 'expired'`).WithArgs(now).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(3))
+
+	a := repositories.NewInventoryDBRepository(db)
+	released, err := a.ReleaseExpired(context.TODO(), now)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), released)
+}
+
+func TestAvailableToPromiseRepositoryInventory(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	rows := sqlmock.NewRows([]string{"id", "code", "sum"}).
+		AddRow("w1", "default", 2).
+		AddRow("w2", "sby", 7)
+
+	mock.ExpectQuery(`SELECT w.id, w.code, sum\(s.on_hand - s.reserved\)`).WithArgs("p1").WillReturnRows(rows)
+
+	a := repositories.NewInventoryDBRepository(db)
+	atp, err := a.AvailableToPromise(context.TODO(), "p1")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(9), atp.Available)
+	assert.Len(t, atp.Warehouses, 2)
+	assert.Equal(t, "sby", atp.Warehouses[1].Code)
+}
@@ -0,0 +1,136 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/fahmilukis/go-product-svc/domain"
+	pkg "github.com/fahmilukis/go-product-svc/pkg/utils"
+	"github.com/sirupsen/logrus"
+)
+
+const warehouseColumns = `id,code,name,latitude,longitude,priority,created_at,updated_at`
+
+type warehouseDBRepositories struct {
+	Conn *sql.DB
+}
+
+func NewWarehouseDBRepository(conn *sql.DB) *warehouseDBRepositories {
+	return &warehouseDBRepositories{Conn: conn}
+}
+
+// fetch to DB
+func (p *warehouseDBRepositories) fetch(ctx context.Context, query string, args ...interface{}) (res []domain.Warehouse, err error) {
+	rows, err := p.Conn.QueryContext(ctx, query, args...)
+	if err != nil {
+		logrus.Error(err)
+		return nil, err
+	}
+
+	defer func() {
+		errRow := rows.Close()
+		if errRow != nil {
+			logrus.Error(err)
+		}
+	}()
+
+	res = make([]domain.Warehouse, 0)
+	for rows.Next() {
+		w := domain.Warehouse{}
+		err = rows.Scan(
+			&w.ID,
+			&w.Code,
+			&w.Name,
+			&w.Latitude,
+			&w.Longitude,
+			&w.Priority,
+			&w.CreatedAt,
+			&w.UpdatedAt,
+		)
+		if err != nil {
+			logrus.Error(err)
+			return nil, err
+		}
+		res = append(res, w)
+	}
+
+	return res, nil
+}
+
+func (p *warehouseDBRepositories) Fetch(ctx context.Context) (res []domain.Warehouse, err error) {
+	query := `SELECT ` + warehouseColumns + ` FROM warehouses ORDER BY priority, code`
+	return p.fetch(ctx, query)
+}
+
+func (p *warehouseDBRepositories) GetByID(ctx context.Context, id string) (res domain.Warehouse, err error) {
+	query := `SELECT ` + warehouseColumns + ` FROM warehouses WHERE id=$1`
+	return p.getOne(ctx, query, id)
+}
+
+func (p *warehouseDBRepositories) GetByCode(ctx context.Context, code string) (res domain.Warehouse, err error) {
+	query := `SELECT ` + warehouseColumns + ` FROM warehouses WHERE code=$1`
+	return p.getOne(ctx, query, code)
+}
+
+func (p *warehouseDBRepositories) getOne(ctx context.Context, query string, args ...interface{}) (res domain.Warehouse, err error) {
+	list, err := p.fetch(ctx, query, args...)
+	if err != nil {
+		return domain.Warehouse{}, err
+	}
+
+	if len(list) > 0 {
+		res = list[0]
+	} else {
+		return res, domain.ErrNotFound
+	}
+
+	return
+}
+
+func (p *warehouseDBRepositories) Store(ctx context.Context, w *domain.Warehouse) (err error) {
+	query := `INSERT INTO warehouses (id,code,name,latitude,longitude,priority,created_at,updated_at)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`
+	_, err = p.Conn.ExecContext(ctx, query, w.ID, w.Code, w.Name, w.Latitude, w.Longitude, w.Priority, w.CreatedAt, w.UpdatedAt)
+	if pkg.IsUniqueViolation(err) {
+		return domain.ErrConflict
+	}
+	return
+}
+
+func (p *warehouseDBRepositories) Update(ctx context.Context, w *domain.Warehouse) (err error) {
+	query := `UPDATE warehouses SET code=$1 , name=$2 , latitude=$3 , longitude=$4 , priority=$5 , updated_at=$6 WHERE id=$7`
+	res, err := p.Conn.ExecContext(ctx, query, w.Code, w.Name, w.Latitude, w.Longitude, w.Priority, w.UpdatedAt, w.ID)
+	if pkg.IsUniqueViolation(err) {
+		return domain.ErrConflict
+	}
+	if err != nil {
+		return
+	}
+
+	return requireAffected(res)
+}
+
+// Delete refuses a warehouse that stock levels still point at.
+func (p *warehouseDBRepositories) Delete(ctx context.Context, id string) (err error) {
+	res, err := p.Conn.ExecContext(ctx, `DELETE FROM warehouses WHERE id = $1`, id)
+	if pkg.IsForeignKeyViolation(err) {
+		return domain.ErrConflict
+	}
+	if err != nil {
+		return
+	}
+
+	return requireAffected(res)
+}
+
+// requireAffected turns a statement that touched no row into ErrNotFound.
+func requireAffected(res sql.Result) error {
+	rowsAfected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAfected == 0 {
+		return domain.ErrNotFound
+	}
+	return nil
+}
@@ -0,0 +1,48 @@
+package repositories_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/fahmilukis/go-product-svc/domain"
+	"github.com/fahmilukis/go-product-svc/inventory/repositories"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/DATA-DOG/go-sqlmock.v1"
+)
+
+func TestFetchRepositoryWarehouse(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	now := time.Now()
+
+	rows := sqlmock.NewRows([]string{"id", "code", "name", "latitude", "longitude", "priority", "created_at", "updated_at"}).
+		AddRow("w1", "default", "Default", nil, nil, 0, now, now).
+		AddRow("w2", "sby", "Surabaya", -7.25, 112.75, 1, now, now)
+
+	mock.ExpectQuery(`FROM warehouses ORDER BY priority, code`).WillReturnRows(rows)
+
+	a := repositories.NewWarehouseDBRepository(db)
+	list, err := a.Fetch(context.TODO())
+	assert.NoError(t, err)
+	assert.Len(t, list, 2)
+	assert.Nil(t, list[0].Location())
+	assert.Equal(t, &domain.GeoPoint{Latitude: -7.25, Longitude: 112.75}, list[1].Location())
+}
+
+func TestDeleteRepositoryWarehouseInUse(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	mock.ExpectExec(`DELETE FROM warehouses WHERE id = \$1`).WithArgs("w2").
+		WillReturnError(&pq.Error{Code: "23503", Constraint: "stock_levels_warehouse_id_fkey"})
+
+	a := repositories.NewWarehouseDBRepository(db)
+	err = a.Delete(context.TODO(), "w2")
+	assert.Equal(t, domain.ErrConflict, err)
+}
@@ -0,0 +1,145 @@
+package usecases
+
+import (
+	"context"
+	"time"
+
+	"github.com/fahmilukis/go-product-svc/domain"
+)
+
+const (
+	// DefaultReservationTTL is how long a reservation holds stock when the
+	// caller doesn't say.
+	DefaultReservationTTL = 15 * time.Minute
+	maxReservationTTL     = 24 * time.Hour
+)
+
+type inventoryUsecase struct {
+	productRepository   domain.ProductRepository
+	variantRepository   domain.VariantRepository
+	inventoryRepository domain.InventoryRepository
+	warehouseRepository domain.WarehouseRepository
+	allocation          domain.AllocationStrategy
+	ctxTimeout          time.Duration
+}
+
+func NewInventoryUsecase(p domain.ProductRepository, v domain.VariantRepository, i domain.InventoryRepository, w domain.WarehouseRepository, a domain.AllocationStrategy, to time.Duration) domain.InventoryUsecase {
+	return &inventoryUsecase{
+		productRepository:   p,
+		variantRepository:   v,
+		inventoryRepository: i,
+		warehouseRepository: w,
+		allocation:          a,
+		ctxTimeout:          to,
+	}
+}
+
+func (u *inventoryUsecase) GetStock(c context.Context, productID string) (res []domain.StockLevel, err error) {
+	ctx, cancel := context.WithTimeout(c, u.ctxTimeout)
+	defer cancel()
+
+	if _, err = u.productRepository.GetByID(ctx, productID, domain.ProductFilter{}); err != nil {
+		return nil, err
+	}
+
+	return u.inventoryRepository.FetchStock(ctx, productID)
+}
+
+func (u *inventoryUsecase) SetStock(c context.Context, s *domain.StockLevel) (err error) {
+	ctx, cancel := context.WithTimeout(c, u.ctxTimeout)
+	defer cancel()
+
+	if s.OnHand < 0 {
+		return domain.ErrBadParamInput
+	}
+	if err = u.checkItem(ctx, s.ProductID, s.VariantID); err != nil {
+		return
+	}
+
+	var w domain.Warehouse
+	if s.WarehouseID == "" {
+		w, err = u.warehouseRepository.GetByCode(ctx, domain.DefaultWarehouseCode)
+	} else {
+		w, err = u.warehouseRepository.GetByID(ctx, s.WarehouseID)
+	}
+	if err != nil {
+		return
+	}
+	s.WarehouseID = w.ID
+	s.UpdatedAt = time.Now()
+
+	return u.inventoryRepository.SetStock(ctx, s)
+}
+
+func (u *inventoryUsecase) AvailableToPromise(c context.Context, productID string) (res domain.AvailableToPromise, err error) {
+	ctx, cancel := context.WithTimeout(c, u.ctxTimeout)
+	defer cancel()
+
+	return u.inventoryRepository.AvailableToPromise(ctx, productID)
+}
+
+func (u *inventoryUsecase) Reserve(c context.Context, r *domain.Reservation, ttl time.Duration) (err error) {
+	ctx, cancel := context.WithTimeout(c, u.ctxTimeout)
+	defer cancel()
+
+	if r.Quantity <= 0 || ttl < 0 || ttl > maxReservationTTL {
+		return domain.ErrBadParamInput
+	}
+	if ttl == 0 {
+		ttl = DefaultReservationTTL
+	}
+	if err = u.checkItem(ctx, r.ProductID, r.VariantID); err != nil {
+		return
+	}
+
+	now := time.Now()
+	r.Status = domain.ReservationPending
+	r.ExpiresAt = now.Add(ttl)
+	r.CreatedAt = now
+	r.UpdatedAt = now
+
+	return u.inventoryRepository.Reserve(ctx, r, u.allocation)
+}
+
+func (u *inventoryUsecase) GetReservation(c context.Context, productID, id string) (res domain.Reservation, err error) {
+	ctx, cancel := context.WithTimeout(c, u.ctxTimeout)
+	defer cancel()
+
+	return u.inventoryRepository.GetReservation(ctx, productID, id)
+}
+
+// Commit turns the reservation into a sale, its units leave the stock on hand.
+func (u *inventoryUsecase) Commit(c context.Context, productID, id string) (res domain.Reservation, err error) {
+	ctx, cancel := context.WithTimeout(c, u.ctxTimeout)
+	defer cancel()
+
+	return u.inventoryRepository.Settle(ctx, productID, id, domain.ReservationCommitted, time.Now())
+}
+
+// Cancel gives the reserved units back.
+func (u *inventoryUsecase) Cancel(c context.Context, productID, id string) (res domain.Reservation, err error) {
+	ctx, cancel := context.WithTimeout(c, u.ctxTimeout)
+	defer cancel()
+
+	return u.inventoryRepository.Settle(ctx, productID, id, domain.ReservationCancelled, time.Now())
+}
+
+func (u *inventoryUsecase) ReleaseExpired(c context.Context) (released int64, err error) {
+	ctx, cancel := context.WithTimeout(c, u.ctxTimeout)
+	defer cancel()
+
+	return u.inventoryRepository.ReleaseExpired(ctx, time.Now())
+}
+
+// checkItem makes sure the product is live and owns the variant, if any.
+func (u *inventoryUsecase) checkItem(ctx context.Context, productID string, variantID *string) error {
+	if _, err := u.productRepository.GetByID(ctx, productID, domain.ProductFilter{}); err != nil {
+		return err
+	}
+	if variantID == nil {
+		return nil
+	}
+
+	_, err := u.variantRepository.GetByID(ctx, productID, *variantID)
+	return err
+}
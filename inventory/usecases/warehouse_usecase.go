@@ -0,0 +1,99 @@
+package usecases
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/fahmilukis/go-product-svc/domain"
+)
+
+type warehouseUsecase struct {
+	warehouseRepository domain.WarehouseRepository
+	ctxTimeout          time.Duration
+}
+
+func NewWarehouseUsecase(w domain.WarehouseRepository, to time.Duration) domain.WarehouseUsecase {
+	return &warehouseUsecase{
+		warehouseRepository: w,
+		ctxTimeout:          to,
+	}
+}
+
+func (u *warehouseUsecase) Fetch(c context.Context) (res []domain.Warehouse, err error) {
+	ctx, cancel := context.WithTimeout(c, u.ctxTimeout)
+	defer cancel()
+
+	return u.warehouseRepository.Fetch(ctx)
+}
+
+func (u *warehouseUsecase) GetByID(c context.Context, id string) (res domain.Warehouse, err error) {
+	ctx, cancel := context.WithTimeout(c, u.ctxTimeout)
+	defer cancel()
+
+	return u.warehouseRepository.GetByID(ctx, id)
+}
+
+func (u *warehouseUsecase) Store(c context.Context, w *domain.Warehouse) (err error) {
+	ctx, cancel := context.WithTimeout(c, u.ctxTimeout)
+	defer cancel()
+
+	if err = validateWarehouse(w); err != nil {
+		return
+	}
+
+	return u.warehouseRepository.Store(ctx, w)
+}
+
+// Update keeps the code of the default warehouse, the stock set without a
+// warehouse is found through it.
+func (u *warehouseUsecase) Update(c context.Context, w *domain.Warehouse) (err error) {
+	ctx, cancel := context.WithTimeout(c, u.ctxTimeout)
+	defer cancel()
+
+	if err = validateWarehouse(w); err != nil {
+		return
+	}
+	cur, err := u.warehouseRepository.GetByID(ctx, w.ID)
+	if err != nil {
+		return
+	}
+	if cur.Code == domain.DefaultWarehouseCode && w.Code != cur.Code {
+		return domain.ErrConflict
+	}
+	w.CreatedAt = cur.CreatedAt
+
+	return u.warehouseRepository.Update(ctx, w)
+}
+
+func (u *warehouseUsecase) Delete(c context.Context, id string) (err error) {
+	ctx, cancel := context.WithTimeout(c, u.ctxTimeout)
+	defer cancel()
+
+	cur, err := u.warehouseRepository.GetByID(ctx, id)
+	if err != nil {
+		return
+	}
+	if cur.Code == domain.DefaultWarehouseCode {
+		return domain.ErrConflict
+	}
+
+	return u.warehouseRepository.Delete(ctx, id)
+}
+
+// validateWarehouse wants a code and either both coordinates or none.
+func validateWarehouse(w *domain.Warehouse) error {
+	w.Code = strings.TrimSpace(w.Code)
+	if w.Code == "" || len(w.Code) > 64 || len(w.Name) > 255 {
+		return domain.ErrBadParamInput
+	}
+	if (w.Latitude == nil) != (w.Longitude == nil) {
+		return domain.ErrBadParamInput
+	}
+	if loc := w.Location(); loc != nil {
+		if loc.Latitude < -90 || loc.Latitude > 90 || loc.Longitude < -180 || loc.Longitude > 180 {
+			return domain.ErrBadParamInput
+		}
+	}
+	return nil
+}
@@ -0,0 +1,72 @@
+package handler
+
+import (
+	"github.com/fahmilukis/go-product-svc/domain"
+	"github.com/gofiber/fiber/v2"
+)
+
+type JobHandler struct {
+	JobUC domain.JobUsecase
+}
+
+func JobRoute(a *fiber.App, juc domain.JobUsecase) {
+	handler := &JobHandler{
+		JobUC: juc,
+	}
+
+	route := a.Group("/api/v1")
+
+	route.Get("/jobs/:id", handler.GetJobDetail)
+	route.Post("/jobs/:id/cancel", handler.CancelJob)
+	route.Get("/jobs/:id/output", handler.DownloadJobOutput)
+}
+
+// GetJobDetail shows the status, progress and result of a job, clients poll
+// it until the status is a final one.
+func (h *JobHandler) GetJobDetail(c *fiber.Ctx) error {
+	data, err := h.JobUC.GetByID(c.Context(), c.Params("id"))
+	if err != nil {
+		return c.Status(domain.StatusCode(err)).JSON(fiber.Map{
+			"status": false,
+			"msg":    err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"status": true,
+		"msg":    "success get job",
+		"data":   data,
+	})
+}
+
+func (h *JobHandler) CancelJob(c *fiber.Ctx) error {
+	data, err := h.JobUC.Cancel(c.Context(), c.Params("id"))
+	if err != nil {
+		return c.Status(domain.StatusCode(err)).JSON(fiber.Map{
+			"status": false,
+			"msg":    err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"status": true,
+		"msg":    "success cancel job",
+		"data":   data,
+	})
+}
+
+// DownloadJobOutput streams the file a succeeded job produced, whichever
+// instance ran the job.
+func (h *JobHandler) DownloadJobOutput(c *fiber.Ctx) error {
+	output, size, name, err := h.JobUC.OpenOutput(c.Context(), c.Params("id"))
+	if err != nil {
+		return c.Status(domain.StatusCode(err)).JSON(fiber.Map{
+			"status": false,
+			"msg":    err.Error(),
+		})
+	}
+
+	// the output is closed once sent
+	c.Attachment(name)
+	return c.SendStream(output, int(size))
+}
@@ -0,0 +1,139 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"io"
+
+	"github.com/fahmilukis/go-product-svc/domain"
+)
+
+// jobFileChunkSize is the size of the rows a job file is split in.
+const jobFileChunkSize = 1 << 20
+
+type jobFileDBRepositories struct {
+	Conn *sql.DB
+}
+
+func NewJobFileDBRepository(conn *sql.DB) *jobFileDBRepositories {
+	return &jobFileDBRepositories{Conn: conn}
+}
+
+// Create removes the chunks of a previous run, e.g. the output of a failed
+// attempt, before the writer adds its own.
+func (p *jobFileDBRepositories) Create(ctx context.Context, jobID, file string) (io.WriteCloser, error) {
+	if err := p.Remove(ctx, jobID, file); err != nil {
+		return nil, err
+	}
+
+	return &jobFileWriter{
+		ctx:   ctx,
+		conn:  p.Conn,
+		jobID: jobID,
+		file:  file,
+		buf:   make([]byte, 0, jobFileChunkSize),
+	}, nil
+}
+
+func (p *jobFileDBRepositories) Open(ctx context.Context, jobID, file string) (io.ReadCloser, int64, error) {
+	var chunks int
+	var size int64
+	query := `SELECT count(*), COALESCE(sum(length(data)), 0) FROM job_files WHERE job_id=$1 AND file=$2`
+	if err := p.Conn.QueryRowContext(ctx, query, jobID, file).Scan(&chunks, &size); err != nil {
+		return nil, 0, err
+	}
+	if chunks == 0 {
+		return nil, 0, domain.ErrNotFound
+	}
+
+	return &jobFileReader{
+		ctx:    ctx,
+		conn:   p.Conn,
+		jobID:  jobID,
+		file:   file,
+		chunks: chunks,
+	}, size, nil
+}
+
+func (p *jobFileDBRepositories) Remove(ctx context.Context, jobID, file string) (err error) {
+	_, err = p.Conn.ExecContext(ctx, `DELETE FROM job_files WHERE job_id=$1 AND file=$2`, jobID, file)
+	return
+}
+
+// jobFileWriter stores a chunk whenever its buffer is full, and the rest
+// on Close.
+type jobFileWriter struct {
+	ctx   context.Context
+	conn  *sql.DB
+	jobID string
+	file  string
+	buf   []byte
+	seq   int
+}
+
+func (w *jobFileWriter) Write(b []byte) (n int, err error) {
+	for len(b) > 0 {
+		k := copy(w.buf[len(w.buf):cap(w.buf)], b)
+		w.buf, b, n = w.buf[:len(w.buf)+k], b[k:], n+k
+		if len(w.buf) == cap(w.buf) {
+			if err = w.flush(); err != nil {
+				return
+			}
+		}
+	}
+	return
+}
+
+// Close stores an empty chunk for an empty file, Open would not find it
+// otherwise.
+func (w *jobFileWriter) Close() error {
+	if len(w.buf) == 0 && w.seq > 0 {
+		return nil
+	}
+	return w.flush()
+}
+
+func (w *jobFileWriter) flush() error {
+	query := `INSERT INTO job_files (job_id,file,seq,data) VALUES ($1, $2, $3, $4)`
+	if _, err := w.conn.ExecContext(w.ctx, query, w.jobID, w.file, w.seq, w.buf); err != nil {
+		return err
+	}
+	w.seq++
+	w.buf = w.buf[:0]
+	return nil
+}
+
+// jobFileReader reads the chunks one at a time.
+type jobFileReader struct {
+	ctx    context.Context
+	conn   *sql.DB
+	jobID  string
+	file   string
+	chunks int
+	seq    int
+	buf    []byte
+}
+
+func (r *jobFileReader) Read(b []byte) (int, error) {
+	for len(r.buf) == 0 {
+		if r.seq == r.chunks {
+			return 0, io.EOF
+		}
+		query := `SELECT data FROM job_files WHERE job_id=$1 AND file=$2 AND seq=$3`
+		if err := r.conn.QueryRowContext(r.ctx, query, r.jobID, r.file, r.seq).Scan(&r.buf); err != nil {
+			if err == sql.ErrNoRows {
+				return 0, io.ErrUnexpectedEOF
+			}
+			return 0, err
+		}
+		r.seq++
+	}
+
+	n := copy(b, r.buf)
+	r.buf = r.buf[n:]
+	return n, nil
+}
+
+func (r *jobFileReader) Close() error {
+	return nil
+}
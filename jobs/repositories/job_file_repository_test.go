@@ -0,0 +1,73 @@
+package repositories_test
+
+import (
+	"context"
+	"io"
+	"testing"
+
+	"github.com/fahmilukis/go-product-svc/domain"
+	"github.com/fahmilukis/go-product-svc/jobs/repositories"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/DATA-DOG/go-sqlmock.v1"
+)
+
+func TestCreateRepositoryJobFile(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	// the chunks of a previous attempt go first
+	mock.ExpectExec(`DELETE FROM job_files WHERE job_id=\$1 AND file=\$2`).WithArgs("j1", domain.JobOutputFile).WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectExec(`INSERT INTO job_files \(job_id,file,seq,data\) VALUES \(\$1, \$2, \$3, \$4\)`).
+		WithArgs("j1", domain.JobOutputFile, 0, []byte("id,name\n1,shirt\n")).WillReturnResult(sqlmock.NewResult(0, 1))
+
+	a := repositories.NewJobFileDBRepository(db)
+	w, err := a.Create(context.TODO(), "j1", domain.JobOutputFile)
+	assert.NoError(t, err)
+	_, err = io.WriteString(w, "id,name\n")
+	assert.NoError(t, err)
+	_, err = io.WriteString(w, "1,shirt\n")
+	assert.NoError(t, err)
+	assert.NoError(t, w.Close())
+	assert.NoError(t, w.Close())
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestOpenRepositoryJobFile(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	mock.ExpectQuery(`SELECT count\(\*\), COALESCE\(sum\(length\(data\)\), 0\) FROM job_files WHERE job_id=\$1 AND file=\$2`).
+		WithArgs("j1", domain.JobInputFile).WillReturnRows(sqlmock.NewRows([]string{"count", "sum"}).AddRow(2, 9))
+	mock.ExpectQuery(`SELECT data FROM job_files WHERE job_id=\$1 AND file=\$2 AND seq=\$3`).
+		WithArgs("j1", domain.JobInputFile, 0).WillReturnRows(sqlmock.NewRows([]string{"data"}).AddRow([]byte("name\n")))
+	mock.ExpectQuery(`SELECT data FROM job_files WHERE job_id=\$1 AND file=\$2 AND seq=\$3`).
+		WithArgs("j1", domain.JobInputFile, 1).WillReturnRows(sqlmock.NewRows([]string{"data"}).AddRow([]byte("tee\n")))
+
+	a := repositories.NewJobFileDBRepository(db)
+	r, size, err := a.Open(context.TODO(), "j1", domain.JobInputFile)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(9), size)
+	data, err := io.ReadAll(r)
+	assert.NoError(t, err)
+	assert.Equal(t, "name\ntee\n", string(data))
+	assert.NoError(t, r.Close())
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestOpenRepositoryJobFileNotFound(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	mock.ExpectQuery(`FROM job_files WHERE job_id=\$1 AND file=\$2`).
+		WithArgs("j1", domain.JobOutputFile).WillReturnRows(sqlmock.NewRows([]string{"count", "sum"}).AddRow(0, 0))
+
+	a := repositories.NewJobFileDBRepository(db)
+	_, _, err = a.Open(context.TODO(), "j1", domain.JobOutputFile)
+	assert.Equal(t, domain.ErrNotFound, err)
+}
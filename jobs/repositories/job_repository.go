@@ -0,0 +1,156 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/fahmilukis/go-product-svc/domain"
+	"github.com/sirupsen/logrus"
+)
+
+const jobColumns = `id,kind,status,payload,input,result,error,output,progress_done,progress_total,attempts,max_attempts,cancel_requested,run_at,locked_until,started_at,finished_at,created_at,updated_at`
+
+type jobDBRepositories struct {
+	Conn *sql.DB
+}
+
+func NewJobDBRepository(conn *sql.DB) *jobDBRepositories {
+	return &jobDBRepositories{Conn: conn}
+}
+
+// scanJob reads the jobColumns of a row.
+func scanJob(row *sql.Row, j *domain.Job) error {
+	var payload, result []byte
+	err := row.Scan(
+		&j.ID,
+		&j.Kind,
+		&j.Status,
+		&payload,
+		&j.Input,
+		&result,
+		&j.Error,
+		&j.Output,
+		&j.Progress.Done,
+		&j.Progress.Total,
+		&j.Attempts,
+		&j.MaxAttempts,
+		&j.CancelRequested,
+		&j.RunAt,
+		&j.LockedUntil,
+		&j.StartedAt,
+		&j.FinishedAt,
+		&j.CreatedAt,
+		&j.UpdatedAt,
+	)
+	if err != nil {
+		return err
+	}
+	j.Payload, j.Result = payload, result
+	return nil
+}
+
+func (p *jobDBRepositories) Store(ctx context.Context, j *domain.Job) (err error) {
+	query := `INSERT INTO jobs (id,kind,status,payload,input,max_attempts,run_at,created_at,updated_at)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`
+	_, err = p.Conn.ExecContext(ctx, query, j.ID, j.Kind, j.Status, []byte(j.Payload), j.Input, j.MaxAttempts, j.RunAt, j.CreatedAt, j.UpdatedAt)
+	return
+}
+
+func (p *jobDBRepositories) GetByID(ctx context.Context, id string) (res domain.Job, err error) {
+	err = scanJob(p.Conn.QueryRowContext(ctx, `SELECT `+jobColumns+` FROM jobs WHERE id=$1`, id), &res)
+	if err == sql.ErrNoRows {
+		return domain.Job{}, domain.ErrNotFound
+	}
+	if err != nil {
+		logrus.Error(err)
+		return domain.Job{}, err
+	}
+
+	return
+}
+
+// Claim locks the candidate rows with SKIP LOCKED, so the workers polling at
+// the same time never wait for each other nor take the same job.
+func (p *jobDBRepositories) Claim(ctx context.Context, now, lockedUntil time.Time) (res domain.Job, err error) {
+	query := `UPDATE jobs SET status=$3 , attempts=attempts+1 , locked_until=$2 , started_at=COALESCE(started_at, $1) , updated_at=$1
+	WHERE id = (
+		SELECT id FROM jobs
+		WHERE (status = $4 AND run_at <= $1) OR (status = $3 AND locked_until < $1)
+		ORDER BY run_at, created_at LIMIT 1 FOR UPDATE SKIP LOCKED
+	) RETURNING ` + jobColumns
+
+	row := p.Conn.QueryRowContext(ctx, query, now, lockedUntil, domain.JobRunning, domain.JobQueued)
+	err = scanJob(row, &res)
+	if err == sql.ErrNoRows {
+		return domain.Job{}, domain.ErrNotFound
+	}
+	if err != nil {
+		logrus.Error(err)
+		return domain.Job{}, err
+	}
+
+	return
+}
+
+func (p *jobDBRepositories) Heartbeat(ctx context.Context, id string, attempt int, now, lockedUntil time.Time, progress domain.JobProgress) (canceled bool, err error) {
+	query := `UPDATE jobs SET locked_until=$1 , progress_done=$2 , progress_total=$3 , updated_at=$4
+	WHERE id=$5 AND status=$6 AND attempts=$7 RETURNING cancel_requested`
+
+	err = p.Conn.QueryRowContext(ctx, query, lockedUntil, progress.Done, progress.Total, now, id, domain.JobRunning, attempt).Scan(&canceled)
+	if err == sql.ErrNoRows {
+		return false, domain.ErrNotFound
+	}
+
+	return
+}
+
+func (p *jobDBRepositories) Finish(ctx context.Context, j *domain.Job, attempt int) (err error) {
+	query := `UPDATE jobs SET status=$1 , result=$2 , error=$3 , output=$4 , progress_done=$5 , progress_total=$6 , attempts=$7 ,
+	run_at=$8 , finished_at=$9 , locked_until=NULL , updated_at=$10
+	WHERE id=$11 AND status=$12 AND attempts=$13`
+
+	var result []byte
+	if len(j.Result) > 0 {
+		result = j.Result
+	}
+	res, err := p.Conn.ExecContext(ctx, query, j.Status, result, j.Error, j.Output, j.Progress.Done, j.Progress.Total, j.Attempts,
+		j.RunAt, j.FinishedAt, j.UpdatedAt, j.ID, domain.JobRunning, attempt)
+	if err != nil {
+		return
+	}
+
+	rowsAfected, err := res.RowsAffected()
+	if err != nil {
+		return
+	}
+	if rowsAfected == 0 {
+		return domain.ErrNotFound
+	}
+
+	return
+}
+
+// Cancel finishes a queued job and flags a running one, its worker stops
+// it on the next heartbeat.
+func (p *jobDBRepositories) Cancel(ctx context.Context, id string, now time.Time) (res domain.Job, err error) {
+	query := `UPDATE jobs SET cancel_requested=true ,
+	status=CASE WHEN status = $3 THEN $4 ELSE status END ,
+	finished_at=CASE WHEN status = $3 THEN $2 ELSE finished_at END ,
+	updated_at=$2
+	WHERE id=$1 AND status IN ($3, $5) RETURNING ` + jobColumns
+
+	err = scanJob(p.Conn.QueryRowContext(ctx, query, id, now, domain.JobQueued, domain.JobCanceled, domain.JobRunning), &res)
+	if err == sql.ErrNoRows {
+		if _, err = p.GetByID(ctx, id); err != nil {
+			return domain.Job{}, err
+		}
+		return domain.Job{}, domain.ErrConflict
+	}
+	if err != nil {
+		logrus.Error(err)
+		return domain.Job{}, err
+	}
+
+	return
+}
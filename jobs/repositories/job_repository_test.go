@@ -0,0 +1,144 @@
+package repositories_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/fahmilukis/go-product-svc/domain"
+	"github.com/fahmilukis/go-product-svc/jobs/repositories"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/DATA-DOG/go-sqlmock.v1"
+)
+
+var jobRows = []string{"id", "kind", "status", "payload", "input", "result", "error", "output", "progress_done", "progress_total", "attempts", "max_attempts", "cancel_requested", "run_at", "locked_until", "started_at", "finished_at", "created_at", "updated_at"}
+
+func TestStoreRepositoryJob(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	now := time.Now()
+	job := &domain.Job{ID: "j1", Kind: domain.JobProductImport, Status: domain.JobQueued, Payload: []byte(`{"dry_run":true}`), Input: "j1.input", MaxAttempts: 3, RunAt: now, CreatedAt: now, UpdatedAt: now}
+
+	mock.ExpectExec(`INSERT INTO jobs \(id,kind,status,payload,input,max_attempts,run_at,created_at,updated_at\)`).
+		WithArgs("j1", domain.JobProductImport, domain.JobQueued, []byte(`{"dry_run":true}`), "j1.input", 3, now, now, now).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	a := repositories.NewJobDBRepository(db)
+	assert.NoError(t, a.Store(context.TODO(), job))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetByIDRepositoryJobNotFound(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	mock.ExpectQuery(`FROM jobs WHERE id=\$1`).WithArgs("j1").WillReturnRows(sqlmock.NewRows(jobRows))
+
+	a := repositories.NewJobDBRepository(db)
+	_, err = a.GetByID(context.TODO(), "j1")
+	assert.Equal(t, domain.ErrNotFound, err)
+}
+
+func TestClaimRepositoryJob(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	now := time.Now()
+	lockedUntil := now.Add(time.Minute)
+
+	rows := sqlmock.NewRows(jobRows).
+		AddRow("j1", domain.JobProductImport, "running", []byte(`{}`), "j1.input", nil, "", "", 0, 0, 1, 3, false, now, lockedUntil, now, nil, now, now)
+	mock.ExpectQuery(`UPDATE jobs SET status=\$3 , attempts=attempts\+1 (.+) FOR UPDATE SKIP LOCKED\s+\) RETURNING id,kind,status,payload,input,`).
+		WithArgs(now, lockedUntil, domain.JobRunning, domain.JobQueued).WillReturnRows(rows)
+
+	a := repositories.NewJobDBRepository(db)
+	job, err := a.Claim(context.TODO(), now, lockedUntil)
+	assert.NoError(t, err)
+	assert.Equal(t, "j1", job.ID)
+	assert.Equal(t, domain.JobRunning, job.Status)
+	assert.Equal(t, 1, job.Attempts)
+	assert.Equal(t, "j1.input", job.Input)
+	assert.Nil(t, job.Result)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestClaimRepositoryJobEmptyQueue(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	now := time.Now()
+
+	mock.ExpectQuery(`FOR UPDATE SKIP LOCKED`).WillReturnRows(sqlmock.NewRows(jobRows))
+
+	a := repositories.NewJobDBRepository(db)
+	_, err = a.Claim(context.TODO(), now, now.Add(time.Minute))
+	assert.Equal(t, domain.ErrNotFound, err)
+}
+
+func TestHeartbeatRepositoryJob(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	now := time.Now()
+	lockedUntil := now.Add(time.Minute)
+
+	mock.ExpectQuery(`UPDATE jobs SET locked_until=\$1 , progress_done=\$2 , progress_total=\$3 , updated_at=\$4\s+WHERE id=\$5 AND status=\$6 AND attempts=\$7 RETURNING cancel_requested`).
+		WithArgs(lockedUntil, 40, 100, now, "j1", domain.JobRunning, 2).
+		WillReturnRows(sqlmock.NewRows([]string{"cancel_requested"}).AddRow(true))
+	mock.ExpectQuery(`UPDATE jobs SET locked_until`).
+		WithArgs(lockedUntil, 40, 100, now, "j1", domain.JobRunning, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"cancel_requested"}))
+
+	a := repositories.NewJobDBRepository(db)
+	canceled, err := a.Heartbeat(context.TODO(), "j1", 2, now, lockedUntil, domain.JobProgress{Done: 40, Total: 100})
+	assert.NoError(t, err)
+	assert.True(t, canceled)
+
+	// the run of a previous attempt lost the job
+	_, err = a.Heartbeat(context.TODO(), "j1", 1, now, lockedUntil, domain.JobProgress{Done: 40, Total: 100})
+	assert.Equal(t, domain.ErrNotFound, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestFinishRepositoryJob(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	now := time.Now()
+	job := &domain.Job{ID: "j1", Status: domain.JobSucceeded, Result: []byte(`{"total":3}`), Output: "products.csv", Progress: domain.JobProgress{Done: 3}, Attempts: 1, RunAt: now, FinishedAt: &now, UpdatedAt: now}
+
+	mock.ExpectExec(`UPDATE jobs SET status=\$1 , result=\$2 (.+) WHERE id=\$11 AND status=\$12 AND attempts=\$13`).
+		WithArgs(domain.JobSucceeded, []byte(`{"total":3}`), "", "products.csv", 3, 0, 1, now, &now, now, "j1", domain.JobRunning, 1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	a := repositories.NewJobDBRepository(db)
+	assert.NoError(t, a.Finish(context.TODO(), job, 1))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestCancelRepositoryJobFinished(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	now := time.Now()
+
+	mock.ExpectQuery(`UPDATE jobs SET cancel_requested=true (.+) WHERE id=\$1 AND status IN \(\$3, \$5\)`).
+		WithArgs("j1", now, domain.JobQueued, domain.JobCanceled, domain.JobRunning).
+		WillReturnRows(sqlmock.NewRows(jobRows))
+	mock.ExpectQuery(`FROM jobs WHERE id=\$1`).WithArgs("j1").
+		WillReturnRows(sqlmock.NewRows(jobRows).AddRow("j1", domain.JobProductExport, "succeeded", []byte(`{}`), "", []byte(`{}`), "", "", 0, 0, 1, 3, false, now, nil, now, now, now, now))
+
+	a := repositories.NewJobDBRepository(db)
+	_, err = a.Cancel(context.TODO(), "j1", now)
+	assert.Equal(t, domain.ErrConflict, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
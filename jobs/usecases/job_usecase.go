@@ -0,0 +1,439 @@
+package usecases
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/fahmilukis/go-product-svc/domain"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// jobAttempts is the number of runs a job gets before it fails.
+	jobAttempts = 3
+	// jobLease is how long a worker holds a job without a heartbeat, another
+	// worker takes the job over after that.
+	jobLease = time.Minute
+	// jobHeartbeat is how often a running job extends its lease and saves
+	// its progress.
+	jobHeartbeat = 10 * time.Second
+	// jobPollInterval is how long an idle worker waits before looking for a
+	// job again.
+	jobPollInterval = 2 * time.Second
+	// jobRetryDelay is the delay before the first retry, it doubles on every
+	// retry up to jobRetryMaxDelay.
+	jobRetryDelay    = 30 * time.Second
+	jobRetryMaxDelay = time.Hour
+)
+
+type jobUsecase struct {
+	jobRepository domain.JobRepository
+	// fileRepository keeps the inputs of the jobs and the files they produce
+	fileRepository domain.JobFileRepository
+	// inputLimit is the size of the largest input taken
+	inputLimit int64
+	mu         sync.RWMutex
+	funcs      map[string]domain.JobFunc
+	ctxTimeout time.Duration
+}
+
+func NewJobUsecase(j domain.JobRepository, f domain.JobFileRepository, inputLimit int64, to time.Duration) domain.JobUsecase {
+	return &jobUsecase{
+		jobRepository:  j,
+		fileRepository: f,
+		inputLimit:     inputLimit,
+		funcs:          map[string]domain.JobFunc{},
+		ctxTimeout:     to,
+	}
+}
+
+func (u *jobUsecase) Register(kind string, fn domain.JobFunc) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	u.funcs[kind] = fn
+}
+
+func (u *jobUsecase) lookup(kind string) (domain.JobFunc, bool) {
+	u.mu.RLock()
+	defer u.mu.RUnlock()
+
+	fn, ok := u.funcs[kind]
+	return fn, ok
+}
+
+// Enqueue only takes the kinds registered, the job would wait forever
+// otherwise.
+func (u *jobUsecase) Enqueue(c context.Context, kind string, payload interface{}, input io.Reader) (res domain.Job, err error) {
+	ctx, cancel := context.WithTimeout(c, u.ctxTimeout)
+	defer cancel()
+
+	if _, ok := u.lookup(kind); !ok {
+		return domain.Job{}, domain.ErrBadParamInput
+	}
+	raw := []byte(`{}`)
+	if payload != nil {
+		if raw, err = json.Marshal(payload); err != nil {
+			return domain.Job{}, err
+		}
+	}
+
+	now := time.Now()
+	res = domain.Job{
+		ID:          uuid.NewString(),
+		Kind:        kind,
+		Status:      domain.JobQueued,
+		Payload:     raw,
+		MaxAttempts: jobAttempts,
+		RunAt:       now,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+	// the input takes as long as the client sends it
+	if input != nil {
+		if err = u.saveInput(c, res.ID, input); err != nil {
+			return domain.Job{}, err
+		}
+		res.Input = domain.JobInputFile
+	}
+	if err = u.jobRepository.Store(ctx, &res); err != nil {
+		u.removeInput(res)
+		return domain.Job{}, err
+	}
+
+	return res, nil
+}
+
+// saveInput copies the input of the job to its input file, the input is
+// read up to the limit.
+func (u *jobUsecase) saveInput(ctx context.Context, id string, input io.Reader) error {
+	w, err := u.fileRepository.Create(ctx, id, domain.JobInputFile)
+	if err != nil {
+		return err
+	}
+
+	n, err := io.Copy(w, io.LimitReader(input, u.inputLimit+1))
+	if errClose := w.Close(); err == nil {
+		err = errClose
+	}
+	if err == nil && n > u.inputLimit {
+		err = domain.ErrTooLarge
+	}
+	if err != nil {
+		u.removeFile(id, domain.JobInputFile)
+		return err
+	}
+
+	return nil
+}
+
+// removeInput removes the input of a job that won't run anymore.
+func (u *jobUsecase) removeInput(job domain.Job) {
+	if job.Input == "" {
+		return
+	}
+	u.removeFile(job.ID, domain.JobInputFile)
+}
+
+// removeFile removes a file of the job, the ctx of the caller may be done
+// already.
+func (u *jobUsecase) removeFile(id, file string) {
+	ctx, cancel := context.WithTimeout(context.Background(), u.ctxTimeout)
+	defer cancel()
+
+	if err := u.fileRepository.Remove(ctx, id, file); err != nil {
+		logrus.Errorf("failed to remove the %s of job %s: %v", file, id, err)
+	}
+}
+
+func (u *jobUsecase) GetByID(c context.Context, id string) (res domain.Job, err error) {
+	ctx, cancel := context.WithTimeout(c, u.ctxTimeout)
+	defer cancel()
+
+	return u.jobRepository.GetByID(ctx, id)
+}
+
+func (u *jobUsecase) Cancel(c context.Context, id string) (res domain.Job, err error) {
+	ctx, cancel := context.WithTimeout(c, u.ctxTimeout)
+	defer cancel()
+
+	res, err = u.jobRepository.Cancel(ctx, id, time.Now())
+	if err != nil {
+		return domain.Job{}, err
+	}
+	// a running job removes its input once it stopped
+	if res.Status.Finished() {
+		u.removeInput(res)
+	}
+
+	return res, nil
+}
+
+// OpenOutput reads the output under the ctx given, a download outlives the
+// timeout of the usecase.
+func (u *jobUsecase) OpenOutput(c context.Context, id string) (output io.ReadCloser, size int64, name string, err error) {
+	job, err := u.GetByID(c, id)
+	if err != nil {
+		return nil, 0, "", err
+	}
+	if job.Status != domain.JobSucceeded || job.Output == "" {
+		return nil, 0, "", domain.ErrNotFound
+	}
+
+	output, size, err = u.fileRepository.Open(c, job.ID, domain.JobOutputFile)
+	if err != nil {
+		return nil, 0, "", err
+	}
+	return output, size, job.Output, nil
+}
+
+func (u *jobUsecase) Work(ctx context.Context, workers int) {
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			u.work(ctx)
+		}()
+	}
+	wg.Wait()
+}
+
+// work runs the jobs one after the other and polls for new ones when the
+// queue is empty.
+func (u *jobUsecase) work(ctx context.Context) {
+	for {
+		ran := u.runNext(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		if ran {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(jobPollInterval):
+		}
+	}
+}
+
+// runNext runs the next job due and tells whether there was one.
+func (u *jobUsecase) runNext(ctx context.Context) bool {
+	now := time.Now()
+	claimCtx, cancel := context.WithTimeout(ctx, u.ctxTimeout)
+	job, err := u.jobRepository.Claim(claimCtx, now, now.Add(jobLease))
+	cancel()
+	if err == domain.ErrNotFound {
+		return false
+	}
+	if err != nil {
+		logrus.Errorf("failed to claim a job: %v", err)
+		return false
+	}
+
+	u.run(ctx, job)
+	return true
+}
+
+// jobState is what the heartbeat and the JobFunc of a run share.
+type jobState struct {
+	mu       sync.Mutex
+	progress domain.JobProgress
+	output   string
+	canceled int32
+	lost     int32
+}
+
+func (s *jobState) setProgress(done, total int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.progress = domain.JobProgress{Done: done, Total: total}
+}
+
+func (s *jobState) getProgress() domain.JobProgress {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.progress
+}
+
+func (u *jobUsecase) run(ctx context.Context, job domain.Job) {
+	attempt := job.Attempts
+	fn, ok := u.lookup(job.Kind)
+	switch {
+	case job.CancelRequested:
+		u.finish(&job, attempt, domain.JobCanceled, domain.ErrJobCanceled)
+		return
+	case !ok:
+		u.finish(&job, attempt, domain.JobFailed, fmt.Errorf("no worker runs the jobs of kind %q", job.Kind))
+		return
+	case job.Attempts > job.MaxAttempts:
+		// a stale job taken over once its last run was made
+		u.finish(&job, attempt, domain.JobFailed, errors.New("the worker running the job was lost"))
+		return
+	}
+
+	runCtx, cancelRun := context.WithCancel(ctx)
+	defer cancelRun()
+	state := &jobState{progress: job.Progress}
+
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		u.heartbeat(ctx, job, state, cancelRun, done)
+	}()
+
+	result, err := callJob(runCtx, fn, domain.JobRun{
+		Job: job,
+		Progress: func(done, total int64) error {
+			state.setProgress(done, total)
+			if atomic.LoadInt32(&state.canceled) == 1 {
+				return domain.ErrJobCanceled
+			}
+			return runCtx.Err()
+		},
+		CreateOutput: func(name string) (io.WriteCloser, error) {
+			state.mu.Lock()
+			state.output = filepath.Base(name)
+			state.mu.Unlock()
+			return u.fileRepository.Create(runCtx, job.ID, domain.JobOutputFile)
+		},
+		OpenInput: func() (io.ReadCloser, int64, error) {
+			if job.Input == "" {
+				return nil, 0, domain.ErrBadParamInput
+			}
+			return u.fileRepository.Open(runCtx, job.ID, domain.JobInputFile)
+		},
+	})
+	close(done)
+	wg.Wait()
+
+	if atomic.LoadInt32(&state.lost) == 1 {
+		logrus.Errorf("job %s was taken over by another worker", job.ID)
+		return
+	}
+
+	job.Progress, job.Output = state.getProgress(), state.output
+	switch {
+	case err == nil:
+		if result != nil {
+			if job.Result, err = json.Marshal(result); err != nil {
+				u.finish(&job, attempt, domain.JobFailed, err)
+				return
+			}
+		}
+		u.finish(&job, attempt, domain.JobSucceeded, nil)
+	case atomic.LoadInt32(&state.canceled) == 1 || errors.Is(err, domain.ErrJobCanceled):
+		u.finish(&job, attempt, domain.JobCanceled, domain.ErrJobCanceled)
+	case errors.Is(err, domain.ErrBadParamInput) || errors.Is(err, domain.ErrJobFatal):
+		u.finish(&job, attempt, domain.JobFailed, err)
+	case ctx.Err() != nil:
+		// the workers are stopping, the run doesn't count
+		job.Attempts--
+		u.finish(&job, attempt, domain.JobQueued, nil)
+	case job.Attempts >= job.MaxAttempts:
+		u.finish(&job, attempt, domain.JobFailed, err)
+	default:
+		u.finish(&job, attempt, domain.JobQueued, err)
+	}
+}
+
+// callJob turns a panic of the JobFunc into an error of the run.
+func callJob(ctx context.Context, fn domain.JobFunc, run domain.JobRun) (result interface{}, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("job panicked: %v", r)
+		}
+	}()
+
+	return fn(ctx, run)
+}
+
+// heartbeat keeps the lease of the run until done is closed, the run is
+// stopped when the job gets canceled or taken over.
+func (u *jobUsecase) heartbeat(ctx context.Context, job domain.Job, state *jobState, stop context.CancelFunc, done <-chan struct{}) {
+	ticker := time.NewTicker(jobHeartbeat)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+		}
+
+		now := time.Now()
+		hbCtx, cancel := context.WithTimeout(ctx, u.ctxTimeout)
+		canceled, err := u.jobRepository.Heartbeat(hbCtx, job.ID, job.Attempts, now, now.Add(jobLease), state.getProgress())
+		cancel()
+		switch {
+		case err == domain.ErrNotFound:
+			atomic.StoreInt32(&state.lost, 1)
+			stop()
+			return
+		case err != nil:
+			logrus.Errorf("failed to extend the lease of job %s: %v", job.ID, err)
+		case canceled:
+			atomic.StoreInt32(&state.canceled, 1)
+			stop()
+		}
+	}
+}
+
+// finish records the end of the run. A job queued again is retried after a
+// delay growing with its attempts, unless the run was interrupted.
+func (u *jobUsecase) finish(job *domain.Job, attempt int, status domain.JobStatus, runErr error) {
+	now := time.Now()
+	job.Status, job.UpdatedAt = status, now
+	if runErr != nil {
+		job.Error = runErr.Error()
+	} else if status == domain.JobSucceeded {
+		job.Error = ""
+	}
+	switch {
+	case status == domain.JobQueued && runErr != nil:
+		job.RunAt = now.Add(retryDelay(job.Attempts))
+	case status == domain.JobQueued:
+		job.RunAt = now
+	default:
+		job.FinishedAt = &now
+	}
+	if status != domain.JobQueued {
+		u.removeInput(*job)
+	}
+	if status != domain.JobSucceeded && job.Output != "" {
+		u.removeFile(job.ID, domain.JobOutputFile)
+		job.Output = ""
+	}
+
+	// the worker ctx may be done already, the end of the run is recorded
+	// whatever
+	ctx, cancel := context.WithTimeout(context.Background(), u.ctxTimeout)
+	defer cancel()
+	if err := u.jobRepository.Finish(ctx, job, attempt); err != nil {
+		logrus.Errorf("failed to record the end of job %s: %v", job.ID, err)
+	}
+}
+
+// retryDelay doubles the delay on every attempt made.
+func retryDelay(attempts int) time.Duration {
+	delay := jobRetryDelay
+	for i := 1; i < attempts && delay < jobRetryMaxDelay; i++ {
+		delay *= 2
+	}
+	if delay > jobRetryMaxDelay {
+		return jobRetryMaxDelay
+	}
+	return delay
+}
@@ -0,0 +1,184 @@
+package usecases_test
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/fahmilukis/go-product-svc/domain"
+	"github.com/fahmilukis/go-product-svc/jobs/usecases"
+	"github.com/stretchr/testify/assert"
+)
+
+// jobRepository keeps the jobs in memory, it claims the queued jobs only.
+type jobRepository struct {
+	domain.JobRepository
+	mu   sync.Mutex
+	jobs map[string]domain.Job
+}
+
+func (r *jobRepository) Store(ctx context.Context, j *domain.Job) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.jobs[j.ID] = *j
+	return nil
+}
+
+func (r *jobRepository) GetByID(ctx context.Context, id string) (domain.Job, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	j, ok := r.jobs[id]
+	if !ok {
+		return domain.Job{}, domain.ErrNotFound
+	}
+	return j, nil
+}
+
+func (r *jobRepository) Claim(ctx context.Context, now, lockedUntil time.Time) (domain.Job, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for id, j := range r.jobs {
+		if j.Status == domain.JobQueued && !j.RunAt.After(now) {
+			j.Status, j.LockedUntil = domain.JobRunning, &lockedUntil
+			j.Attempts++
+			r.jobs[id] = j
+			return j, nil
+		}
+	}
+	return domain.Job{}, domain.ErrNotFound
+}
+
+func (r *jobRepository) Heartbeat(ctx context.Context, id string, attempt int, now, lockedUntil time.Time, progress domain.JobProgress) (bool, error) {
+	return false, nil
+}
+
+func (r *jobRepository) Finish(ctx context.Context, j *domain.Job, attempt int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.jobs[j.ID] = *j
+	return nil
+}
+
+// jobFileRepository keeps the files in memory, a file shows once closed.
+type jobFileRepository struct {
+	mu    sync.Mutex
+	files map[string][]byte
+}
+
+type jobFileWriter struct {
+	bytes.Buffer
+	repo *jobFileRepository
+	key  string
+}
+
+func (w *jobFileWriter) Close() error {
+	w.repo.mu.Lock()
+	defer w.repo.mu.Unlock()
+	w.repo.files[w.key] = w.Bytes()
+	return nil
+}
+
+func (r *jobFileRepository) Create(ctx context.Context, jobID, file string) (io.WriteCloser, error) {
+	return &jobFileWriter{repo: r, key: jobID + "/" + file}, nil
+}
+
+func (r *jobFileRepository) Open(ctx context.Context, jobID, file string) (io.ReadCloser, int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	data, ok := r.files[jobID+"/"+file]
+	if !ok {
+		return nil, 0, domain.ErrNotFound
+	}
+	return io.NopCloser(bytes.NewReader(data)), int64(len(data)), nil
+}
+
+func (r *jobFileRepository) Remove(ctx context.Context, jobID, file string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.files, jobID+"/"+file)
+	return nil
+}
+
+// upperJob writes its input upper-cased to its output.
+func upperJob(ctx context.Context, run domain.JobRun) (interface{}, error) {
+	input, size, err := run.OpenInput()
+	if err != nil {
+		return nil, err
+	}
+	defer input.Close()
+	data, err := io.ReadAll(input)
+	if err != nil {
+		return nil, err
+	}
+
+	out, err := run.CreateOutput("upper.csv")
+	if err != nil {
+		return nil, err
+	}
+	if _, err = out.Write(bytes.ToUpper(data)); err != nil {
+		return nil, err
+	}
+	return map[string]int64{"size": size}, out.Close()
+}
+
+func TestJobRunsOnAnotherInstance(t *testing.T) {
+	jobs := &jobRepository{jobs: map[string]domain.Job{}}
+	files := &jobFileRepository{files: map[string][]byte{}}
+
+	// the instance taking the upload and the one running the job only share
+	// the repositories
+	api := usecases.NewJobUsecase(jobs, files, 1<<20, time.Second)
+	worker := usecases.NewJobUsecase(jobs, files, 1<<20, time.Second)
+	api.Register("test.upper", upperJob)
+	worker.Register("test.upper", upperJob)
+
+	job, err := api.Enqueue(context.TODO(), "test.upper", nil, strings.NewReader("name\ntee\n"))
+	assert.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan struct{})
+	go func() {
+		worker.Work(ctx, 1)
+		close(stopped)
+	}()
+	assert.Eventually(t, func() bool {
+		j, err := api.GetByID(context.TODO(), job.ID)
+		return err == nil && j.Status.Finished()
+	}, 5*time.Second, 10*time.Millisecond)
+	cancel()
+	<-stopped
+
+	job, err = api.GetByID(context.TODO(), job.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, domain.JobSucceeded, job.Status)
+	assert.JSONEq(t, `{"size":9}`, string(job.Result))
+
+	output, size, name, err := api.OpenOutput(context.TODO(), job.ID)
+	assert.NoError(t, err)
+	defer output.Close()
+	data, err := io.ReadAll(output)
+	assert.NoError(t, err)
+	assert.Equal(t, "NAME\nTEE\n", string(data))
+	assert.Equal(t, int64(9), size)
+	assert.Equal(t, "upper.csv", name)
+
+	// the input goes once the job finished
+	_, _, err = files.Open(context.TODO(), job.ID, domain.JobInputFile)
+	assert.Equal(t, domain.ErrNotFound, err)
+}
+
+func TestEnqueueJobInputTooLarge(t *testing.T) {
+	jobs := &jobRepository{jobs: map[string]domain.Job{}}
+	files := &jobFileRepository{files: map[string][]byte{}}
+	u := usecases.NewJobUsecase(jobs, files, 4, time.Second)
+	u.Register("test.upper", upperJob)
+
+	_, err := u.Enqueue(context.TODO(), "test.upper", nil, strings.NewReader("name\ntee\n"))
+	assert.ErrorIs(t, err, domain.ErrTooLarge)
+	assert.Empty(t, jobs.jobs)
+	assert.Empty(t, files.files)
+}
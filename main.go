@@ -1,15 +1,44 @@
 package main
 
 import (
+	"context"
 	"database/sql"
+	"fmt"
 	"log"
+	"os"
+	"path/filepath"
 	"time"
 
+	attributeHandler "github.com/fahmilukis/go-product-svc/attributes/handler/http"
+	attributeRepositories "github.com/fahmilukis/go-product-svc/attributes/repositories"
+	attributeUsecases "github.com/fahmilukis/go-product-svc/attributes/usecases"
+	categoryHandler "github.com/fahmilukis/go-product-svc/categories/handler/http"
+	categoryRepositories "github.com/fahmilukis/go-product-svc/categories/repositories"
+	categoryUsecases "github.com/fahmilukis/go-product-svc/categories/usecases"
+	"github.com/fahmilukis/go-product-svc/domain"
+	exchangeRateHandler "github.com/fahmilukis/go-product-svc/exchangerates/handler/http"
+	exchangeRateRepositories "github.com/fahmilukis/go-product-svc/exchangerates/repositories"
+	exchangeRateUsecases "github.com/fahmilukis/go-product-svc/exchangerates/usecases"
 	"github.com/fahmilukis/go-product-svc/files"
+	"github.com/fahmilukis/go-product-svc/inventory/allocation"
+	inventoryHandler "github.com/fahmilukis/go-product-svc/inventory/handler/http"
+	inventoryRepositories "github.com/fahmilukis/go-product-svc/inventory/repositories"
+	inventoryUsecases "github.com/fahmilukis/go-product-svc/inventory/usecases"
+	jobHandler "github.com/fahmilukis/go-product-svc/jobs/handler/http"
+	jobRepositories "github.com/fahmilukis/go-product-svc/jobs/repositories"
+	jobUsecases "github.com/fahmilukis/go-product-svc/jobs/usecases"
 	pkg "github.com/fahmilukis/go-product-svc/pkg/utils"
+	priceHandler "github.com/fahmilukis/go-product-svc/prices/handler/http"
+	priceRepositories "github.com/fahmilukis/go-product-svc/prices/repositories"
+	priceUsecases "github.com/fahmilukis/go-product-svc/prices/usecases"
+	"github.com/fahmilukis/go-product-svc/products/events"
 	handler "github.com/fahmilukis/go-product-svc/products/handler/http"
 	"github.com/fahmilukis/go-product-svc/products/repositories"
+	"github.com/fahmilukis/go-product-svc/products/search"
 	"github.com/fahmilukis/go-product-svc/products/usecases"
+	variantHandler "github.com/fahmilukis/go-product-svc/variants/handler/http"
+	variantRepositories "github.com/fahmilukis/go-product-svc/variants/repositories"
+	variantUsecases "github.com/fahmilukis/go-product-svc/variants/usecases"
 	"github.com/gofiber/fiber/v2"
 
 	_ "github.com/lib/pq"
@@ -28,13 +57,151 @@ func main() {
 		}
 	}()
 
-	app := fiber.New()
-	files.NewUploadImageRoutes(app)
+	pkg.SetCursorKey([]byte(pkg.GetEnv("PAGINATION_CURSOR_SECRET", "")))
 
 	productRepo := repositories.NewProductDBRepository(dbConn)
-	productUsecase := usecases.NewProductUsecase(productRepo, 10*time.Second)
+	revisionRepo := repositories.NewProductRevisionDBRepository(dbConn)
+	searchIndex, err := newSearchIndex(productRepo)
+	if err != nil {
+		log.Fatal(err)
+	}
+	attributeRepo := attributeRepositories.NewAttributeDBRepository(dbConn)
+	attributeUsecase := attributeUsecases.NewAttributeUsecase(productRepo, attributeRepo, 10*time.Second)
+	productUsecase := usecases.NewProductUsecase(productRepo, searchIndex, attributeRepo, files.NewImageStore(), 10*time.Second)
+	revisionUsecase := usecases.NewProductRevisionUsecase(productRepo, revisionRepo, searchIndex, attributeRepo, 10*time.Second)
+	translationRepo := repositories.NewProductTranslationDBRepository(dbConn)
+	// the products are written in the default locale and translated to the
+	// other locales they are sold in
+	defaultLocale := pkg.GetEnv("PRODUCT_DEFAULT_LOCALE", "en")
+	translationUsecase := usecases.NewProductTranslationUsecase(productRepo, translationRepo, defaultLocale, pkg.ParseList(pkg.GetEnv("PRODUCT_LOCALES", defaultLocale)), 10*time.Second)
+	categoryRepo := categoryRepositories.NewCategoryDBRepository(dbConn)
+	categoryUsecase := categoryUsecases.NewCategoryUsecase(categoryRepo, 10*time.Second)
+	variantRepo := variantRepositories.NewVariantDBRepository(dbConn)
+	variantUsecase := variantUsecases.NewVariantUsecase(productRepo, variantRepo, 10*time.Second)
+	// the rates come from a local file or the admin endpoint, never from a
+	// live FX service
+	exchangeRateRepo, err := exchangeRateRepositories.NewFileExchangeRateRepository(pkg.GetEnv("EXCHANGE_RATES_PATH", ""))
+	if err != nil {
+		log.Fatal(err)
+	}
+	exchangeRateUsecase := exchangeRateUsecases.NewExchangeRateUsecase(exchangeRateRepo, 10*time.Second)
+	priceRepo := priceRepositories.NewPriceDBRepository(dbConn)
+	priceUsecase := priceUsecases.NewPriceUsecase(productRepo, priceRepo, exchangeRateRepo, 10*time.Second)
+	warehouseRepo := inventoryRepositories.NewWarehouseDBRepository(dbConn)
+	warehouseUsecase := inventoryUsecases.NewWarehouseUsecase(warehouseRepo, 10*time.Second)
+	allocationStrategy, err := allocation.New(pkg.GetEnv("INVENTORY_ALLOCATION_STRATEGY", allocation.Priority))
+	if err != nil {
+		log.Fatal(err)
+	}
+	inventoryRepo := inventoryRepositories.NewInventoryDBRepository(dbConn)
+	inventoryUsecase := inventoryUsecases.NewInventoryUsecase(productRepo, variantRepo, inventoryRepo, warehouseRepo, allocationStrategy, 10*time.Second)
+	// the inputs of the jobs, e.g. the imported CSV, are kept in the database
+	// until the job finishes and the files they produce, e.g. the exports,
+	// for good, any instance runs the jobs and serves their outputs
+	jobRepo := jobRepositories.NewJobDBRepository(dbConn)
+	jobFileRepo := jobRepositories.NewJobFileDBRepository(dbConn)
+	jobUsecase := jobUsecases.NewJobUsecase(jobRepo, jobFileRepo, int64(pkg.GetEnvInt("JOB_INPUT_LIMIT", 1<<30)), 10*time.Second)
+	usecases.RegisterProductJobs(jobUsecase, productUsecase)
+	eventPublisher, err := newEventPublisher()
+	if err != nil {
+		log.Fatal(err)
+	}
+	eventUsecase := usecases.NewProductEventUsecase(repositories.NewProductEventDBRepository(dbConn), eventPublisher, 10*time.Second)
+
+	// the server streams the request bodies so an import doesn't sit in
+	// memory, the bodies of the other routes are capped as before
+	bodyLimit := pkg.GetEnvInt("HTTP_BODY_LIMIT", fiber.DefaultBodyLimit)
+	app := fiber.New(fiber.Config{StreamRequestBody: true, BodyLimit: bodyLimit})
+	app.Use(pkg.LimitBody(bodyLimit, "/api/v1/product/import"))
+	files.NewUploadImageRoutes(app)
 
-	handler.ProductRoute(app, productUsecase)
+	handler.ProductRoute(app, productUsecase, priceUsecase, inventoryUsecase, translationUsecase, jobUsecase)
+	handler.ProductRevisionRoute(app, revisionUsecase)
+	handler.ProductTranslationRoute(app, translationUsecase)
+	categoryHandler.CategoryRoute(app, categoryUsecase, productUsecase)
+	variantHandler.VariantRoute(app, variantUsecase)
+	priceHandler.PriceRoute(app, priceUsecase)
+	exchangeRateHandler.ExchangeRateRoute(app, exchangeRateUsecase)
+	inventoryHandler.InventoryRoute(app, inventoryUsecase)
+	inventoryHandler.WarehouseRoute(app, warehouseUsecase)
+	attributeHandler.AttributeRoute(app, attributeUsecase)
+	jobHandler.JobRoute(app, jobUsecase)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	trashRetention := pkg.GetEnvDuration("PRODUCT_TRASH_RETENTION", 30*24*time.Hour)
+	go pkg.RunPeriodically(ctx, time.Hour, func(ctx context.Context) {
+		purged, err := productUsecase.PurgeDeleted(ctx, trashRetention)
+		if err != nil {
+			log.Printf("failed to purge trashed products: %v", err)
+			return
+		}
+		if purged > 0 {
+			log.Printf("purged %d trashed products", purged)
+		}
+	})
+
+	// scheduled products go live and expiring ones get archived
+	go pkg.RunPeriodically(ctx, pkg.GetEnvDuration("PRODUCT_SCHEDULE_INTERVAL", time.Minute), func(ctx context.Context) {
+		moved, err := productUsecase.RunSchedule(ctx)
+		if err != nil {
+			log.Printf("failed to run the product schedule: %v", err)
+			return
+		}
+		if moved > 0 {
+			log.Printf("moved %d scheduled products", moved)
+		}
+	})
+
+	// reservations of abandoned carts give their stock back once they expire
+	go pkg.RunPeriodically(ctx, time.Minute, func(ctx context.Context) {
+		released, err := inventoryUsecase.ReleaseExpired(ctx)
+		if err != nil {
+			log.Printf("failed to release expired reservations: %v", err)
+			return
+		}
+		if released > 0 {
+			log.Printf("released %d expired reservations", released)
+		}
+	})
+
+	// the relay hands the product changes to the downstream services
+	go pkg.RunPeriodically(ctx, pkg.GetEnvDuration("PRODUCT_EVENTS_INTERVAL", time.Second), func(ctx context.Context) {
+		if _, err := eventUsecase.Relay(ctx); err != nil {
+			log.Printf("failed to relay the product events: %v", err)
+		}
+	})
+
+	// the workers run the jobs alongside the server and stop with it, every
+	// instance of the service takes its share of the queue
+	go jobUsecase.Work(ctx, pkg.GetEnvInt("JOB_WORKERS", 2))
 
 	pkg.StartServer(app)
 }
+
+// newSearchIndex picks the search backend set by PRODUCT_SEARCH_BACKEND.
+func newSearchIndex(productRepo domain.ProductRepository) (domain.ProductSearchIndex, error) {
+	switch backend := pkg.GetEnv("PRODUCT_SEARCH_BACKEND", "postgres"); backend {
+	case "postgres":
+		return search.NewPostgresIndex(productRepo), nil
+	case "embedded":
+		path := pkg.GetEnv("PRODUCT_SEARCH_INDEX_PATH", filepath.Join(os.TempDir(), "product_search", "index.jsonl"))
+		return search.NewEmbeddedIndex(path)
+	default:
+		return nil, fmt.Errorf("unknown search backend %q", backend)
+	}
+}
+
+// newEventPublisher picks where the product events go, set by
+// PRODUCT_EVENTS_PUBLISHER. Both publishers are meant for local use.
+func newEventPublisher() (domain.EventPublisher, error) {
+	switch publisher := pkg.GetEnv("PRODUCT_EVENTS_PUBLISHER", "file"); publisher {
+	case "file":
+		return events.NewFilePublisher(pkg.GetEnv("PRODUCT_EVENTS_PATH", filepath.Join(os.TempDir(), "product_events", "events.jsonl")))
+	case "memory":
+		return events.NewMemoryPublisher(pkg.GetEnvInt("PRODUCT_EVENTS_MEMORY_LIMIT", 10000)), nil
+	default:
+		return nil, fmt.Errorf("unknown event publisher %q", publisher)
+	}
+}
@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS product_revisions (
+    product_id     UUID        NOT NULL,
+    revision       BIGINT      NOT NULL,
+    action         TEXT        NOT NULL,
+    actor          TEXT        NOT NULL DEFAULT '',
+    changed_fields JSONB       NOT NULL DEFAULT '[]',
+    snapshot       JSONB       NOT NULL,
+    created_at     TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (product_id, revision)
+);
@@ -0,0 +1,19 @@
+CREATE TABLE IF NOT EXISTS categories (
+    id         UUID        PRIMARY KEY,
+    parent_id  UUID        REFERENCES categories (id),
+    name       TEXT        NOT NULL,
+    path       TEXT        NOT NULL UNIQUE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+-- subtrees are read with path LIKE '<prefix>%'
+CREATE INDEX IF NOT EXISTS categories_path_idx ON categories (path text_pattern_ops);
+
+CREATE TABLE IF NOT EXISTS product_categories (
+    product_id  UUID NOT NULL REFERENCES products (id) ON DELETE CASCADE,
+    category_id UUID NOT NULL REFERENCES categories (id) ON DELETE CASCADE,
+    PRIMARY KEY (product_id, category_id)
+);
+
+CREATE INDEX IF NOT EXISTS product_categories_category_id_idx ON product_categories (category_id);
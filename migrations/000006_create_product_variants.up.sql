@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS product_variants (
+    id         UUID        PRIMARY KEY DEFAULT gen_random_uuid(),
+    product_id UUID        NOT NULL REFERENCES products (id) ON DELETE CASCADE,
+    sku        TEXT        NOT NULL UNIQUE,
+    options    JSONB       NOT NULL DEFAULT '{}',
+    price      BIGINT      NOT NULL DEFAULT 0 CHECK (price >= 0),
+    img_src    TEXT        NOT NULL DEFAULT '',
+    stock      BIGINT      NOT NULL DEFAULT 0 CHECK (stock >= 0),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    -- a product can't have the same combination of options twice
+    UNIQUE (product_id, options)
+);
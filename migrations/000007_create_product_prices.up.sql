@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS product_prices (
+    product_id UUID        NOT NULL REFERENCES products (id) ON DELETE CASCADE,
+    price_list TEXT        NOT NULL DEFAULT 'retail',
+    currency   CHAR(3)     NOT NULL,
+    -- minor units of the currency, e.g. cents
+    amount     BIGINT      NOT NULL CHECK (amount >= 0),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (product_id, price_list, currency)
+);
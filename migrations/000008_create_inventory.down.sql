@@ -0,0 +1,8 @@
+DROP TABLE IF EXISTS stock_reservations;
+
+ALTER TABLE product_variants ADD COLUMN IF NOT EXISTS stock BIGINT NOT NULL DEFAULT 0 CHECK (stock >= 0);
+
+UPDATE product_variants v SET stock = s.on_hand
+FROM stock_levels s WHERE s.variant_id = v.id;
+
+DROP TABLE IF EXISTS stock_levels;
@@ -0,0 +1,35 @@
+CREATE TABLE IF NOT EXISTS stock_levels (
+    id         UUID        PRIMARY KEY DEFAULT gen_random_uuid(),
+    product_id UUID        NOT NULL REFERENCES products (id) ON DELETE CASCADE,
+    variant_id UUID        REFERENCES product_variants (id) ON DELETE CASCADE,
+    on_hand    BIGINT      NOT NULL DEFAULT 0 CHECK (on_hand >= 0),
+    reserved   BIGINT      NOT NULL DEFAULT 0 CHECK (reserved >= 0),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    CHECK (reserved <= on_hand)
+);
+
+-- one level for the product itself and one per variant
+CREATE UNIQUE INDEX IF NOT EXISTS stock_levels_item_idx
+    ON stock_levels (product_id, (COALESCE(variant_id, '00000000-0000-0000-0000-000000000000'::uuid)));
+
+-- the variant stock moves to stock_levels so reservations lock a single kind of row
+INSERT INTO stock_levels (product_id, variant_id, on_hand)
+SELECT product_id, id, stock FROM product_variants;
+
+ALTER TABLE product_variants DROP COLUMN IF EXISTS stock;
+
+CREATE TABLE IF NOT EXISTS stock_reservations (
+    id             UUID        PRIMARY KEY DEFAULT gen_random_uuid(),
+    product_id     UUID        NOT NULL REFERENCES products (id) ON DELETE CASCADE,
+    variant_id     UUID,
+    stock_level_id UUID        NOT NULL REFERENCES stock_levels (id) ON DELETE CASCADE,
+    quantity       BIGINT      NOT NULL CHECK (quantity > 0),
+    status         TEXT        NOT NULL DEFAULT 'pending',
+    expires_at     TIMESTAMPTZ NOT NULL,
+    created_at     TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at     TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+-- the expiry worker only looks at pending reservations
+CREATE INDEX IF NOT EXISTS stock_reservations_pending_idx
+    ON stock_reservations (expires_at) WHERE status = 'pending';
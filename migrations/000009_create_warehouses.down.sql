@@ -0,0 +1,11 @@
+ALTER TABLE stock_reservations DROP COLUMN IF EXISTS warehouse_id;
+
+-- only the stock of the default warehouse survives, with its reservations
+DELETE FROM stock_levels WHERE warehouse_id <> (SELECT id FROM warehouses WHERE code = 'default');
+
+DROP INDEX IF EXISTS stock_levels_item_idx;
+ALTER TABLE stock_levels DROP COLUMN IF EXISTS warehouse_id;
+CREATE UNIQUE INDEX IF NOT EXISTS stock_levels_item_idx
+    ON stock_levels (product_id, (COALESCE(variant_id, '00000000-0000-0000-0000-000000000000'::uuid)));
+
+DROP TABLE IF EXISTS warehouses;
@@ -0,0 +1,26 @@
+CREATE TABLE IF NOT EXISTS warehouses (
+    id         UUID             PRIMARY KEY DEFAULT gen_random_uuid(),
+    code       TEXT             NOT NULL UNIQUE,
+    name       TEXT             NOT NULL DEFAULT '',
+    latitude   DOUBLE PRECISION,
+    longitude  DOUBLE PRECISION,
+    priority   INTEGER          NOT NULL DEFAULT 0,
+    created_at TIMESTAMPTZ      NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ      NOT NULL DEFAULT now()
+);
+
+-- the stock tracked so far is held in the default warehouse
+INSERT INTO warehouses (code, name) VALUES ('default', 'Default') ON CONFLICT (code) DO NOTHING;
+
+ALTER TABLE stock_levels ADD COLUMN IF NOT EXISTS warehouse_id UUID REFERENCES warehouses (id);
+UPDATE stock_levels SET warehouse_id = (SELECT id FROM warehouses WHERE code = 'default') WHERE warehouse_id IS NULL;
+ALTER TABLE stock_levels ALTER COLUMN warehouse_id SET NOT NULL;
+
+-- one level per warehouse for the product itself and for each variant
+DROP INDEX IF EXISTS stock_levels_item_idx;
+CREATE UNIQUE INDEX IF NOT EXISTS stock_levels_item_idx
+    ON stock_levels (warehouse_id, product_id, (COALESCE(variant_id, '00000000-0000-0000-0000-000000000000'::uuid)));
+
+ALTER TABLE stock_reservations ADD COLUMN IF NOT EXISTS warehouse_id UUID REFERENCES warehouses (id);
+UPDATE stock_reservations r SET warehouse_id = s.warehouse_id FROM stock_levels s WHERE s.id = r.stock_level_id;
+ALTER TABLE stock_reservations ALTER COLUMN warehouse_id SET NOT NULL;
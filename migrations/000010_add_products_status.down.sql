@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS products_unpublish_at_idx;
+DROP INDEX IF EXISTS products_publish_at_idx;
+
+ALTER TABLE products DROP COLUMN IF EXISTS unpublish_at;
+ALTER TABLE products DROP COLUMN IF EXISTS publish_at;
+ALTER TABLE products DROP COLUMN IF EXISTS status;
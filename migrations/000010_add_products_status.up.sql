@@ -0,0 +1,11 @@
+ALTER TABLE products ADD COLUMN IF NOT EXISTS status TEXT NOT NULL DEFAULT 'draft'
+    CHECK (status IN ('draft', 'scheduled', 'published', 'archived'));
+ALTER TABLE products ADD COLUMN IF NOT EXISTS publish_at TIMESTAMPTZ;
+ALTER TABLE products ADD COLUMN IF NOT EXISTS unpublish_at TIMESTAMPTZ;
+
+-- the products that already exist are live
+UPDATE products SET status = 'published', publish_at = created_at;
+
+-- the scheduler looks for the products due to be published or unpublished
+CREATE INDEX IF NOT EXISTS products_publish_at_idx ON products (publish_at) WHERE status = 'scheduled';
+CREATE INDEX IF NOT EXISTS products_unpublish_at_idx ON products (unpublish_at) WHERE status = 'published';
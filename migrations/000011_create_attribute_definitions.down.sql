@@ -0,0 +1,4 @@
+ALTER TABLE products DROP COLUMN IF EXISTS attributes;
+ALTER TABLE products DROP COLUMN IF EXISTS product_type;
+
+DROP TABLE IF EXISTS attribute_definitions;
@@ -0,0 +1,22 @@
+CREATE TABLE IF NOT EXISTS attribute_definitions (
+    id           UUID        PRIMARY KEY DEFAULT gen_random_uuid(),
+    category_id  UUID        REFERENCES categories (id) ON DELETE CASCADE,
+    product_type TEXT,
+    name         TEXT        NOT NULL,
+    type         TEXT        NOT NULL CHECK (type IN ('string', 'number', 'bool', 'enum', 'unit')),
+    required     BOOLEAN     NOT NULL DEFAULT false,
+    options      TEXT[]      NOT NULL DEFAULT '{}',
+    units        TEXT[]      NOT NULL DEFAULT '{}',
+    created_at   TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at   TIMESTAMPTZ NOT NULL DEFAULT now(),
+    -- a definition belongs either to a category or to a product type
+    CHECK ((category_id IS NULL) <> (product_type IS NULL))
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS attribute_definitions_category_name_idx
+    ON attribute_definitions (category_id, name) WHERE category_id IS NOT NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS attribute_definitions_product_type_name_idx
+    ON attribute_definitions (product_type, name) WHERE product_type IS NOT NULL;
+
+ALTER TABLE products ADD COLUMN IF NOT EXISTS product_type TEXT NOT NULL DEFAULT '';
+ALTER TABLE products ADD COLUMN IF NOT EXISTS attributes JSONB NOT NULL DEFAULT '{}';
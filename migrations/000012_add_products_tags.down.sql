@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS products_tags_idx;
+
+ALTER TABLE products DROP COLUMN IF EXISTS tags;
@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS product_slugs;
+
+DROP INDEX IF EXISTS products_slug_idx;
+ALTER TABLE products DROP COLUMN IF EXISTS slug;
@@ -0,0 +1,31 @@
+ALTER TABLE products ADD COLUMN IF NOT EXISTS slug TEXT;
+
+-- existing products get their name as slug, the repeated names are told
+-- apart by the start of their id
+WITH named AS (
+    SELECT id, COALESCE(NULLIF(trim(both '-' from lower(regexp_replace(product_name, '[^a-zA-Z0-9]+', '-', 'g'))), ''), 'product') AS base
+    FROM products
+), numbered AS (
+    SELECT id, base, row_number() OVER (PARTITION BY base ORDER BY id) AS n
+    FROM named
+)
+UPDATE products p
+SET slug = CASE WHEN numbered.n = 1 THEN numbered.base ELSE numbered.base || '-' || left(p.id::text, 8) END
+FROM numbered
+WHERE numbered.id = p.id AND p.slug IS NULL;
+
+ALTER TABLE products ALTER COLUMN slug SET NOT NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS products_slug_idx ON products (slug);
+
+-- every slug a product ever had, a slug is never handed to another product
+CREATE TABLE IF NOT EXISTS product_slugs (
+    slug       TEXT        PRIMARY KEY,
+    product_id UUID        NOT NULL REFERENCES products (id) ON DELETE CASCADE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS product_slugs_product_id_idx ON product_slugs (product_id);
+
+INSERT INTO product_slugs (slug, product_id, created_at)
+SELECT slug, id, created_at FROM products
+ON CONFLICT (slug) DO NOTHING;
@@ -0,0 +1,8 @@
+CREATE TABLE IF NOT EXISTS product_translations (
+    product_id  UUID        NOT NULL REFERENCES products (id) ON DELETE CASCADE,
+    locale      TEXT        NOT NULL,
+    name        TEXT        NOT NULL,
+    description TEXT        NOT NULL,
+    updated_at  TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (product_id, locale)
+);
@@ -0,0 +1,26 @@
+CREATE TABLE IF NOT EXISTS jobs (
+    id               UUID        PRIMARY KEY,
+    kind             TEXT        NOT NULL,
+    status           TEXT        NOT NULL DEFAULT 'queued',
+    payload          JSONB       NOT NULL DEFAULT '{}',
+    input            TEXT        NOT NULL DEFAULT '',
+    result           JSONB,
+    error            TEXT        NOT NULL DEFAULT '',
+    output           TEXT        NOT NULL DEFAULT '',
+    progress_done    BIGINT      NOT NULL DEFAULT 0,
+    progress_total   BIGINT      NOT NULL DEFAULT 0,
+    attempts         INT         NOT NULL DEFAULT 0,
+    max_attempts     INT         NOT NULL DEFAULT 3,
+    cancel_requested BOOLEAN     NOT NULL DEFAULT false,
+    run_at           TIMESTAMPTZ NOT NULL DEFAULT now(),
+    locked_until     TIMESTAMPTZ,
+    started_at       TIMESTAMPTZ,
+    finished_at      TIMESTAMPTZ,
+    created_at       TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at       TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+-- the workers only look at the jobs waiting for a run and the running ones
+-- whose worker may be gone
+CREATE INDEX IF NOT EXISTS jobs_queued_idx ON jobs (run_at) WHERE status = 'queued';
+CREATE INDEX IF NOT EXISTS jobs_running_idx ON jobs (locked_until) WHERE status = 'running';
@@ -0,0 +1,15 @@
+-- the outbox of the product changes, written in the transaction of the
+-- change and published by the relay. The ids are taken before the changes
+-- commit, the position is given by the relay in the order it publishes.
+CREATE TABLE IF NOT EXISTS product_events (
+    id           BIGSERIAL   PRIMARY KEY,
+    product_id   UUID        NOT NULL,
+    type         TEXT        NOT NULL,
+    payload      JSONB       NOT NULL,
+    created_at   TIMESTAMPTZ NOT NULL DEFAULT now(),
+    position     BIGINT,
+    delivered_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS product_events_pending_idx ON product_events (id) WHERE delivered_at IS NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS product_events_position_idx ON product_events (position);
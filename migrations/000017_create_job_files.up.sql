@@ -0,0 +1,11 @@
+-- the inputs and outputs of the jobs, kept in the database so any instance
+-- can run a job and serve what it produced. A file is split in chunks so it
+-- is never held whole on either side.
+CREATE TABLE IF NOT EXISTS job_files (
+    job_id UUID  NOT NULL,
+    file   TEXT  NOT NULL,
+    seq    INT   NOT NULL,
+    data   BYTEA NOT NULL,
+    PRIMARY KEY (job_id, file, seq)
+);
+
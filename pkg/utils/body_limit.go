@@ -0,0 +1,62 @@
+package pkg
+
+import (
+	"io"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// LimitBody caps the request bodies of an app set to stream them, the server
+// hands the bodies past its BodyLimit to the handlers as a stream instead of
+// rejecting them. The body is read into memory up to limit and rejected past
+// it, except on the paths in streamed which read their body as a stream. The
+// paths are matched the way the router matches them, so the trailing slash
+// and the case only count on an app set to strict or case sensitive routing.
+func LimitBody(limit int, streamed ...string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		body := c.Context().RequestBodyStream()
+		if body == nil || isStreamed(c, streamed) {
+			return c.Next()
+		}
+
+		if c.Request().Header.ContentLength() > limit {
+			c.Context().SetConnectionClose()
+			return fiber.ErrRequestEntityTooLarge
+		}
+		raw, err := io.ReadAll(io.LimitReader(body, int64(limit)+1))
+		if err != nil {
+			return fiber.ErrBadRequest
+		}
+		if len(raw) > limit {
+			c.Context().SetConnectionClose()
+			return fiber.ErrRequestEntityTooLarge
+		}
+		c.Request().SetBody(raw)
+
+		return c.Next()
+	}
+}
+
+// isStreamed tells whether the request is for one of the streamed paths.
+func isStreamed(c *fiber.Ctx, streamed []string) bool {
+	cfg := c.App().Config()
+	path := routePath(c.Path(), cfg)
+	for _, p := range streamed {
+		if routePath(p, cfg) == path {
+			return true
+		}
+	}
+	return false
+}
+
+// routePath normalises a path the way the router of the app compares them.
+func routePath(path string, cfg fiber.Config) string {
+	if !cfg.CaseSensitive {
+		path = strings.ToLower(path)
+	}
+	if !cfg.StrictRouting && len(path) > 1 {
+		path = strings.TrimSuffix(path, "/")
+	}
+	return path
+}
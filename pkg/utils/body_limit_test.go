@@ -0,0 +1,53 @@
+package pkg_test
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	pkg "github.com/fahmilukis/go-product-svc/pkg/utils"
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLimitBody(t *testing.T) {
+	app := fiber.New(fiber.Config{StreamRequestBody: true, BodyLimit: 16})
+	app.Use(pkg.LimitBody(16, "/import"))
+	app.Post("/json", func(c *fiber.Ctx) error {
+		return c.Send(c.Body())
+	})
+	app.Post("/import", func(c *fiber.Ctx) error {
+		n, err := io.Copy(io.Discard, c.Context().RequestBodyStream())
+		if err != nil {
+			return err
+		}
+		return c.JSON(n)
+	})
+
+	res, err := app.Test(httptest.NewRequest(http.MethodPost, "/json", strings.NewReader(`{"name":"a"}`)))
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	body, _ := io.ReadAll(res.Body)
+	assert.Equal(t, `{"name":"a"}`, string(body))
+
+	big := strings.Repeat("a", 1024)
+	res, err = app.Test(httptest.NewRequest(http.MethodPost, "/json", strings.NewReader(big)))
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, res.StatusCode)
+
+	// the import streams past the limit
+	res, err = app.Test(httptest.NewRequest(http.MethodPost, "/import", strings.NewReader(big)))
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	body, _ = io.ReadAll(res.Body)
+	assert.Equal(t, "1024", string(body))
+
+	// and so it does on the paths the router takes for it
+	for _, path := range []string{"/import/", "/Import"} {
+		res, err = app.Test(httptest.NewRequest(http.MethodPost, path, strings.NewReader(big)))
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, res.StatusCode, path)
+	}
+}
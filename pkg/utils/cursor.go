@@ -0,0 +1,79 @@
+package pkg
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+)
+
+// ErrInvalidCursor is returned for cursors that were tampered with or signed
+// with another key.
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// cursorKey signs the cursors, it is random unless SetCursorKey is called
+// so cursors don't survive a restart by default.
+var cursorKey = func() []byte {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		panic(err)
+	}
+	return key
+}()
+
+// SetCursorKey sets the secret used to sign the cursors, an empty key keeps
+// the random one.
+func SetCursorKey(key []byte) {
+	if len(key) > 0 {
+		cursorKey = key
+	}
+}
+
+// Cursor is the keyset position of a row in a list ordered by (created_at, id).
+type Cursor struct {
+	CreatedAt time.Time `json:"t"`
+	ID        string    `json:"id"`
+	// Backward reads the page before the position instead of after it.
+	Backward bool `json:"b,omitempty"`
+}
+
+// EncodeCursor turns c into an opaque signed token.
+func EncodeCursor(c Cursor) string {
+	payload, _ := json.Marshal(c)
+	body := base64.RawURLEncoding.EncodeToString(payload)
+	return body + "." + base64.RawURLEncoding.EncodeToString(signCursor(body))
+}
+
+// DecodeCursor verifies and decodes a token built by EncodeCursor.
+func DecodeCursor(token string) (Cursor, error) {
+	body, sig, ok := strings.Cut(token, ".")
+	if !ok {
+		return Cursor{}, ErrInvalidCursor
+	}
+
+	givenSig, err := base64.RawURLEncoding.DecodeString(sig)
+	if err != nil || !hmac.Equal(givenSig, signCursor(body)) {
+		return Cursor{}, ErrInvalidCursor
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(body)
+	if err != nil {
+		return Cursor{}, ErrInvalidCursor
+	}
+	c := Cursor{}
+	if err := json.Unmarshal(payload, &c); err != nil {
+		return Cursor{}, ErrInvalidCursor
+	}
+
+	return c, nil
+}
+
+func signCursor(body string) []byte {
+	mac := hmac.New(sha256.New, cursorKey)
+	mac.Write([]byte(body))
+	return mac.Sum(nil)
+}
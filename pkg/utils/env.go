@@ -0,0 +1,48 @@
+package pkg
+
+import (
+	"log"
+	"os"
+	"strconv"
+	"time"
+)
+
+// GetEnv returns the value of the environment variable or def when it is unset.
+func GetEnv(key, def string) string {
+	if v, ok := os.LookupEnv(key); ok && v != "" {
+		return v
+	}
+	return def
+}
+
+// GetEnvDuration parses the environment variable as a time.Duration, falling
+// back to def when it is unset or malformed.
+func GetEnvDuration(key string, def time.Duration) time.Duration {
+	v, ok := os.LookupEnv(key)
+	if !ok || v == "" {
+		return def
+	}
+
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		log.Printf("invalid duration %q for %s, using %s", v, key, def)
+		return def
+	}
+	return d
+}
+
+// GetEnvInt parses the environment variable as an int, falling back to def
+// when it is unset or malformed.
+func GetEnvInt(key string, def int) int {
+	v, ok := os.LookupEnv(key)
+	if !ok || v == "" {
+		return def
+	}
+
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		log.Printf("invalid int %q for %s, using %d", v, key, def)
+		return def
+	}
+	return n
+}
@@ -0,0 +1,103 @@
+package pkg
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// NormalizeLocale spells a language tag the canonical way, e.g. "id_id"
+// becomes "id-ID" and "zh-hant-tw" becomes "zh-Hant-TW". It returns "" for
+// anything that isn't a tag.
+func NormalizeLocale(raw string) string {
+	parts := strings.FieldsFunc(strings.TrimSpace(raw), func(r rune) bool { return r == '-' || r == '_' })
+	if len(parts) == 0 {
+		return ""
+	}
+
+	for i, part := range parts {
+		if len(part) > 8 || !isAlphanumeric(part) {
+			return ""
+		}
+		switch {
+		case i == 0:
+			if len(part) < 2 || len(part) > 3 {
+				return ""
+			}
+			parts[i] = strings.ToLower(part)
+		case len(part) == 4:
+			parts[i] = strings.ToUpper(part[:1]) + strings.ToLower(part[1:])
+		case len(part) == 2 || len(part) == 3:
+			parts[i] = strings.ToUpper(part)
+		default:
+			parts[i] = strings.ToLower(part)
+		}
+	}
+	return strings.Join(parts, "-")
+}
+
+func isAlphanumeric(s string) bool {
+	for _, r := range s {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9') {
+			return false
+		}
+	}
+	return true
+}
+
+// LocaleFallbacks returns the locale followed by the locales it falls back
+// to, e.g. "zh-Hant-TW", "zh-Hant", "zh".
+func LocaleFallbacks(locale string) []string {
+	locale = NormalizeLocale(locale)
+	if locale == "" {
+		return nil
+	}
+
+	res := []string{locale}
+	for i := strings.LastIndexByte(locale, '-'); i > 0; i = strings.LastIndexByte(locale, '-') {
+		locale = locale[:i]
+		res = append(res, locale)
+	}
+	return res
+}
+
+// ParseAcceptLanguage returns the locales of an Accept-Language header, the
+// preferred first. The wildcard, invalid tags and q=0 are skipped.
+func ParseAcceptLanguage(header string) []string {
+	type weighted struct {
+		locale string
+		q      float64
+	}
+
+	list := make([]weighted, 0)
+	for _, item := range strings.Split(header, ",") {
+		params := strings.Split(item, ";")
+		locale := NormalizeLocale(params[0])
+		if locale == "" {
+			continue
+		}
+
+		q := 1.0
+		for _, param := range params[1:] {
+			param = strings.TrimSpace(param)
+			if strings.HasPrefix(param, "q=") {
+				v, err := strconv.ParseFloat(param[2:], 64)
+				if err != nil {
+					v = 0
+				}
+				q = v
+			}
+		}
+		if q <= 0 {
+			continue
+		}
+		list = append(list, weighted{locale, q})
+	}
+	sort.SliceStable(list, func(i, j int) bool { return list[i].q > list[j].q })
+
+	res := make([]string, 0, len(list))
+	for _, w := range list {
+		res = append(res, w.locale)
+	}
+	return res
+}
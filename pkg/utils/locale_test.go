@@ -0,0 +1,39 @@
+package pkg_test
+
+import (
+	"testing"
+
+	pkg "github.com/fahmilukis/go-product-svc/pkg/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalizeLocale(t *testing.T) {
+	cases := map[string]string{
+		"en":         "en",
+		"id_id":      "id-ID",
+		"ID-id":      "id-ID",
+		"zh-hant-tw": "zh-Hant-TW",
+		"es-419":     "es-419",
+		"":           "",
+		"*":          "",
+		"e":          "",
+		"en-US!":     "",
+	}
+	for raw, locale := range cases {
+		assert.Equal(t, locale, pkg.NormalizeLocale(raw), raw)
+	}
+}
+
+func TestLocaleFallbacks(t *testing.T) {
+	assert.Equal(t, []string{"id-ID", "id"}, pkg.LocaleFallbacks("id-ID"))
+	assert.Equal(t, []string{"zh-Hant-TW", "zh-Hant", "zh"}, pkg.LocaleFallbacks("zh-hant-tw"))
+	assert.Equal(t, []string{"en"}, pkg.LocaleFallbacks("en"))
+	assert.Nil(t, pkg.LocaleFallbacks("*"))
+}
+
+func TestParseAcceptLanguage(t *testing.T) {
+	assert.Equal(t, []string{"id-ID", "id", "en"}, pkg.ParseAcceptLanguage("id-ID,id;q=0.9,en;q=0.8"))
+	assert.Equal(t, []string{"fr", "de-CH", "en"}, pkg.ParseAcceptLanguage("en;q=0.5, de-ch;q=0.7, fr, *;q=0.1"))
+	assert.Equal(t, []string{"en"}, pkg.ParseAcceptLanguage("en, de;q=0, nl;q=abc"))
+	assert.Empty(t, pkg.ParseAcceptLanguage(""))
+}
@@ -0,0 +1,212 @@
+package pkg
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// ErrInvalidAmount is returned for amounts that aren't plain decimals or
+// carry more fraction digits than their currency has.
+var ErrInvalidAmount = errors.New("invalid amount")
+
+// Currency describes an ISO 4217 currency, Exponent is the number of digits
+// after the decimal separator of its minor unit.
+type Currency struct {
+	Code     string `json:"code"`
+	Exponent int    `json:"exponent"`
+	Symbol   string `json:"symbol"`
+}
+
+var currencies = map[string]Currency{
+	"AUD": {Code: "AUD", Exponent: 2, Symbol: "A$"},
+	"CAD": {Code: "CAD", Exponent: 2, Symbol: "CA$"},
+	"CHF": {Code: "CHF", Exponent: 2, Symbol: "CHF"},
+	"CNY": {Code: "CNY", Exponent: 2, Symbol: "¥"},
+	"EUR": {Code: "EUR", Exponent: 2, Symbol: "€"},
+	"GBP": {Code: "GBP", Exponent: 2, Symbol: "£"},
+	"IDR": {Code: "IDR", Exponent: 2, Symbol: "Rp"},
+	"JPY": {Code: "JPY", Exponent: 0, Symbol: "¥"},
+	"KRW": {Code: "KRW", Exponent: 0, Symbol: "₩"},
+	"KWD": {Code: "KWD", Exponent: 3, Symbol: "KD"},
+	"MYR": {Code: "MYR", Exponent: 2, Symbol: "RM"},
+	"SGD": {Code: "SGD", Exponent: 2, Symbol: "S$"},
+	"USD": {Code: "USD", Exponent: 2, Symbol: "$"},
+}
+
+// LookupCurrency returns the currency with the given ISO 4217 code.
+func LookupCurrency(code string) (Currency, bool) {
+	c, ok := currencies[strings.ToUpper(code)]
+	return c, ok
+}
+
+// ParseAmount converts a decimal such as "12.34" to minor units of c. It
+// never goes through a float: exponents, signs and fraction digits the
+// currency can't represent are rejected instead of rounded.
+func ParseAmount(raw string, c Currency) (int64, error) {
+	whole, frac, hasFrac := strings.Cut(raw, ".")
+	if whole == "" || !isDigits(whole) || (hasFrac && (frac == "" || !isDigits(frac))) {
+		return 0, ErrInvalidAmount
+	}
+
+	frac = strings.TrimRight(frac, "0")
+	if len(frac) > c.Exponent {
+		return 0, ErrInvalidAmount
+	}
+	frac += strings.Repeat("0", c.Exponent-len(frac))
+
+	minor, err := strconv.ParseInt(whole+frac, 10, 64)
+	if err != nil {
+		return 0, ErrInvalidAmount
+	}
+	return minor, nil
+}
+
+// FormatAmount renders minor units of c as a plain decimal, e.g. "12.34".
+func FormatAmount(minor int64, c Currency) string {
+	sign := ""
+	if minor < 0 {
+		sign, minor = "-", -minor
+	}
+
+	digits := strconv.FormatInt(minor, 10)
+	if c.Exponent == 0 {
+		return sign + digits
+	}
+	if len(digits) <= c.Exponent {
+		digits = strings.Repeat("0", c.Exponent-len(digits)+1) + digits
+	}
+	cut := len(digits) - c.Exponent
+	return sign + digits[:cut] + "." + digits[cut:]
+}
+
+// ParseRate parses a positive exchange rate written as a plain decimal such
+// as "0.9215", it is kept as an exact fraction.
+func ParseRate(raw string) (*big.Rat, error) {
+	whole, frac, hasFrac := strings.Cut(raw, ".")
+	if !isDigits(whole) || (hasFrac && !isDigits(frac)) {
+		return nil, ErrInvalidAmount
+	}
+
+	rate, ok := new(big.Rat).SetString(raw)
+	if !ok || rate.Sign() <= 0 {
+		return nil, ErrInvalidAmount
+	}
+	return rate, nil
+}
+
+type RoundingMode string
+
+var (
+	RoundHalfEven RoundingMode = "half_even"
+	RoundHalfUp   RoundingMode = "half_up"
+	RoundDown     RoundingMode = "down"
+	RoundUp       RoundingMode = "up"
+)
+
+// RoundingRule says how a converted amount lands on the minor units of its
+// currency. Increment rounds to a multiple of that many minor units, e.g. 5
+// for the Swiss 0.05 steps, zero means 1.
+type RoundingRule struct {
+	Mode      RoundingMode `json:"mode"`
+	Increment int64        `json:"increment,omitempty"`
+}
+
+// Valid reports whether r can be applied, an empty mode is half even.
+func (r RoundingRule) Valid() bool {
+	switch r.Mode {
+	case "", RoundHalfEven, RoundHalfUp, RoundDown, RoundUp:
+		return r.Increment >= 0
+	}
+	return false
+}
+
+// ConvertAmount converts minor units of from into minor units of to at rate,
+// the units of to per unit of from. The arithmetic is exact until the final
+// rounding done by rule.
+func ConvertAmount(minor int64, from, to Currency, rate *big.Rat, rule RoundingRule) (int64, error) {
+	if minor < 0 {
+		return 0, ErrInvalidAmount
+	}
+
+	x := new(big.Rat).SetInt64(minor)
+	x.Mul(x, rate)
+	x.Mul(x, new(big.Rat).SetFrac(pow10(to.Exponent), pow10(from.Exponent)))
+
+	increment := rule.Increment
+	if increment <= 0 {
+		increment = 1
+	}
+	x.Quo(x, new(big.Rat).SetInt64(increment))
+
+	n := roundRat(x, rule.Mode)
+	n.Mul(n, big.NewInt(increment))
+	if !n.IsInt64() {
+		return 0, ErrInvalidAmount
+	}
+	return n.Int64(), nil
+}
+
+// roundRat rounds a non-negative fraction to an integer.
+func roundRat(x *big.Rat, mode RoundingMode) *big.Int {
+	q, m := new(big.Int).QuoRem(x.Num(), x.Denom(), new(big.Int))
+	if m.Sign() == 0 {
+		return q
+	}
+
+	half := new(big.Int).Lsh(m, 1).Cmp(x.Denom())
+	roundUp := false
+	switch mode {
+	case RoundDown:
+	case RoundUp:
+		roundUp = true
+	case RoundHalfUp:
+		roundUp = half >= 0
+	default:
+		roundUp = half > 0 || (half == 0 && q.Bit(0) == 1)
+	}
+
+	if roundUp {
+		q.Add(q, big.NewInt(1))
+	}
+	return q
+}
+
+func pow10(n int) *big.Int {
+	return new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(n)), nil)
+}
+
+// Decimal is an amount as it was written by the client, a JSON number or
+// string kept verbatim so it can be parsed exactly once the currency is known.
+type Decimal string
+
+func (d *Decimal) UnmarshalJSON(b []byte) error {
+	b = bytes.TrimSpace(b)
+	if len(b) > 0 && b[0] == '"' {
+		var s string
+		if err := json.Unmarshal(b, &s); err != nil {
+			return err
+		}
+		*d = Decimal(s)
+		return nil
+	}
+
+	var n json.Number
+	if err := json.Unmarshal(b, &n); err != nil {
+		return ErrInvalidAmount
+	}
+	*d = Decimal(n)
+	return nil
+}
+
+func isDigits(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return len(s) > 0
+}
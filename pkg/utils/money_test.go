@@ -0,0 +1,105 @@
+package pkg_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	pkg "github.com/fahmilukis/go-product-svc/pkg/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseAmount(t *testing.T) {
+	eur, _ := pkg.LookupCurrency("eur")
+	jpy, _ := pkg.LookupCurrency("JPY")
+	kwd, _ := pkg.LookupCurrency("KWD")
+
+	cases := []struct {
+		raw      string
+		currency pkg.Currency
+		minor    int64
+		err      error
+	}{
+		{"12.34", eur, 1234, nil},
+		{"12.3", eur, 1230, nil},
+		{"12", eur, 1200, nil},
+		{"12.340", eur, 1234, nil},
+		{"0.1", kwd, 100, nil},
+		{"1500", jpy, 1500, nil},
+		{"12.345", eur, 0, pkg.ErrInvalidAmount},
+		{"1500.5", jpy, 0, pkg.ErrInvalidAmount},
+		{"1e3", eur, 0, pkg.ErrInvalidAmount},
+		{"-1", eur, 0, pkg.ErrInvalidAmount},
+		{".5", eur, 0, pkg.ErrInvalidAmount},
+		{"5.", eur, 0, pkg.ErrInvalidAmount},
+		{"99999999999999999999", eur, 0, pkg.ErrInvalidAmount},
+	}
+
+	for _, c := range cases {
+		minor, err := pkg.ParseAmount(c.raw, c.currency)
+		assert.Equal(t, c.err, err, c.raw)
+		assert.Equal(t, c.minor, minor, c.raw)
+	}
+}
+
+func TestFormatAmount(t *testing.T) {
+	eur, _ := pkg.LookupCurrency("EUR")
+	jpy, _ := pkg.LookupCurrency("JPY")
+
+	assert.Equal(t, "12.34", pkg.FormatAmount(1234, eur))
+	assert.Equal(t, "0.05", pkg.FormatAmount(5, eur))
+	assert.Equal(t, "1500", pkg.FormatAmount(1500, jpy))
+}
+
+func TestDecimalKeepsTheNumberVerbatim(t *testing.T) {
+	var in struct {
+		Number pkg.Decimal `json:"number"`
+		String pkg.Decimal `json:"string"`
+	}
+	err := json.Unmarshal([]byte(`{"number": 0.10000000000000001, "string": "12.30"}`), &in)
+	assert.NoError(t, err)
+	assert.Equal(t, pkg.Decimal("0.10000000000000001"), in.Number)
+	assert.Equal(t, pkg.Decimal("12.30"), in.String)
+}
+
+func TestConvertAmount(t *testing.T) {
+	usd, _ := pkg.LookupCurrency("USD")
+	jpy, _ := pkg.LookupCurrency("JPY")
+	chf, _ := pkg.LookupCurrency("CHF")
+
+	rate, err := pkg.ParseRate("151.25")
+	assert.NoError(t, err)
+
+	// 12.34 USD * 151.25 = 1866.425 JPY
+	cases := []struct {
+		rule  pkg.RoundingRule
+		minor int64
+	}{
+		{pkg.RoundingRule{}, 1866},
+		{pkg.RoundingRule{Mode: pkg.RoundHalfUp}, 1866},
+		{pkg.RoundingRule{Mode: pkg.RoundUp}, 1867},
+		{pkg.RoundingRule{Mode: pkg.RoundDown}, 1866},
+		{pkg.RoundingRule{Mode: pkg.RoundUp, Increment: 10}, 1870},
+	}
+	for _, c := range cases {
+		minor, err := pkg.ConvertAmount(1234, usd, jpy, rate, c.rule)
+		assert.NoError(t, err)
+		assert.Equal(t, c.minor, minor, c.rule)
+	}
+
+	// 10.01 USD * 0.5 = 5.005 CHF, half even lands on 5.00, half up on 5.01
+	half, _ := pkg.ParseRate("0.5")
+	minor, _ := pkg.ConvertAmount(1001, usd, chf, half, pkg.RoundingRule{Mode: pkg.RoundHalfEven})
+	assert.Equal(t, int64(500), minor)
+	minor, _ = pkg.ConvertAmount(1001, usd, chf, half, pkg.RoundingRule{Mode: pkg.RoundHalfUp})
+	assert.Equal(t, int64(501), minor)
+	// Swiss cash rounding to 0.05
+	minor, _ = pkg.ConvertAmount(1001, usd, chf, half, pkg.RoundingRule{Mode: pkg.RoundHalfUp, Increment: 5})
+	assert.Equal(t, int64(500), minor)
+}
+
+func TestParseRate(t *testing.T) {
+	for _, raw := range []string{"0", "-1.2", "1e3", "1/3", ""} {
+		_, err := pkg.ParseRate(raw)
+		assert.Equal(t, pkg.ErrInvalidAmount, err, raw)
+	}
+}
@@ -1,5 +1,7 @@
 package pkg
 
+import "math"
+
 type Sorting string
 
 var (
@@ -7,11 +9,43 @@ var (
 	SortingDesc Sorting = "DESC"
 )
 
+// CountStrategy picks how the total number of rows of a list is computed.
+type CountStrategy string
+
+var (
+	// CountExact runs a count(*) with the same filter as the page query.
+	CountExact CountStrategy = "exact"
+	// CountEstimate asks the query planner, cheap but approximate.
+	CountEstimate CountStrategy = "estimate"
+	// CountNone skips the count, has_next/has_prev still tell whether
+	// there are more pages.
+	CountNone CountStrategy = "none"
+)
+
+func (s CountStrategy) Valid() bool {
+	switch s {
+	case CountExact, CountEstimate, CountNone:
+		return true
+	}
+	return false
+}
+
+// MaxLimit caps the rows of a page, larger limits are lowered to it.
+const MaxLimit = 500
+
 type Pagination struct {
-	Limit      int   `json:"limit"`
-	Page       int   `json:"page"`
+	Limit      int   `json:"limit" query:"limit"`
+	Page       int   `json:"page" query:"page"`
 	TotalRows  int64 `json:"total_rows"`
 	TotalPages int   `json:"total_pages"`
+	// Cursor asks for keyset pagination starting at the given opaque token,
+	// Page is ignored when it is set.
+	Cursor     string        `json:"cursor,omitempty" query:"cursor"`
+	NextCursor string        `json:"next_cursor,omitempty"`
+	PrevCursor string        `json:"prev_cursor,omitempty"`
+	Count      CountStrategy `json:"count" query:"count"`
+	HasNext    bool          `json:"has_next"`
+	HasPrev    bool          `json:"has_prev"`
 }
 
 func (p *Pagination) GetOffset() int {
@@ -22,8 +56,32 @@ func (p *Pagination) GetLimit() int {
 	if p.Limit == 0 {
 		p.Limit = 10
 	}
+	if p.Limit > MaxLimit {
+		p.Limit = MaxLimit
+	}
 	return p.Limit
 }
+
+// Valid tells whether the limit and the page are in range, zero picks the
+// default of either.
+func (p *Pagination) Valid() bool {
+	return p.Limit >= 0 && p.Page >= 0
+}
+
+// GetCount defaults to an exact count.
+func (p *Pagination) GetCount() CountStrategy {
+	if p.Count == "" {
+		p.Count = CountExact
+	}
+	return p.Count
+}
+
+// SetTotal fills the totals once the rows matching the list are known.
+func (p *Pagination) SetTotal(total int64) {
+	p.TotalRows = total
+	p.TotalPages = int(math.Ceil(float64(total) / float64(p.GetLimit())))
+}
+
 func (p *Pagination) GetPage() int {
 	if p.Page == 0 {
 		p.Page = 1
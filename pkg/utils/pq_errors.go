@@ -0,0 +1,31 @@
+package pkg
+
+import (
+	"errors"
+
+	"github.com/lib/pq"
+)
+
+// IsUniqueViolation reports whether err is a Postgres unique_violation.
+func IsUniqueViolation(err error) bool {
+	return pqCode(err) == "23505"
+}
+
+// IsForeignKeyViolation reports whether err is a Postgres
+// foreign_key_violation.
+func IsForeignKeyViolation(err error) bool {
+	return pqCode(err) == "23503"
+}
+
+// IsCheckViolation reports whether err is a Postgres check_violation.
+func IsCheckViolation(err error) bool {
+	return pqCode(err) == "23514"
+}
+
+func pqCode(err error) pq.ErrorCode {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		return pqErr.Code
+	}
+	return ""
+}
@@ -0,0 +1,146 @@
+package pkg
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrInvalidQuery is returned for sort or filter parameters that are unknown
+// or can't be parsed.
+var ErrInvalidQuery = errors.New("invalid query parameter")
+
+// SortField orders a list by a single field.
+type SortField struct {
+	Field     string
+	Direction Sorting
+}
+
+// ParseSort parses a comma separated list of fields such as
+// "-updated_at,name", a leading "-" sorts that field descending. Only the
+// allowed fields are accepted.
+func ParseSort(raw string, allowed []string) ([]SortField, error) {
+	if strings.TrimSpace(raw) == "" {
+		return nil, nil
+	}
+
+	res := make([]SortField, 0)
+	seen := map[string]bool{}
+	for _, part := range strings.Split(raw, ",") {
+		part = strings.TrimSpace(part)
+		sort := SortField{Field: part, Direction: SortingAsc}
+		if strings.HasPrefix(part, "-") {
+			sort = SortField{Field: part[1:], Direction: SortingDesc}
+		}
+
+		if !contains(allowed, sort.Field) || seen[sort.Field] {
+			return nil, fmt.Errorf("%w: sort by %q", ErrInvalidQuery, sort.Field)
+		}
+		seen[sort.Field] = true
+		res = append(res, sort)
+	}
+
+	return res, nil
+}
+
+// ParseList splits a comma separated parameter such as "id,name", blank
+// items are dropped.
+func ParseList(raw string) []string {
+	res := make([]string, 0)
+	for _, item := range strings.Split(raw, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			res = append(res, item)
+		}
+	}
+	return res
+}
+
+type FilterOp string
+
+var (
+	FilterEq       FilterOp = "eq"
+	FilterContains FilterOp = "contains"
+	FilterAfter    FilterOp = "after"
+	FilterBefore   FilterOp = "before"
+	FilterHas      FilterOp = "has"
+	FilterGte      FilterOp = "gte"
+	FilterLte      FilterOp = "lte"
+)
+
+type FilterType string
+
+var (
+	FilterString FilterType = "string"
+	FilterTime   FilterType = "time"
+	FilterBool   FilterType = "bool"
+	FilterNumber FilterType = "number"
+)
+
+// FilterDef declares a query string parameter accepted as a filter.
+type FilterDef struct {
+	Param string
+	Field string
+	Op    FilterOp
+	Type  FilterType
+}
+
+// Filter is a parsed filter condition, Value holds a string, time.Time, bool
+// or float64 depending on the FilterType of its definition.
+type Filter struct {
+	Field string
+	Op    FilterOp
+	Value interface{}
+}
+
+// ParseFilters reads the parameters declared by defs through get, which is
+// typically fiber's Ctx.Query. Parameters that aren't declared are ignored.
+func ParseFilters(get func(key string, defaultValue ...string) string, defs []FilterDef) ([]Filter, error) {
+	res := make([]Filter, 0)
+	for _, def := range defs {
+		raw := get(def.Param)
+		if raw == "" {
+			continue
+		}
+
+		value, err := ParseFilterValue(def.Type, raw)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s=%q", ErrInvalidQuery, def.Param, raw)
+		}
+		res = append(res, Filter{Field: def.Field, Op: def.Op, Value: value})
+	}
+
+	return res, nil
+}
+
+// ParseFilterValue converts the raw value of a filter to its type.
+func ParseFilterValue(t FilterType, raw string) (interface{}, error) {
+	switch t {
+	case FilterTime:
+		if v, err := time.Parse(time.RFC3339, raw); err == nil {
+			return v, nil
+		}
+		return time.Parse("2006-01-02", raw)
+	case FilterBool:
+		return strconv.ParseBool(raw)
+	case FilterNumber:
+		v, err := strconv.ParseFloat(raw, 64)
+		if err == nil && (math.IsNaN(v) || math.IsInf(v, 0)) {
+			return nil, ErrInvalidQuery
+		}
+		return v, err
+	default:
+		return raw, nil
+	}
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
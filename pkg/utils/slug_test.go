@@ -0,0 +1,30 @@
+package pkg_test
+
+import (
+	"strings"
+	"testing"
+
+	pkg "github.com/fahmilukis/go-product-svc/pkg/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSlugify(t *testing.T) {
+	cases := map[string]string{
+		"Blue Cotton Shirt":        "blue-cotton-shirt",
+		"  Blue -- Cotton/Shirt  ": "blue-cotton-shirt",
+		"Men's T-Shirt (XL)":       "mens-t-shirt-xl",
+		"Crème Brûlée Set":         "creme-brulee-set",
+		"Straße Größe 42":          "strasse-grosse-42",
+		"Футболка":                 "futbolka",
+		"木のスプーン":                   "",
+		"Tee 木 2":                  "tee-2",
+	}
+	for name, slug := range cases {
+		assert.Equal(t, slug, pkg.Slugify(name), name)
+	}
+
+	long := pkg.Slugify(strings.Repeat("shirt ", 30))
+	assert.True(t, len(long) <= 80)
+	assert.False(t, strings.HasSuffix(long, "-"))
+	assert.True(t, strings.HasSuffix(long, "shirt"))
+}
@@ -0,0 +1,76 @@
+package pkg
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+var uuidPattern = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
+// ValidateStruct checks the string fields of the struct v points to against
+// their `validate` tags and returns a reason per failed rule, e.g.
+// "name: required". The rules known are required, lte=n and gte=n on the
+// length in characters, and uuid. The fields are named after their JSON
+// names.
+func ValidateStruct(v interface{}) []string {
+	val := reflect.Indirect(reflect.ValueOf(v))
+	typ := val.Type()
+
+	reasons := make([]string, 0)
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		tag := field.Tag.Get("validate")
+		if tag == "" || field.Type.Kind() != reflect.String {
+			continue
+		}
+
+		name := strings.Split(field.Tag.Get("json"), ",")[0]
+		if name == "" || name == "-" {
+			name = field.Name
+		}
+		s := val.Field(i).String()
+
+		for _, rule := range strings.Split(tag, ",") {
+			if reason := checkRule(rule, s); reason != "" {
+				reasons = append(reasons, fmt.Sprintf("%s: %s", name, reason))
+				break
+			}
+		}
+	}
+
+	return reasons
+}
+
+// checkRule returns why s breaks the rule, or "" when it doesn't.
+func checkRule(rule, s string) string {
+	key, param, _ := strings.Cut(rule, "=")
+	switch key {
+	case "required":
+		if strings.TrimSpace(s) == "" {
+			return "required"
+		}
+	case "uuid":
+		if s != "" && !uuidPattern.MatchString(s) {
+			return "must be a uuid"
+		}
+	case "lte", "gte":
+		n, err := strconv.Atoi(param)
+		if err != nil {
+			return "invalid rule " + rule
+		}
+		length := utf8.RuneCountInString(s)
+		if key == "lte" && length > n {
+			return fmt.Sprintf("must be at most %d characters", n)
+		}
+		if key == "gte" && length < n {
+			return fmt.Sprintf("must be at least %d characters", n)
+		}
+	default:
+		return "unknown rule " + rule
+	}
+	return ""
+}
@@ -0,0 +1,28 @@
+package pkg_test
+
+import (
+	"strings"
+	"testing"
+
+	pkg "github.com/fahmilukis/go-product-svc/pkg/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateStruct(t *testing.T) {
+	type item struct {
+		ID    string `json:"id" validate:"required,uuid"`
+		Name  string `json:"name" validate:"required,lte=5"`
+		Note  string `json:"note,omitempty" validate:"gte=2"`
+		Count int    `json:"count"`
+	}
+
+	assert.Empty(t, pkg.ValidateStruct(&item{ID: "0b7e5a6c-8d3f-4a61-9d0e-3f1c2b4a5d6e", Name: "shirt", Note: "ok"}))
+	assert.Empty(t, pkg.ValidateStruct(item{ID: "0b7e5a6c-8d3f-4a61-9d0e-3f1c2b4a5d6e", Name: "kópia", Note: "ok"}))
+	assert.Equal(t, []string{
+		"id: must be a uuid",
+		"name: required",
+		"note: must be at least 2 characters",
+	}, pkg.ValidateStruct(&item{ID: "12", Name: "  ", Note: "x"}))
+	assert.Equal(t, []string{"id: required", "name: must be at most 5 characters", "note: must be at least 2 characters"},
+		pkg.ValidateStruct(&item{Name: strings.Repeat("a", 6)}))
+}
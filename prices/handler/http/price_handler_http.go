@@ -0,0 +1,86 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/fahmilukis/go-product-svc/domain"
+	"github.com/gofiber/fiber/v2"
+)
+
+type PriceHandler struct {
+	PriceUC domain.PriceUsecase
+}
+
+func PriceRoute(a *fiber.App, pruc domain.PriceUsecase) {
+	handler := &PriceHandler{
+		PriceUC: pruc,
+	}
+
+	route := a.Group("/api/v1")
+
+	route.Get("/product/:id/prices", handler.GetListPrices)
+	route.Put("/product/:id/prices", handler.SetPrice)
+	route.Delete("/product/:id/prices/:price_list/:currency", handler.DeletePrice)
+}
+
+func (h *PriceHandler) GetListPrices(c *fiber.Ctx) error {
+	data, err := h.PriceUC.Fetch(c.Context(), c.Params("id"))
+	if err != nil {
+		return c.Status(domain.StatusCode(err)).JSON(fiber.Map{
+			"status": false,
+			"msg":    err.Error(),
+		})
+	}
+
+	quotes := make([]domain.PriceQuote, 0, len(data))
+	for _, p := range data {
+		quotes = append(quotes, domain.NewPriceQuote(p))
+	}
+
+	return c.JSON(fiber.Map{
+		"status": true,
+		"msg":    "success get price lists",
+		"data":   quotes,
+	})
+}
+
+// SetPrice creates or replaces the price of the product in a price list and
+// currency. The amount is a decimal in the major unit, e.g. "12.34".
+func (h *PriceHandler) SetPrice(c *fiber.Ctx) error {
+	in := domain.PriceInput{}
+	if err := c.BodyParser(&in); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"status": false,
+			"msg":    err.Error(),
+		})
+	}
+
+	data, err := h.PriceUC.Set(c.Context(), c.Params("id"), in)
+	if err != nil {
+		return c.Status(domain.StatusCode(err)).JSON(fiber.Map{
+			"status": false,
+			"msg":    err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"status": true,
+		"msg":    "success set price",
+		"data":   domain.NewPriceQuote(data),
+	})
+}
+
+func (h *PriceHandler) DeletePrice(c *fiber.Ctx) error {
+	err := h.PriceUC.Delete(c.Context(), c.Params("id"), c.Params("price_list"), c.Params("currency"))
+	if err != nil {
+		return c.Status(domain.StatusCode(err)).JSON(fiber.Map{
+			"status": false,
+			"msg":    err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"status": true,
+		"msg":    "success delete price",
+	})
+}
@@ -0,0 +1,113 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/fahmilukis/go-product-svc/domain"
+	pkg "github.com/fahmilukis/go-product-svc/pkg/utils"
+	"github.com/lib/pq"
+	"github.com/sirupsen/logrus"
+)
+
+const priceColumns = `product_id,price_list,currency,amount,updated_at`
+
+type priceDBRepositories struct {
+	Conn *sql.DB
+}
+
+func NewPriceDBRepository(conn *sql.DB) *priceDBRepositories {
+	return &priceDBRepositories{Conn: conn}
+}
+
+// fetch to DB
+func (p *priceDBRepositories) fetch(ctx context.Context, query string, args ...interface{}) (res []domain.Price, err error) {
+	rows, err := p.Conn.QueryContext(ctx, query, args...)
+	if err != nil {
+		logrus.Error(err)
+		return nil, err
+	}
+
+	defer func() {
+		errRow := rows.Close()
+		if errRow != nil {
+			logrus.Error(err)
+		}
+	}()
+
+	res = make([]domain.Price, 0)
+	for rows.Next() {
+		pr := domain.Price{}
+		err = rows.Scan(
+			&pr.ProductID,
+			&pr.PriceList,
+			&pr.Currency,
+			&pr.Amount,
+			&pr.UpdatedAt,
+		)
+		if err != nil {
+			logrus.Error(err)
+			return nil, err
+		}
+		res = append(res, pr)
+	}
+
+	return res, nil
+}
+
+func (p *priceDBRepositories) Fetch(ctx context.Context, productID string) (res []domain.Price, err error) {
+	query := `SELECT ` + priceColumns + ` FROM product_prices WHERE product_id=$1 ORDER BY price_list, currency`
+	return p.fetch(ctx, query, productID)
+}
+
+func (p *priceDBRepositories) Get(ctx context.Context, productID, priceList, currency string) (res domain.Price, err error) {
+	query := `SELECT ` + priceColumns + ` FROM product_prices WHERE product_id=$1 AND price_list=$2 AND currency=$3`
+	list, err := p.fetch(ctx, query, productID, priceList, currency)
+	if err != nil {
+		return domain.Price{}, err
+	}
+
+	if len(list) > 0 {
+		res = list[0]
+	} else {
+		return res, domain.ErrNotFound
+	}
+
+	return
+}
+
+func (p *priceDBRepositories) FetchByProducts(ctx context.Context, productIDs []string, priceLists []string) (res []domain.Price, err error) {
+	query := `SELECT ` + priceColumns + ` FROM product_prices WHERE product_id = ANY($1) AND price_list = ANY($2) ORDER BY product_id, price_list, currency`
+	return p.fetch(ctx, query, pq.Array(productIDs), pq.Array(priceLists))
+}
+
+// Upsert sets the price of the product in a list and currency, replacing the
+// previous one.
+func (p *priceDBRepositories) Upsert(ctx context.Context, pr *domain.Price) (err error) {
+	query := `INSERT INTO product_prices (product_id,price_list,currency,amount,updated_at) VALUES ($1, $2, $3, $4, $5)
+	ON CONFLICT (product_id, price_list, currency) DO UPDATE SET amount = EXCLUDED.amount, updated_at = EXCLUDED.updated_at`
+
+	_, err = p.Conn.ExecContext(ctx, query, pr.ProductID, pr.PriceList, pr.Currency, pr.Amount, pr.UpdatedAt)
+	if pkg.IsForeignKeyViolation(err) {
+		return domain.ErrNotFound
+	}
+	return
+}
+
+func (p *priceDBRepositories) Delete(ctx context.Context, productID, priceList, currency string) (err error) {
+	query := `DELETE FROM product_prices WHERE product_id=$1 AND price_list=$2 AND currency=$3`
+	res, err := p.Conn.ExecContext(ctx, query, productID, priceList, currency)
+	if err != nil {
+		return
+	}
+
+	rowsAfected, err := res.RowsAffected()
+	if err != nil {
+		return
+	}
+	if rowsAfected == 0 {
+		return domain.ErrNotFound
+	}
+
+	return
+}
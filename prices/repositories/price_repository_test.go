@@ -0,0 +1,86 @@
+package repositories_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/fahmilukis/go-product-svc/domain"
+	"github.com/fahmilukis/go-product-svc/prices/repositories"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/DATA-DOG/go-sqlmock.v1"
+)
+
+func TestGetRepositoryPrice(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	now := time.Now()
+
+	rows := sqlmock.NewRows([]string{"product_id", "price_list", "currency", "amount", "updated_at"}).
+		AddRow("p1", "wholesale", "EUR", 1234, now)
+	mock.ExpectQuery(`FROM product_prices WHERE product_id=\$1 AND price_list=\$2 AND currency=\$3`).
+		WithArgs("p1", "wholesale", "EUR").WillReturnRows(rows)
+
+	a := repositories.NewPriceDBRepository(db)
+	price, err := a.Get(context.TODO(), "p1", "wholesale", "EUR")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1234), price.Amount)
+
+	quote := domain.NewPriceQuote(price)
+	assert.Equal(t, "12.34", quote.Decimal)
+	assert.Equal(t, 2, quote.Exponent)
+	assert.Equal(t, "€", quote.Symbol)
+}
+
+func TestGetRepositoryPriceNotFound(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	rows := sqlmock.NewRows([]string{"product_id", "price_list", "currency", "amount", "updated_at"})
+	mock.ExpectQuery(`FROM product_prices`).WithArgs("p1", "retail", "JPY").WillReturnRows(rows)
+
+	a := repositories.NewPriceDBRepository(db)
+	_, err = a.Get(context.TODO(), "p1", "retail", "JPY")
+	assert.Equal(t, domain.ErrNotFound, err)
+}
+
+func TestUpsertRepositoryPrice(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	price := &domain.Price{ProductID: "p1", PriceList: "retail", Currency: "USD", Amount: 999, UpdatedAt: time.Now()}
+
+	mock.ExpectExec(`INSERT INTO product_prices .* ON CONFLICT \(product_id, price_list, currency\) DO UPDATE`).
+		WithArgs(price.ProductID, price.PriceList, price.Currency, price.Amount, price.UpdatedAt).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	a := repositories.NewPriceDBRepository(db)
+	err = a.Upsert(context.TODO(), price)
+	assert.NoError(t, err)
+}
+
+func TestFetchByProductsRepositoryPrice(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	now := time.Now()
+
+	rows := sqlmock.NewRows([]string{"product_id", "price_list", "currency", "amount", "updated_at"}).
+		AddRow("p1", "retail", "USD", 1234, now).
+		AddRow("p2", "wholesale", "EUR", 900, now)
+	mock.ExpectQuery(`FROM product_prices WHERE product_id = ANY\(\$1\) AND price_list = ANY\(\$2\)`).
+		WithArgs(pq.Array([]string{"p1", "p2"}), pq.Array([]string{"wholesale", "retail"})).
+		WillReturnRows(rows)
+
+	a := repositories.NewPriceDBRepository(db)
+	list, err := a.FetchByProducts(context.TODO(), []string{"p1", "p2"}, []string{"wholesale", "retail"})
+	assert.NoError(t, err)
+	assert.Len(t, list, 2)
+}
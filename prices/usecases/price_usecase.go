@@ -0,0 +1,191 @@
+package usecases
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/fahmilukis/go-product-svc/domain"
+	pkg "github.com/fahmilukis/go-product-svc/pkg/utils"
+)
+
+type priceUsecase struct {
+	productRepository domain.ProductRepository
+	priceRepository   domain.PriceRepository
+	rateRepository    domain.ExchangeRateRepository
+	ctxTimeout        time.Duration
+}
+
+func NewPriceUsecase(p domain.ProductRepository, pr domain.PriceRepository, r domain.ExchangeRateRepository, to time.Duration) domain.PriceUsecase {
+	return &priceUsecase{
+		productRepository: p,
+		priceRepository:   pr,
+		rateRepository:    r,
+		ctxTimeout:        to,
+	}
+}
+
+func (u *priceUsecase) Fetch(c context.Context, productID string) (res []domain.Price, err error) {
+	ctx, cancel := context.WithTimeout(c, u.ctxTimeout)
+	defer cancel()
+
+	if _, err = u.productRepository.GetByID(ctx, productID, domain.ProductFilter{}); err != nil {
+		return nil, err
+	}
+
+	return u.priceRepository.Fetch(ctx, productID)
+}
+
+func (u *priceUsecase) Quote(c context.Context, productID string, q domain.PriceQuery) (res domain.PriceQuote, err error) {
+	quotes, err := u.QuoteMany(c, []string{productID}, q)
+	if err != nil {
+		return domain.PriceQuote{}, err
+	}
+
+	res, ok := quotes[productID]
+	if !ok {
+		return domain.PriceQuote{}, domain.ErrNotFound
+	}
+	return res, nil
+}
+
+// QuoteMany prefers a price set in the requested currency. With q.Convert a
+// product that has none is quoted from its price in the base currency of the
+// exchange rates, or else from any price whose currency has a rate.
+func (u *priceUsecase) QuoteMany(c context.Context, productIDs []string, q domain.PriceQuery) (res map[string]domain.PriceQuote, err error) {
+	ctx, cancel := context.WithTimeout(c, u.ctxTimeout)
+	defer cancel()
+
+	cur, ok := pkg.LookupCurrency(q.Currency)
+	if !ok {
+		return nil, domain.ErrBadParamInput
+	}
+	lists := []string{domain.DefaultPriceList}
+	if priceList := strings.ToLower(q.PriceList); priceList != "" && priceList != domain.DefaultPriceList {
+		lists = []string{priceList, domain.DefaultPriceList}
+	}
+
+	var rates *domain.ExchangeRates
+	if q.Convert {
+		r, err := u.rateRepository.Get(ctx)
+		switch err {
+		case nil:
+			rates = &r
+		case domain.ErrNotFound:
+			// without a rate table only the prices set in the currency show
+		default:
+			return nil, err
+		}
+	}
+
+	prices, err := u.priceRepository.FetchByProducts(ctx, productIDs, lists)
+	if err != nil {
+		return nil, err
+	}
+	byProduct := map[string][]domain.Price{}
+	for _, p := range prices {
+		byProduct[p.ProductID] = append(byProduct[p.ProductID], p)
+	}
+
+	res = make(map[string]domain.PriceQuote, len(byProduct))
+	for id, productPrices := range byProduct {
+		for _, list := range lists {
+			quote, ok, err := pickPrice(productPrices, list, cur.Code, rates)
+			if err != nil {
+				return nil, err
+			}
+			if ok {
+				res[id] = quote
+				break
+			}
+		}
+	}
+
+	return res, nil
+}
+
+// pickPrice quotes the price of a list in currency, converting another price
+// of the list when rates is set.
+func pickPrice(prices []domain.Price, list, currency string, rates *domain.ExchangeRates) (domain.PriceQuote, bool, error) {
+	var source *domain.Price
+	for i, p := range prices {
+		if p.PriceList != list {
+			continue
+		}
+		if p.Currency == currency {
+			return domain.NewPriceQuote(p), true, nil
+		}
+		if rates == nil || !rates.Has(p.Currency) {
+			continue
+		}
+		if source == nil || p.Currency == rates.Base {
+			source = &prices[i]
+		}
+	}
+	if source == nil || !rates.Has(currency) {
+		return domain.PriceQuote{}, false, nil
+	}
+
+	amount, rate, err := rates.Convert(source.Amount, source.Currency, currency)
+	if err != nil {
+		return domain.PriceQuote{}, false, err
+	}
+	quote := domain.NewPriceQuote(domain.Price{
+		ProductID: source.ProductID,
+		PriceList: source.PriceList,
+		Currency:  currency,
+		Amount:    amount,
+	})
+	quote.Conversion = &domain.PriceConversion{
+		FromCurrency:  source.Currency,
+		FromAmount:    source.Amount,
+		Rate:          rate.FloatString(6),
+		RateUpdatedAt: rates.UpdatedAt,
+	}
+
+	return quote, true, nil
+}
+
+// Set parses the amount against the currency of the input, so "9.999" EUR
+// is rejected rather than rounded.
+func (u *priceUsecase) Set(c context.Context, productID string, in domain.PriceInput) (res domain.Price, err error) {
+	ctx, cancel := context.WithTimeout(c, u.ctxTimeout)
+	defer cancel()
+
+	cur, ok := pkg.LookupCurrency(in.Currency)
+	if !ok {
+		return domain.Price{}, domain.ErrBadParamInput
+	}
+	amount, err := pkg.ParseAmount(string(in.Amount), cur)
+	if err != nil {
+		return domain.Price{}, domain.ErrBadParamInput
+	}
+	priceList := strings.ToLower(strings.TrimSpace(in.PriceList))
+	if priceList == "" {
+		priceList = domain.DefaultPriceList
+	}
+
+	if _, err = u.productRepository.GetByID(ctx, productID, domain.ProductFilter{}); err != nil {
+		return domain.Price{}, err
+	}
+
+	res = domain.Price{
+		ProductID: productID,
+		PriceList: priceList,
+		Currency:  cur.Code,
+		Amount:    amount,
+		UpdatedAt: time.Now(),
+	}
+	if err = u.priceRepository.Upsert(ctx, &res); err != nil {
+		return domain.Price{}, err
+	}
+
+	return res, nil
+}
+
+func (u *priceUsecase) Delete(c context.Context, productID, priceList, currency string) (err error) {
+	ctx, cancel := context.WithTimeout(c, u.ctxTimeout)
+	defer cancel()
+
+	return u.priceRepository.Delete(ctx, productID, strings.ToLower(priceList), strings.ToUpper(currency))
+}
@@ -0,0 +1,108 @@
+package events
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/fahmilukis/go-product-svc/domain"
+)
+
+// filePublisher appends the events to a JSON Lines file on local disk, one
+// event per line. The IDs of the events in the file are read back when it is
+// opened, the events published again after a failed relay aren't written
+// twice.
+type filePublisher struct {
+	mu      sync.Mutex
+	path    string
+	written map[int64]bool
+}
+
+func NewFilePublisher(path string) (domain.EventPublisher, error) {
+	pub := &filePublisher{path: path, written: map[int64]bool{}}
+	if err := pub.load(); err != nil {
+		return nil, err
+	}
+
+	return pub, nil
+}
+
+func (f *filePublisher) Publish(ctx context.Context, events []domain.ProductEvent) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	ids := make([]int64, 0, len(events))
+	for _, event := range events {
+		if f.written[event.ID] {
+			continue
+		}
+		if err := enc.Encode(event); err != nil {
+			return err
+		}
+		ids = append(ids, event.ID)
+	}
+	if buf.Len() == 0 {
+		return nil
+	}
+
+	file, err := os.OpenFile(f.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err = file.Write(buf.Bytes()); err == nil {
+		err = file.Sync()
+	}
+	if errClose := file.Close(); err == nil {
+		err = errClose
+	}
+	if err != nil {
+		return err
+	}
+
+	for _, id := range ids {
+		f.written[id] = true
+	}
+	return nil
+}
+
+// load reads the IDs of the events in the file. A last line left
+// unfinished by a crash is cut off, its event is still pending and is
+// written again.
+func (f *filePublisher) load() error {
+	if err := os.MkdirAll(filepath.Dir(f.path), 0o755); err != nil {
+		return err
+	}
+
+	file, err := os.OpenFile(f.path, os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	var complete int64
+	r := bufio.NewReader(file)
+	for {
+		line, err := r.ReadBytes('\n')
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		event := domain.ProductEvent{}
+		if err := json.Unmarshal(line, &event); err != nil {
+			return err
+		}
+		f.written[event.ID] = true
+		complete += int64(len(line))
+	}
+
+	return file.Truncate(complete)
+}
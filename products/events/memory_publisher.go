@@ -0,0 +1,61 @@
+package events
+
+import (
+	"context"
+	"sync"
+
+	"github.com/fahmilukis/go-product-svc/domain"
+)
+
+// MemoryPublisher keeps the last events published in memory for the
+// consumers of the same process, e.g. a local cache or a test. An event it
+// still keeps is dropped when it is published again, the limit has to be
+// larger than a batch of the relay.
+type MemoryPublisher struct {
+	mu     sync.RWMutex
+	limit  int
+	events []domain.ProductEvent
+	kept   map[int64]bool
+}
+
+// NewMemoryPublisher keeps at most limit events, the oldest go first.
+func NewMemoryPublisher(limit int) *MemoryPublisher {
+	return &MemoryPublisher{limit: limit, kept: map[int64]bool{}}
+}
+
+func (m *MemoryPublisher) Publish(ctx context.Context, events []domain.ProductEvent) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, event := range events {
+		if m.kept[event.ID] {
+			continue
+		}
+		m.events = append(m.events, event)
+		m.kept[event.ID] = true
+	}
+	if over := len(m.events) - m.limit; over > 0 {
+		for _, event := range m.events[:over] {
+			delete(m.kept, event.ID)
+		}
+		m.events = append([]domain.ProductEvent(nil), m.events[over:]...)
+	}
+
+	return nil
+}
+
+// Events returns the events kept that came after the position afterPosition,
+// a consumer passes the position of the last event it got.
+func (m *MemoryPublisher) Events(afterPosition int64) []domain.ProductEvent {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	res := make([]domain.ProductEvent, 0)
+	for _, event := range m.events {
+		if event.Position > afterPosition {
+			res = append(res, event)
+		}
+	}
+
+	return res
+}
@@ -0,0 +1,110 @@
+package events_test
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/fahmilukis/go-product-svc/domain"
+	"github.com/fahmilukis/go-product-svc/products/events"
+	"github.com/stretchr/testify/assert"
+)
+
+// productEvents builds the events of the ids, positioned in the order given.
+func productEvents(position int64, ids ...int64) []domain.ProductEvent {
+	res := make([]domain.ProductEvent, len(ids))
+	for i, id := range ids {
+		res[i] = domain.ProductEvent{ID: id, ProductID: "1", Type: domain.ProductUpdated, Payload: json.RawMessage(`{"id":"1"}`), Position: position + int64(i)}
+	}
+	return res
+}
+
+func TestMemoryPublisher(t *testing.T) {
+	pub := events.NewMemoryPublisher(3)
+
+	assert.NoError(t, pub.Publish(context.TODO(), productEvents(1, 1, 2)))
+	// 2 is published again after a failed relay
+	assert.NoError(t, pub.Publish(context.TODO(), productEvents(2, 2, 3, 4)))
+
+	got := pub.Events(0)
+	assert.Len(t, got, 3)
+	assert.Equal(t, int64(2), got[0].ID)
+	assert.Equal(t, int64(4), got[2].ID)
+	assert.Len(t, pub.Events(3), 1)
+}
+
+func TestMemoryPublisherOutOfOrder(t *testing.T) {
+	pub := events.NewMemoryPublisher(10)
+
+	// 5 committed before 4, which is published by the next run
+	assert.NoError(t, pub.Publish(context.TODO(), productEvents(1, 5)))
+	assert.NoError(t, pub.Publish(context.TODO(), productEvents(2, 4)))
+
+	got := pub.Events(0)
+	assert.Len(t, got, 2)
+	assert.Equal(t, int64(5), got[0].ID)
+	assert.Equal(t, int64(4), got[1].ID)
+	assert.Equal(t, int64(2), got[1].Position)
+}
+
+// readEvents reads back the events of the file.
+func readEvents(t *testing.T, path string) []domain.ProductEvent {
+	raw, err := os.ReadFile(path)
+	assert.NoError(t, err)
+
+	var res []domain.ProductEvent
+	for _, line := range strings.Split(strings.TrimSpace(string(raw)), "\n") {
+		event := domain.ProductEvent{}
+		assert.NoError(t, json.Unmarshal([]byte(line), &event))
+		res = append(res, event)
+	}
+	return res
+}
+
+func TestFilePublisher(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "events", "events.jsonl")
+	pub, err := events.NewFilePublisher(path)
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening the publisher", err)
+	}
+	assert.NoError(t, pub.Publish(context.TODO(), productEvents(1, 1, 2)))
+
+	// a crash left half a line behind
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0o644)
+	assert.NoError(t, err)
+	_, err = f.WriteString(`{"id":3,"product_id"`)
+	assert.NoError(t, err)
+	assert.NoError(t, f.Close())
+
+	pub, err = events.NewFilePublisher(path)
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when reopening the publisher", err)
+	}
+	assert.NoError(t, pub.Publish(context.TODO(), productEvents(2, 2, 3)))
+
+	got := readEvents(t, path)
+	assert.Len(t, got, 3)
+	for i, event := range got {
+		assert.Equal(t, int64(i+1), event.ID)
+	}
+}
+
+func TestFilePublisherOutOfOrder(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "events.jsonl")
+	pub, err := events.NewFilePublisher(path)
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening the publisher", err)
+	}
+
+	// 5 committed before 4, which is published by the next run
+	assert.NoError(t, pub.Publish(context.TODO(), productEvents(1, 5)))
+	assert.NoError(t, pub.Publish(context.TODO(), productEvents(2, 4, 5)))
+
+	got := readEvents(t, path)
+	assert.Len(t, got, 2)
+	assert.Equal(t, int64(5), got[0].ID)
+	assert.Equal(t, int64(4), got[1].ID)
+}
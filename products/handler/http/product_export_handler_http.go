@@ -0,0 +1,152 @@
+package handler
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/fahmilukis/go-product-svc/domain"
+	"github.com/gofiber/fiber/v2"
+	"github.com/sirupsen/logrus"
+)
+
+// exportFormats maps the ?format= of an export to the format written, ndjson
+// is another name of jsonl.
+var exportFormats = map[string]string{
+	"csv":    domain.ProductExportCSV,
+	"jsonl":  domain.ProductExportJSONL,
+	"ndjson": domain.ProductExportJSONL,
+}
+
+// exportContentTypes are the content types of the export formats.
+var exportContentTypes = map[string]string{
+	domain.ProductExportCSV:   "text/csv",
+	domain.ProductExportJSONL: "application/x-ndjson",
+}
+
+// parseExport reads the format, the gzip flag and the filters of an export,
+// which are the ones of the list.
+func parseExport(c *fiber.Ctx) (req domain.ProductExportRequest, err error) {
+	format, ok := exportFormats[c.Query("format", "csv")]
+	if !ok {
+		return req, fmt.Errorf("unknown export format %q", c.Query("format"))
+	}
+	filter, err := parseProductFilter(c)
+	if err != nil {
+		return req, err
+	}
+	filter.PublishedOnly = c.Query("admin") != "true"
+
+	return domain.ProductExportRequest{Format: format, Gzip: c.Query("gzip") == "true", Filter: filter}, nil
+}
+
+// ExportProducts streams every product matching the filters of the list as
+// a file, e.g. ?format=jsonl&gzip=true&admin=true. The rows are written as
+// they are read from the database, a failure midway cuts the file short and
+// leaves a gzip file without its trailer.
+func (puc *ProductHandler) ExportProducts(c *fiber.Ctx) error {
+	req, err := parseExport(c)
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"status": false,
+			"msg":    err.Error(),
+		})
+	}
+
+	// the cursor outlives the handler, it is bound to its own ctx rather than
+	// the one of the server and closed by the stream writer. It is closed
+	// here when the writer isn't set.
+	ctx, cancel := context.WithCancel(context.Background())
+	export, err := puc.ProductUC.Export(ctx, req.Filter)
+	if err != nil {
+		cancel()
+		return c.Status(getStatusCode(err)).JSON(fiber.Map{
+			"status": false,
+			"msg":    err.Error(),
+		})
+	}
+	streaming := false
+	defer func() {
+		if streaming {
+			return
+		}
+		if errClose := export.Close(); errClose != nil {
+			logrus.Error(errClose)
+		}
+		cancel()
+	}()
+
+	name, contentType := "products."+req.Format, exportContentTypes[req.Format]
+	if req.Gzip {
+		name, contentType = name+".gz", "application/gzip"
+	}
+	c.Attachment(name)
+	c.Set(fiber.HeaderContentType, contentType)
+
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		defer cancel()
+		defer func() {
+			if err := export.Close(); err != nil {
+				logrus.Error(err)
+			}
+		}()
+
+		if err := writeExport(ctx, puc.ProductUC, w, export, req); err != nil {
+			logrus.Errorf("product export stopped: %v", err)
+		}
+	})
+	streaming = true
+
+	return nil
+}
+
+// writeExport writes the export to w, compressed when asked to. The gzip
+// trailer is only written once every product was.
+func writeExport(ctx context.Context, puc domain.ProductUsecase, w io.Writer, export domain.ProductExport, req domain.ProductExportRequest) error {
+	if !req.Gzip {
+		_, err := puc.WriteExport(ctx, w, export, req.Format, req.Filter.Fields)
+		return err
+	}
+
+	zw := gzip.NewWriter(w)
+	if _, err := puc.WriteExport(ctx, zw, export, req.Format, req.Filter.Fields); err != nil {
+		return err
+	}
+	return zw.Close()
+}
+
+// ExportProductsAsync exports the products like ExportProducts in a job, the
+// file is downloaded from the job once it succeeded.
+func (puc *ProductHandler) ExportProductsAsync(c *fiber.Ctx) error {
+	req, err := parseExport(c)
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"status": false,
+			"msg":    err.Error(),
+		})
+	}
+
+	job, err := puc.JobUC.Enqueue(c.Context(), domain.JobProductExport, req, nil)
+	if err != nil {
+		return c.Status(getStatusCode(err)).JSON(fiber.Map{
+			"status": false,
+			"msg":    err.Error(),
+		})
+	}
+
+	return jobAccepted(c, job, "success enqueue product export")
+}
+
+// jobAccepted answers a request handed to a job with the job, its progress
+// is polled at the Location.
+func jobAccepted(c *fiber.Ctx, job domain.Job, msg string) error {
+	c.Location("/api/v1/jobs/" + job.ID)
+	return c.Status(http.StatusAccepted).JSON(fiber.Map{
+		"status": true,
+		"msg":    msg,
+		"data":   job,
+	})
+}
@@ -1,7 +1,16 @@
 package handler
 
 import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"net/http"
+	"sort"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/fahmilukis/go-product-svc/domain"
@@ -10,20 +19,43 @@ import (
 	"github.com/google/uuid"
 )
 
+var errIfMatchRequired = errors.New("If-Match header is required")
+
 type ProductHandler struct {
-	ProductUC domain.ProductUsecase
+	ProductUC     domain.ProductUsecase
+	PriceUC       domain.PriceUsecase
+	InventoryUC   domain.InventoryUsecase
+	TranslationUC domain.ProductTranslationUsecase
+	JobUC         domain.JobUsecase
 }
 
-func ProductRoute(a *fiber.App, puc domain.ProductUsecase) {
+func ProductRoute(a *fiber.App, puc domain.ProductUsecase, pruc domain.PriceUsecase, iuc domain.InventoryUsecase, tuc domain.ProductTranslationUsecase, juc domain.JobUsecase) {
 	handler := &ProductHandler{
-		ProductUC: puc,
+		ProductUC:     puc,
+		PriceUC:       pruc,
+		InventoryUC:   iuc,
+		TranslationUC: tuc,
+		JobUC:         juc,
 	}
 
-	route := a.Group("/api/v1")
+	route := a.Group("/api/v1", withActor)
 
 	route.Post("/product", handler.CreateProduct)
 	route.Get("/product", handler.GetListProducts)
+	route.Get("/product/trash", handler.GetTrashedProducts)
+	route.Get("/product/search", handler.SearchProducts)
+	route.Get("/product/tags", handler.GetTags)
+	route.Get("/product/export", handler.ExportProducts)
+	route.Post("/product/export", handler.ExportProductsAsync)
+	route.Post("/product/reindex", handler.ReindexProducts)
+	route.Get("/product/by-slug/:slug", handler.GetProductBySlug)
+	route.Post("/product/tags/rename", handler.RenameTags)
+	route.Post("/product/import", handler.ImportProducts)
 	route.Get("/product/:id", handler.GetProductDetail)
+	route.Put("/product/:id", handler.UpdateProduct)
+	route.Patch("/product/:id", handler.PatchProduct)
+	route.Delete("/product/:id", handler.DeleteProduct)
+	route.Post("/product/:id/restore", handler.RestoreProduct)
 }
 
 func (puc *ProductHandler) CreateProduct(c *fiber.Ctx) error {
@@ -44,7 +76,7 @@ func (puc *ProductHandler) CreateProduct(c *fiber.Ctx) error {
 	prd.UpdatedAt = now
 
 	if err := puc.ProductUC.Store(c.Context(), prd); err != nil {
-		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+		return c.Status(getStatusCode(err)).JSON(fiber.Map{
 			"status": false,
 			"msg":    err.Error(),
 		})
@@ -56,58 +88,748 @@ func (puc *ProductHandler) CreateProduct(c *fiber.Ctx) error {
 	})
 }
 
+// GetListProducts lists the published products, ?admin=true lists them in
+// every status. The names and descriptions are localized like in
+// GetProductDetail.
 func (puc *ProductHandler) GetListProducts(c *fiber.Ctx) error {
 	params := &pkg.Pagination{}
-	if err := c.BodyParser(params); err != nil {
+	if err := c.QueryParser(params); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"status": false,
+			"msg":    err.Error(),
+		})
+	}
+	filter, err := parseProductFilter(c)
+	if err != nil {
 		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
 			"status": false,
 			"msg":    err.Error(),
 		})
 	}
+	filter.PublishedOnly = c.Query("admin") != "true"
+
+	data, nextPagination, err := puc.ProductUC.Fetch(c.Context(), filter, *params)
+	if err != nil {
+		return c.Status(getStatusCode(err)).JSON(fiber.Map{
+			"status": false,
+			"msg":    err.Error(),
+		})
+	}
+
+	if err := puc.localize(c, data); err != nil {
+		return c.Status(getStatusCode(err)).JSON(fiber.Map{
+			"status": false,
+			"msg":    err.Error(),
+		})
+	}
 
-	data, nextPagination, err := puc.ProductUC.Fetch(c.Context(), *params)
+	res, err := project(data, filter.Fields)
 	if err != nil {
-		if err == domain.ErrNotFound {
-			return c.Status(http.StatusNotFound).JSON(fiber.Map{
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"status": false,
+			"msg":    err.Error(),
+		})
+	}
+
+	resp := fiber.Map{
+		"status": true,
+		"msg":    "success get product lists",
+		"data":   res,
+		"meta":   nextPagination,
+	}
+
+	// prices are keyed by product id, products without one are left out
+	if q, ok := priceQuery(c); ok {
+		ids := make([]string, 0, len(data))
+		for _, prd := range data {
+			ids = append(ids, prd.ID)
+		}
+		prices, err := puc.PriceUC.QuoteMany(c.Context(), ids, q)
+		if err != nil {
+			return c.Status(getStatusCode(err)).JSON(fiber.Map{
 				"status": false,
 				"msg":    err.Error(),
 			})
 		}
+		resp["prices"] = prices
+	}
+
+	return c.JSON(resp)
+}
+
+// GetProductDetail answers with the name and description in the locale of
+// ?locale= or else Accept-Language, falling back to the parent locales and
+// then to the default one. Content-Language tells the locale used.
+func (puc *ProductHandler) GetProductDetail(c *fiber.Ctx) error {
+	id := c.Params("id")
+	filter := domain.ProductFilter{}
+	if err := c.QueryParser(&filter); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"status": false,
+			"msg":    err.Error(),
+		})
+	}
+	filter.Fields = pkg.ParseList(c.Query("fields"))
+
+	data, err := puc.ProductUC.GetByID(c.Context(), id, filter)
+
+	if err != nil {
+		return c.Status(getStatusCode(err)).JSON(fiber.Map{
+			"status": false,
+			"msg":    err.Error(),
+		})
+	}
+
+	return puc.productDetail(c, data, filter.Fields, fiber.Map{})
+}
+
+// GetProductBySlug answers like GetProductDetail. A former slug of the
+// product gets the current one under "redirect", for the client to answer
+// with a 301 to it.
+func (puc *ProductHandler) GetProductBySlug(c *fiber.Ctx) error {
+	slug := c.Params("slug")
+	filter := domain.ProductFilter{}
+	if err := c.QueryParser(&filter); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"status": false,
+			"msg":    err.Error(),
+		})
+	}
+	fields := pkg.ParseList(c.Query("fields"))
+
+	// the slug is read whatever the fields asked for, they are applied when
+	// the product is rendered
+	data, err := puc.ProductUC.GetBySlug(c.Context(), slug, filter)
+	if err != nil {
+		return c.Status(getStatusCode(err)).JSON(fiber.Map{
+			"status": false,
+			"msg":    err.Error(),
+		})
+	}
+
+	resp := fiber.Map{}
+	if data.Slug != strings.ToLower(slug) {
+		resp["redirect"] = fiber.Map{
+			"status":   http.StatusMovedPermanently,
+			"slug":     data.Slug,
+			"location": "/api/v1/product/by-slug/" + data.Slug,
+		}
+	}
+	return puc.productDetail(c, data, fields, resp)
+}
+
+// productDetail renders a product with its available to promise and the
+// price asked for into resp.
+func (puc *ProductHandler) productDetail(c *fiber.Ctx, data domain.Products, fields []string, resp fiber.Map) error {
+	localized := []domain.Products{data}
+	if err := puc.localize(c, localized); err != nil {
+		return c.Status(getStatusCode(err)).JSON(fiber.Map{
+			"status": false,
+			"msg":    err.Error(),
+		})
+	}
+	data = localized[0]
+
+	res, err := project(data, fields)
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"status": false,
+			"msg":    err.Error(),
+		})
+	}
+
+	atp, err := puc.InventoryUC.AvailableToPromise(c.Context(), data.ID)
+	if err != nil {
+		return c.Status(getStatusCode(err)).JSON(fiber.Map{
+			"status": false,
+			"msg":    err.Error(),
+		})
+	}
+
+	resp["status"] = true
+	resp["msg"] = "success get product"
+	resp["data"] = res
+	resp["available_to_promise"] = atp
+
+	// a product without a price in the asked currency gets a null price
+	if q, ok := priceQuery(c); ok {
+		quote, err := puc.PriceUC.Quote(c.Context(), data.ID, q)
+		switch err {
+		case nil:
+			resp["price"] = quote
+		case domain.ErrNotFound:
+			resp["price"] = nil
+		default:
+			return c.Status(getStatusCode(err)).JSON(fiber.Map{
+				"status": false,
+				"msg":    err.Error(),
+			})
+		}
+	}
+
+	c.Set(fiber.HeaderETag, etag(data.Version))
+	return c.JSON(resp)
+}
+
+func (puc *ProductHandler) UpdateProduct(c *fiber.Ctx) error {
+	prd := &domain.Products{}
+	if err := c.BodyParser(prd); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"status": false,
+			"msg":    err.Error(),
+		})
+	}
+	version, err := ifMatchVersion(c)
+	if err != nil {
+		return c.Status(getStatusCode(err)).JSON(fiber.Map{
+			"status": false,
+			"msg":    err.Error(),
+		})
+	}
+	prd.ID = c.Params("id")
+	prd.Version = version
+
+	if err := puc.ProductUC.Update(c.Context(), prd); err != nil {
+		return c.Status(getStatusCode(err)).JSON(fiber.Map{
+			"status": false,
+			"msg":    err.Error(),
+		})
+	}
+
+	c.Set(fiber.HeaderETag, etag(prd.Version))
+	return c.JSON(fiber.Map{
+		"status": true,
+		"msg":    "success update product",
+		"data":   prd,
+	})
+}
+
+// PatchProduct applies a JSON merge patch (RFC 7396) to the stored product,
+// so only the fields present in the body are changed.
+func (puc *ProductHandler) PatchProduct(c *fiber.Ctx) error {
+	id := c.Params("id")
+
+	version, err := ifMatchVersion(c)
+	if err != nil {
+		return c.Status(getStatusCode(err)).JSON(fiber.Map{
+			"status": false,
+			"msg":    err.Error(),
+		})
+	}
+
+	current, err := puc.ProductUC.GetByID(c.Context(), id, domain.ProductFilter{})
+	if err != nil {
+		return c.Status(getStatusCode(err)).JSON(fiber.Map{
+			"status": false,
+			"msg":    err.Error(),
+		})
+	}
+
+	doc, err := json.Marshal(current)
+	if err != nil {
 		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
 			"status": false,
 			"msg":    err.Error(),
 		})
 	}
 
+	patched, err := pkg.MergePatch(doc, c.Body())
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"status": false,
+			"msg":    err.Error(),
+		})
+	}
+
+	prd := &domain.Products{}
+	if err := json.Unmarshal(patched, prd); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"status": false,
+			"msg":    err.Error(),
+		})
+	}
+	prd.ID = current.ID
+	prd.CreatedAt = current.CreatedAt
+	prd.Version = version
+	// the patched document starts from the whole product, tags or
+	// attributes missing from it were removed by a null in the patch while
+	// Update keeps the ones it gets nil
+	if prd.Tags == nil {
+		prd.Tags = []string{}
+	}
+	if prd.Attributes == nil {
+		prd.Attributes = map[string]interface{}{}
+	}
+
+	if err := puc.ProductUC.Update(c.Context(), prd); err != nil {
+		return c.Status(getStatusCode(err)).JSON(fiber.Map{
+			"status": false,
+			"msg":    err.Error(),
+		})
+	}
+
+	c.Set(fiber.HeaderETag, etag(prd.Version))
 	return c.JSON(fiber.Map{
 		"status": true,
-		"msg":    "success get product lists",
+		"msg":    "success patch product",
+		"data":   prd,
+	})
+}
+
+func (puc *ProductHandler) DeleteProduct(c *fiber.Ctx) error {
+	id := c.Params("id")
+
+	version, err := ifMatchVersion(c)
+	if err != nil {
+		return c.Status(getStatusCode(err)).JSON(fiber.Map{
+			"status": false,
+			"msg":    err.Error(),
+		})
+	}
+
+	if err := puc.ProductUC.Delete(c.Context(), id, version); err != nil {
+		return c.Status(getStatusCode(err)).JSON(fiber.Map{
+			"status": false,
+			"msg":    err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"status": true,
+		"msg":    "success delete product",
+	})
+}
+
+// SearchProducts runs a full-text search over the product name and
+// description. Like the list it only finds the published products,
+// ?admin=true finds them in every status.
+func (puc *ProductHandler) SearchProducts(c *fiber.Ctx) error {
+	params := &pkg.Pagination{}
+	if err := c.QueryParser(params); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"status": false,
+			"msg":    err.Error(),
+		})
+	}
+	filter := domain.ProductFilter{}
+	if err := c.QueryParser(&filter); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"status": false,
+			"msg":    err.Error(),
+		})
+	}
+	filter.PublishedOnly = c.Query("admin") != "true"
+
+	data, nextPagination, err := puc.ProductUC.Search(c.Context(), c.Query("q"), filter, *params)
+	if err != nil {
+		return c.Status(getStatusCode(err)).JSON(fiber.Map{
+			"status": false,
+			"msg":    err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"status": true,
+		"msg":    "success search products",
 		"data":   data,
 		"meta":   nextPagination,
 	})
 }
 
-func (puc *ProductHandler) GetProductDetail(c *fiber.Ctx) error {
+// GetTrashedProducts lists the soft deleted products that can still be restored.
+func (puc *ProductHandler) GetTrashedProducts(c *fiber.Ctx) error {
+	params := &pkg.Pagination{}
+	if err := c.QueryParser(params); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"status": false,
+			"msg":    err.Error(),
+		})
+	}
+
+	filter, err := parseProductFilter(c)
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"status": false,
+			"msg":    err.Error(),
+		})
+	}
+	filter.IncludeDeleted, filter.OnlyDeleted = false, true
+
+	data, nextPagination, err := puc.ProductUC.Fetch(c.Context(), filter, *params)
+	if err != nil {
+		return c.Status(getStatusCode(err)).JSON(fiber.Map{
+			"status": false,
+			"msg":    err.Error(),
+		})
+	}
+
+	res, err := project(data, filter.Fields)
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"status": false,
+			"msg":    err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"status": true,
+		"msg":    "success get trashed products",
+		"data":   res,
+		"meta":   nextPagination,
+	})
+}
+
+func (puc *ProductHandler) RestoreProduct(c *fiber.Ctx) error {
 	id := c.Params("id")
 
-	data, err := puc.ProductUC.GetByID(c.Context(), id)
+	if err := puc.ProductUC.Restore(c.Context(), id); err != nil {
+		return c.Status(getStatusCode(err)).JSON(fiber.Map{
+			"status": false,
+			"msg":    err.Error(),
+		})
+	}
+
+	data, err := puc.ProductUC.GetByID(c.Context(), id, domain.ProductFilter{})
+	if err != nil {
+		return c.Status(getStatusCode(err)).JSON(fiber.Map{
+			"status": false,
+			"msg":    err.Error(),
+		})
+	}
+
+	c.Set(fiber.HeaderETag, etag(data.Version))
+	return c.JSON(fiber.Map{
+		"status": true,
+		"msg":    "success restore product",
+		"data":   data,
+	})
+}
+
+// GetTags lists every tag in use with the number of products carrying it.
+func (puc *ProductHandler) GetTags(c *fiber.Ctx) error {
+	data, err := puc.ProductUC.FetchTags(c.Context())
+	if err != nil {
+		return c.Status(getStatusCode(err)).JSON(fiber.Map{
+			"status": false,
+			"msg":    err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"status": true,
+		"msg":    "success get tags",
+		"data":   data,
+	})
+}
+
+// RenameTags renames or merges tags on every product, e.g.
+// {"from": ["tee", "t-shirt"], "to": "tshirt"}.
+func (puc *ProductHandler) RenameTags(c *fiber.Ctx) error {
+	r := domain.TagRename{}
+	if err := c.BodyParser(&r); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"status": false,
+			"msg":    err.Error(),
+		})
+	}
 
+	renamed, err := puc.ProductUC.RenameTags(c.Context(), r)
 	if err != nil {
-		if err == domain.ErrNotFound {
-			return c.Status(http.StatusNotFound).JSON(fiber.Map{
+		return c.Status(getStatusCode(err)).JSON(fiber.Map{
+			"status": false,
+			"msg":    err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"status": true,
+		"msg":    "success rename tags",
+		"data":   fiber.Map{"renamed": renamed},
+	})
+}
+
+// ImportProducts imports the CSV sent as body, e.g.
+// ?dry_run=true&mapping={"name":"Title"}. With ?report=csv the rejected rows
+// are sent back as a CSV file instead of the JSON summary, with ?async=true
+// the import runs in a job instead.
+func (puc *ProductHandler) ImportProducts(c *fiber.Ctx) error {
+	mapping := domain.ProductImportMapping{}
+	if raw := c.Query("mapping"); raw != "" {
+		if err := json.Unmarshal([]byte(raw), &mapping); err != nil {
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{
 				"status": false,
 				"msg":    err.Error(),
 			})
 		}
-		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+	}
+
+	// the body is streamed when the app is set to, read whole otherwise
+	var body io.Reader = c.Context().RequestBodyStream()
+	if body == nil {
+		body = bytes.NewReader(c.Body())
+	}
+
+	dryRun := c.Query("dry_run") == "true"
+
+	// the CSV is saved to a file the job reads once a worker runs it
+	if c.Query("async") == "true" {
+		job, err := puc.JobUC.Enqueue(c.Context(), domain.JobProductImport, domain.ProductImportRequest{Mapping: mapping, DryRun: dryRun}, body)
+		if err != nil {
+			return c.Status(getStatusCode(err)).JSON(fiber.Map{
+				"status": false,
+				"msg":    err.Error(),
+			})
+		}
+		return jobAccepted(c, job, "success enqueue product import")
+	}
+
+	data, err := puc.ProductUC.Import(c.Context(), body, mapping, dryRun)
+	if err != nil {
+		return c.Status(getStatusCode(err)).JSON(fiber.Map{
 			"status": false,
 			"msg":    err.Error(),
 		})
 	}
 
+	if c.Query("report") == "csv" {
+		return importReport(c, data)
+	}
+
 	return c.JSON(fiber.Map{
 		"status": true,
-		"msg":    "success get product",
+		"msg":    "success import products",
 		"data":   data,
 	})
 }
+
+// importReport writes the rejected rows as a CSV attachment, the line and
+// reasons first and then the row as it was sent. X-Import-Rejected counts
+// every rejected row, the report only has the ones the import kept.
+func importReport(c *fiber.Ctx, data domain.ProductImportResult) error {
+	c.Attachment("import-report.csv")
+	c.Set("X-Import-Total", strconv.Itoa(data.Total))
+	c.Set("X-Import-Imported", strconv.Itoa(data.Imported))
+	c.Set("X-Import-Rejected", strconv.Itoa(data.RejectedTotal))
+
+	w := csv.NewWriter(c)
+	if err := w.Write(append([]string{"line", "reasons"}, data.Header...)); err != nil {
+		return err
+	}
+	for _, r := range data.Rejected {
+		row := append([]string{strconv.Itoa(r.Line), strings.Join(r.Reasons, "; ")}, r.Record...)
+		if err := w.Write(row); err != nil {
+			return err
+		}
+	}
+	w.Flush()
+
+	return w.Error()
+}
+
+// ReindexProducts rebuilds the search index in a job.
+func (puc *ProductHandler) ReindexProducts(c *fiber.Ctx) error {
+	job, err := puc.JobUC.Enqueue(c.Context(), domain.JobProductReindex, nil, nil)
+	if err != nil {
+		return c.Status(getStatusCode(err)).JSON(fiber.Map{
+			"status": false,
+			"msg":    err.Error(),
+		})
+	}
+
+	return jobAccepted(c, job, "success enqueue product reindex")
+}
+
+// parseProductFilter reads the trash flags, the field filters declared in
+// domain.ProductFilterDefs and the `sort` parameter of a product list.
+func parseProductFilter(c *fiber.Ctx) (filter domain.ProductFilter, err error) {
+	if err = c.QueryParser(&filter); err != nil {
+		return
+	}
+	if filter.Filters, err = pkg.ParseFilters(c.Query, domain.ProductFilterDefs); err != nil {
+		return
+	}
+	if filter.Attributes, err = parseAttributeFilters(c); err != nil {
+		return
+	}
+	filter.Tags = domain.NormalizeTags(pkg.ParseList(c.Query("tags")))
+	switch mode := domain.TagsMode(c.Query("tags_mode", string(domain.TagsAny))); mode {
+	case domain.TagsAny, domain.TagsAll:
+		filter.TagsMode = mode
+	default:
+		return filter, fmt.Errorf("%w: tags_mode=%q", pkg.ErrInvalidQuery, mode)
+	}
+	filter.Sort, err = pkg.ParseSort(c.Query("sort"), domain.ProductSortFields)
+	filter.Fields = pkg.ParseList(c.Query("fields"))
+	return
+}
+
+// attributeFilterPrefix starts the parameters filtering on attribute values:
+// attr.<name>=v matches the value, attr.<name>.min and attr.<name>.max bound
+// a number or the value of a unit attribute.
+const attributeFilterPrefix = "attr."
+
+func parseAttributeFilters(c *fiber.Ctx) ([]pkg.Filter, error) {
+	params := map[string]string{}
+	c.Context().QueryArgs().VisitAll(func(key, value []byte) {
+		if k := string(key); strings.HasPrefix(k, attributeFilterPrefix) {
+			params[k] = string(value)
+		}
+	})
+	keys := make([]string, 0, len(params))
+	for k := range params {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	res := make([]pkg.Filter, 0, len(keys))
+	for _, k := range keys {
+		name, op, t := strings.TrimPrefix(k, attributeFilterPrefix), pkg.FilterEq, pkg.FilterString
+		switch {
+		case strings.HasSuffix(name, ".min"):
+			name, op, t = strings.TrimSuffix(name, ".min"), pkg.FilterGte, pkg.FilterNumber
+		case strings.HasSuffix(name, ".max"):
+			name, op, t = strings.TrimSuffix(name, ".max"), pkg.FilterLte, pkg.FilterNumber
+		}
+		if name == "" || params[k] == "" {
+			return nil, fmt.Errorf("%w: %s=%q", pkg.ErrInvalidQuery, k, params[k])
+		}
+
+		value, err := pkg.ParseFilterValue(t, params[k])
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s=%q", pkg.ErrInvalidQuery, k, params[k])
+		}
+		res = append(res, pkg.Filter{Field: name, Op: op, Value: value})
+	}
+
+	return res, nil
+}
+
+// localize translates the products in place for the locales the request
+// prefers and sets Content-Language, a list of mixed locales gets all of
+// them.
+func (puc *ProductHandler) localize(c *fiber.Ctx, products []domain.Products) error {
+	prefs := pkg.ParseAcceptLanguage(c.Get(fiber.HeaderAcceptLanguage))
+	if locale := c.Query("locale"); locale != "" {
+		prefs = []string{locale}
+	}
+
+	ptrs := make([]*domain.Products, 0, len(products))
+	for i := range products {
+		ptrs = append(ptrs, &products[i])
+	}
+	if err := puc.TranslationUC.Localize(c.Context(), prefs, ptrs...); err != nil {
+		return err
+	}
+
+	locales := make([]string, 0, 1)
+	for _, prd := range products {
+		if prd.Locale != "" && !containsLocale(locales, prd.Locale) {
+			locales = append(locales, prd.Locale)
+		}
+	}
+	if len(locales) > 0 {
+		c.Set(fiber.HeaderContentLanguage, strings.Join(locales, ", "))
+	}
+	return nil
+}
+
+func containsLocale(list []string, locale string) bool {
+	for _, l := range list {
+		if l == locale {
+			return true
+		}
+	}
+	return false
+}
+
+// priceQuery reads the price asked for with ?currency= and ?price_list=.
+// ?display_currency= also converts a price set in another currency with the
+// exchange rates.
+func priceQuery(c *fiber.Ctx) (domain.PriceQuery, bool) {
+	q := domain.PriceQuery{
+		PriceList: c.Query("price_list"),
+		Currency:  c.Query("currency"),
+	}
+	if display := c.Query("display_currency"); display != "" {
+		q.Currency, q.Convert = display, true
+	}
+	return q, q.Currency != ""
+}
+
+// project trims a product or a list of products down to the requested JSON
+// fields, the other fields weren't read from the database.
+func project(data interface{}, fields []string) (interface{}, error) {
+	if len(fields) == 0 {
+		return data, nil
+	}
+
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+	var doc interface{}
+	if err := json.Unmarshal(raw, &doc); err != nil {
+		return nil, err
+	}
+
+	keep := func(v interface{}) interface{} {
+		item, ok := v.(map[string]interface{})
+		if !ok {
+			return v
+		}
+		res := make(map[string]interface{}, len(fields))
+		for _, field := range fields {
+			if value, ok := item[field]; ok {
+				res[field] = value
+			}
+		}
+		return res
+	}
+
+	if list, ok := doc.([]interface{}); ok {
+		for i := range list {
+			list[i] = keep(list[i])
+		}
+		return list, nil
+	}
+	return keep(doc), nil
+}
+
+// withActor remembers who issued the request so it ends up on the product
+// revisions.
+func withActor(c *fiber.Ctx) error {
+	c.Context().SetUserValue(domain.ActorContextKey, c.Get("X-Actor"))
+	return c.Next()
+}
+
+// etag formats a product version as a strong entity tag.
+func etag(version int64) string {
+	return strconv.Quote(strconv.FormatInt(version, 10))
+}
+
+// ifMatchVersion reads the product version the client based its write on.
+// A missing header is rejected so writes can't silently overwrite each other.
+func ifMatchVersion(c *fiber.Ctx) (int64, error) {
+	header := strings.TrimSpace(c.Get(fiber.HeaderIfMatch))
+	if header == "" {
+		return 0, errIfMatchRequired
+	}
+
+	tag, err := strconv.Unquote(header)
+	if err != nil {
+		return 0, domain.ErrPreconditionFailed
+	}
+	version, err := strconv.ParseInt(tag, 10, 64)
+	if err != nil {
+		return 0, domain.ErrPreconditionFailed
+	}
+
+	return version, nil
+}
+
+func getStatusCode(err error) int {
+	if err == errIfMatchRequired {
+		return http.StatusPreconditionRequired
+	}
+	return domain.StatusCode(err)
+}
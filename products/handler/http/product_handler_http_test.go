@@ -0,0 +1,136 @@
+package handler_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fahmilukis/go-product-svc/domain"
+	pkg "github.com/fahmilukis/go-product-svc/pkg/utils"
+	handler "github.com/fahmilukis/go-product-svc/products/handler/http"
+	"github.com/fahmilukis/go-product-svc/products/usecases"
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+// searchUsecase records the filter of the searches.
+type searchUsecase struct {
+	domain.ProductUsecase
+	filters []domain.ProductFilter
+}
+
+func (s *searchUsecase) Search(ctx context.Context, q string, f domain.ProductFilter, pagination pkg.Pagination) ([]domain.ProductSearchHit, pkg.Pagination, error) {
+	s.filters = append(s.filters, f)
+	return []domain.ProductSearchHit{}, pkg.Pagination{}, nil
+}
+
+func TestSearchProductsPublishedOnly(t *testing.T) {
+	puc := &searchUsecase{}
+	app := fiber.New()
+	handler.ProductRoute(app, puc, nil, nil, nil, nil)
+
+	res, err := app.Test(httptest.NewRequest(http.MethodGet, "/api/v1/product/search?q=shirt", nil))
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+
+	// the admins search the drafts and the archived products too
+	res, err = app.Test(httptest.NewRequest(http.MethodGet, "/api/v1/product/search?q=shirt&admin=true", nil))
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+
+	assert.Len(t, puc.filters, 2)
+	assert.True(t, puc.filters[0].PublishedOnly)
+	assert.False(t, puc.filters[1].PublishedOnly)
+}
+
+// patchUsecase serves the product patched and records the update.
+type patchUsecase struct {
+	domain.ProductUsecase
+	current domain.Products
+	updated *domain.Products
+}
+
+func (p *patchUsecase) GetByID(ctx context.Context, id string, f domain.ProductFilter) (domain.Products, error) {
+	return p.current, nil
+}
+
+func (p *patchUsecase) Update(ctx context.Context, a *domain.Products) error {
+	p.updated = a
+	return nil
+}
+
+func TestPatchProductClearsTagsAndAttributes(t *testing.T) {
+	puc := &patchUsecase{current: domain.Products{
+		ID:         "1",
+		Name:       "product 1",
+		Version:    2,
+		Tags:       []string{"summer"},
+		Attributes: map[string]interface{}{"color": "blue"},
+	}}
+	app := fiber.New()
+	handler.ProductRoute(app, puc, nil, nil, nil, nil)
+
+	req := httptest.NewRequest(http.MethodPatch, "/api/v1/product/1", strings.NewReader(`{"tags":null,"attributes":null}`))
+	req.Header.Set(fiber.HeaderContentType, "application/merge-patch+json")
+	req.Header.Set(fiber.HeaderIfMatch, `"2"`)
+	res, err := app.Test(req)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+
+	assert.Equal(t, []string{}, puc.updated.Tags)
+	assert.Equal(t, map[string]interface{}{}, puc.updated.Attributes)
+}
+
+// productRepository serves a stored product, a write reaching it fails the
+// test.
+type productRepository struct {
+	domain.ProductRepository
+	current domain.Products
+}
+
+func (p *productRepository) GetByID(ctx context.Context, id string, f domain.ProductFilter) (domain.Products, error) {
+	return p.current, nil
+}
+
+func TestPatchProductNullName(t *testing.T) {
+	repo := &productRepository{current: domain.Products{
+		ID:          "4b0f1c8e-4d7c-4f5e-9a3b-2c1d0e9f8a7b",
+		Name:        "product 1",
+		Description: "description 1",
+		Version:     2,
+	}}
+	puc := usecases.NewProductUsecase(repo, nil, nil, nil, time.Second)
+	app := fiber.New()
+	handler.ProductRoute(app, puc, nil, nil, nil, nil)
+
+	for _, body := range []string{`{"name":null}`, `{"name":""}`} {
+		req := httptest.NewRequest(http.MethodPatch, "/api/v1/product/"+repo.current.ID, strings.NewReader(body))
+		req.Header.Set(fiber.HeaderContentType, "application/merge-patch+json")
+		req.Header.Set(fiber.HeaderIfMatch, `"2"`)
+		res, err := app.Test(req)
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusBadRequest, res.StatusCode, body)
+	}
+}
+
+func TestImportProductsReportCapsTheRejections(t *testing.T) {
+	puc := usecases.NewProductUsecase(&productRepository{}, nil, nil, nil, time.Second)
+	app := fiber.New()
+	handler.ProductRoute(app, puc, nil, nil, nil, nil)
+
+	// every row misses its name
+	csv := "name,desc\n" + strings.Repeat(",no name\n", 1500)
+	res, err := app.Test(httptest.NewRequest(http.MethodPost, "/api/v1/product/import?dry_run=true&report=csv", strings.NewReader(csv)))
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Equal(t, "1500", res.Header.Get("X-Import-Rejected"))
+
+	body, err := io.ReadAll(res.Body)
+	assert.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(string(body)), "\n")
+	assert.Len(t, lines, 1001)
+}
@@ -0,0 +1,139 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/fahmilukis/go-product-svc/domain"
+	pkg "github.com/fahmilukis/go-product-svc/pkg/utils"
+	"github.com/gofiber/fiber/v2"
+)
+
+type ProductRevisionHandler struct {
+	RevisionUC domain.ProductRevisionUsecase
+}
+
+func ProductRevisionRoute(a *fiber.App, ruc domain.ProductRevisionUsecase) {
+	handler := &ProductRevisionHandler{
+		RevisionUC: ruc,
+	}
+
+	route := a.Group("/api/v1")
+
+	route.Get("/product/:id/revisions", handler.GetListRevisions)
+	route.Get("/product/:id/revisions/:rev", handler.GetRevisionDetail)
+	route.Get("/product/:id/revisions/:rev/diff/:other", handler.GetRevisionDiff)
+	route.Post("/product/:id/revisions/:rev/revert", handler.RevertRevision)
+}
+
+func (ruc *ProductRevisionHandler) GetListRevisions(c *fiber.Ctx) error {
+	params := &pkg.Pagination{}
+	if err := c.QueryParser(params); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"status": false,
+			"msg":    err.Error(),
+		})
+	}
+
+	data, nextPagination, err := ruc.RevisionUC.Fetch(c.Context(), c.Params("id"), *params)
+	if err != nil {
+		return c.Status(getStatusCode(err)).JSON(fiber.Map{
+			"status": false,
+			"msg":    err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"status": true,
+		"msg":    "success get product revisions",
+		"data":   data,
+		"meta":   nextPagination,
+	})
+}
+
+func (ruc *ProductRevisionHandler) GetRevisionDetail(c *fiber.Ctx) error {
+	rev, err := c.ParamsInt("rev")
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"status": false,
+			"msg":    domain.ErrBadParamInput.Error(),
+		})
+	}
+
+	data, err := ruc.RevisionUC.GetByRevision(c.Context(), c.Params("id"), int64(rev))
+	if err != nil {
+		return c.Status(getStatusCode(err)).JSON(fiber.Map{
+			"status": false,
+			"msg":    err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"status": true,
+		"msg":    "success get product revision",
+		"data":   data,
+	})
+}
+
+// GetRevisionDiff lists the fields that differ between two revisions.
+func (ruc *ProductRevisionHandler) GetRevisionDiff(c *fiber.Ctx) error {
+	from, err := c.ParamsInt("rev")
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"status": false,
+			"msg":    domain.ErrBadParamInput.Error(),
+		})
+	}
+	to, err := c.ParamsInt("other")
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"status": false,
+			"msg":    domain.ErrBadParamInput.Error(),
+		})
+	}
+
+	data, err := ruc.RevisionUC.Diff(c.Context(), c.Params("id"), int64(from), int64(to))
+	if err != nil {
+		return c.Status(getStatusCode(err)).JSON(fiber.Map{
+			"status": false,
+			"msg":    err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"status": true,
+		"msg":    "success get product revision diff",
+		"data":   data,
+	})
+}
+
+func (ruc *ProductRevisionHandler) RevertRevision(c *fiber.Ctx) error {
+	rev, err := c.ParamsInt("rev")
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"status": false,
+			"msg":    domain.ErrBadParamInput.Error(),
+		})
+	}
+	version, err := ifMatchVersion(c)
+	if err != nil {
+		return c.Status(getStatusCode(err)).JSON(fiber.Map{
+			"status": false,
+			"msg":    err.Error(),
+		})
+	}
+
+	data, err := ruc.RevisionUC.Revert(c.Context(), c.Params("id"), int64(rev), version)
+	if err != nil {
+		return c.Status(getStatusCode(err)).JSON(fiber.Map{
+			"status": false,
+			"msg":    err.Error(),
+		})
+	}
+
+	c.Set(fiber.HeaderETag, etag(data.Version))
+	return c.JSON(fiber.Map{
+		"status": true,
+		"msg":    "success revert product",
+		"data":   data,
+	})
+}
@@ -0,0 +1,98 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/fahmilukis/go-product-svc/domain"
+	"github.com/gofiber/fiber/v2"
+)
+
+type ProductTranslationHandler struct {
+	TranslationUC domain.ProductTranslationUsecase
+}
+
+func ProductTranslationRoute(a *fiber.App, tuc domain.ProductTranslationUsecase) {
+	handler := &ProductTranslationHandler{
+		TranslationUC: tuc,
+	}
+
+	route := a.Group("/api/v1")
+
+	route.Get("/product/:id/translations", handler.GetListTranslations)
+	route.Get("/product/:id/translations/missing", handler.GetMissingLocales)
+	route.Put("/product/:id/translations/:locale", handler.UpsertTranslation)
+	route.Delete("/product/:id/translations/:locale", handler.DeleteTranslation)
+}
+
+func (tuc *ProductTranslationHandler) GetListTranslations(c *fiber.Ctx) error {
+	data, err := tuc.TranslationUC.Fetch(c.Context(), c.Params("id"))
+	if err != nil {
+		return c.Status(getStatusCode(err)).JSON(fiber.Map{
+			"status": false,
+			"msg":    err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"status": true,
+		"msg":    "success get translations",
+		"data":   data,
+	})
+}
+
+// GetMissingLocales lists the supported locales the product isn't
+// translated to yet.
+func (tuc *ProductTranslationHandler) GetMissingLocales(c *fiber.Ctx) error {
+	data, err := tuc.TranslationUC.Missing(c.Context(), c.Params("id"))
+	if err != nil {
+		return c.Status(getStatusCode(err)).JSON(fiber.Map{
+			"status": false,
+			"msg":    err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"status": true,
+		"msg":    "success get missing locales",
+		"data":   data,
+	})
+}
+
+func (tuc *ProductTranslationHandler) UpsertTranslation(c *fiber.Ctx) error {
+	t := &domain.ProductTranslation{}
+	if err := c.BodyParser(t); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"status": false,
+			"msg":    err.Error(),
+		})
+	}
+	t.ProductID = c.Params("id")
+	t.Locale = c.Params("locale")
+
+	if err := tuc.TranslationUC.Upsert(c.Context(), t); err != nil {
+		return c.Status(getStatusCode(err)).JSON(fiber.Map{
+			"status": false,
+			"msg":    err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"status": true,
+		"msg":    "success save translation",
+		"data":   t,
+	})
+}
+
+func (tuc *ProductTranslationHandler) DeleteTranslation(c *fiber.Ctx) error {
+	if err := tuc.TranslationUC.Delete(c.Context(), c.Params("id"), c.Params("locale")); err != nil {
+		return c.Status(getStatusCode(err)).JSON(fiber.Map{
+			"status": false,
+			"msg":    err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"status": true,
+		"msg":    "success delete translation",
+	})
+}
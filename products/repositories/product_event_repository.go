@@ -0,0 +1,107 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/fahmilukis/go-product-svc/domain"
+	"github.com/lib/pq"
+	"github.com/sirupsen/logrus"
+)
+
+type productEventDBRepositories struct {
+	Conn *sql.DB
+}
+
+func NewProductEventDBRepository(conn *sql.DB) *productEventDBRepositories {
+	return &productEventDBRepositories{Conn: conn}
+}
+
+// productEventRelayLock is the advisory lock the relays take, one relay
+// publishes at a time so the positions follow the publishing order.
+const productEventRelayLock = 7310
+
+// Relay reads the pending events in id order. An event whose change commits
+// after an event with a higher id was published is read by a later run, it
+// gets a higher position rather than being skipped.
+func (p *productEventDBRepositories) Relay(ctx context.Context, limit int, publish func(ctx context.Context, events []domain.ProductEvent) error) (delivered int, err error) {
+	query := `SELECT id,product_id,type,payload,created_at FROM product_events WHERE delivered_at IS NULL ORDER BY id LIMIT $1`
+
+	tx, err := p.Conn.BeginTx(ctx, nil)
+	if err != nil {
+		return
+	}
+	defer func() {
+		if err != nil {
+			if errRollback := tx.Rollback(); errRollback != nil {
+				logrus.Error(errRollback)
+			}
+		}
+	}()
+
+	if _, err = tx.ExecContext(ctx, `SELECT pg_advisory_xact_lock($1)`, productEventRelayLock); err != nil {
+		return
+	}
+	events, err := pendingEvents(ctx, tx, query, limit)
+	if err != nil {
+		return
+	}
+	if len(events) == 0 {
+		return 0, tx.Commit()
+	}
+
+	var last int64
+	if err = tx.QueryRowContext(ctx, `SELECT COALESCE(max(position), 0) FROM product_events`).Scan(&last); err != nil {
+		return
+	}
+	ids := make([]int64, len(events))
+	positions := make([]int64, len(events))
+	for i := range events {
+		events[i].Position = last + int64(i) + 1
+		ids[i], positions[i] = events[i].ID, events[i].Position
+	}
+
+	if err = publish(ctx, events); err != nil {
+		return
+	}
+
+	query = `UPDATE product_events SET delivered_at = now(), position = d.position
+	FROM unnest($1::bigint[], $2::bigint[]) AS d(id, position) WHERE product_events.id = d.id`
+	if _, err = tx.ExecContext(ctx, query, pq.Array(ids), pq.Array(positions)); err != nil {
+		return
+	}
+	if err = tx.Commit(); err != nil {
+		return
+	}
+
+	return len(events), nil
+}
+
+func pendingEvents(ctx context.Context, tx *sql.Tx, query string, limit int) (res []domain.ProductEvent, err error) {
+	rows, err := tx.QueryContext(ctx, query, limit)
+	if err != nil {
+		logrus.Error(err)
+		return nil, err
+	}
+
+	defer func() {
+		errRow := rows.Close()
+		if errRow != nil {
+			logrus.Error(errRow)
+		}
+	}()
+
+	res = make([]domain.ProductEvent, 0)
+	for rows.Next() {
+		event := domain.ProductEvent{}
+		var payload []byte
+		if err = rows.Scan(&event.ID, &event.ProductID, &event.Type, &payload, &event.CreatedAt); err != nil {
+			logrus.Error(err)
+			return nil, err
+		}
+		event.Payload = payload
+		res = append(res, event)
+	}
+
+	return res, rows.Err()
+}
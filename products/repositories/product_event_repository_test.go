@@ -0,0 +1,77 @@
+package repositories_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/fahmilukis/go-product-svc/domain"
+	"github.com/fahmilukis/go-product-svc/products/repositories"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/DATA-DOG/go-sqlmock.v1"
+)
+
+func TestRelayRepositoryProductEvent(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	now := time.Now()
+
+	// 7 committed after 8 and 9 were published, it is published now with a
+	// higher position than theirs
+	rows := sqlmock.NewRows([]string{"id", "product_id", "type", "payload", "created_at"}).
+		AddRow(7, "1", "product.created", []byte(`{"id":"1"}`), now).
+		AddRow(10, "1", "product.updated", []byte(`{"id":"1"}`), now)
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`SELECT pg_advisory_xact_lock\(\$1\)`).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(`SELECT id,product_id,type,payload,created_at FROM product_events WHERE delivered_at IS NULL ORDER BY id LIMIT \$1`).
+		WithArgs(10).WillReturnRows(rows)
+	mock.ExpectQuery(`SELECT COALESCE\(max\(position\), 0\) FROM product_events`).
+		WillReturnRows(sqlmock.NewRows([]string{"max"}).AddRow(3))
+	mock.ExpectExec(`UPDATE product_events SET delivered_at = now\(\), position = d.position\s+FROM unnest\(\$1::bigint\[\], \$2::bigint\[\]\) AS d\(id, position\) WHERE product_events.id = d.id`).
+		WithArgs("{7,10}", "{4,5}").WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectCommit()
+
+	var published []domain.ProductEvent
+	a := repositories.NewProductEventDBRepository(db)
+	delivered, err := a.Relay(context.TODO(), 10, func(ctx context.Context, events []domain.ProductEvent) error {
+		published = append(published, events...)
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, delivered)
+	assert.Len(t, published, 2)
+	assert.Equal(t, int64(7), published[0].ID)
+	assert.Equal(t, int64(4), published[0].Position)
+	assert.Equal(t, int64(5), published[1].Position)
+	assert.Equal(t, domain.ProductUpdated, published[1].Type)
+	assert.JSONEq(t, `{"id":"1"}`, string(published[1].Payload))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRelayRepositoryProductEventPublishFailed(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	now := time.Now()
+
+	// the events stay pending for the next run
+	mock.ExpectBegin()
+	mock.ExpectExec(`SELECT pg_advisory_xact_lock`).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(`FROM product_events WHERE delivered_at IS NULL`).WithArgs(10).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "product_id", "type", "payload", "created_at"}).AddRow(7, "1", "product.deleted", []byte(`{}`), now))
+	mock.ExpectQuery(`SELECT COALESCE\(max\(position\), 0\)`).WillReturnRows(sqlmock.NewRows([]string{"max"}).AddRow(0))
+	mock.ExpectRollback()
+
+	a := repositories.NewProductEventDBRepository(db)
+	delivered, err := a.Relay(context.TODO(), 10, func(ctx context.Context, events []domain.ProductEvent) error {
+		return errors.New("broker down")
+	})
+	assert.EqualError(t, err, "broker down")
+	assert.Equal(t, 0, delivered)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -3,14 +3,18 @@ package repositories
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"fmt"
-	"math"
+	"html"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
 
 	"github.com/fahmilukis/go-product-svc/domain"
 	pkg "github.com/fahmilukis/go-product-svc/pkg/utils"
+	"github.com/lib/pq"
 	"github.com/sirupsen/logrus"
-
-	_ "github.com/lib/pq"
 )
 
 type productDBRepositories struct {
@@ -22,7 +26,7 @@ func NewProductDBRepository(conn *sql.DB) *productDBRepositories {
 }
 
 // fetch to DB
-func (p *productDBRepositories) fetch(ctx context.Context, query string, args ...interface{}) (res []domain.Products, err error) {
+func (p *productDBRepositories) fetch(ctx context.Context, pr productProjection, query string, args ...interface{}) (res []domain.Products, err error) {
 	rows, err := p.Conn.QueryContext(ctx, query, args...)
 	if err != nil {
 		logrus.Error(err)
@@ -39,14 +43,7 @@ func (p *productDBRepositories) fetch(ctx context.Context, query string, args ..
 	res = make([]domain.Products, 0)
 	for rows.Next() {
 		prd := domain.Products{}
-		err = rows.Scan(
-			&prd.ID,
-			&prd.Name,
-			&prd.Description,
-			&prd.CreatedAt,
-			&prd.UpdatedAt,
-			&prd.ImageSrc,
-		)
+		err = rows.Scan(pr.targets(&prd)...)
 		if err != nil {
 			logrus.Error(err)
 			return nil, err
@@ -57,31 +54,467 @@ func (p *productDBRepositories) fetch(ctx context.Context, query string, args ..
 	return res, nil
 }
 
-func (p *productDBRepositories) Fetch(ctx context.Context, pagination pkg.Pagination) (res []domain.Products, nextPagination pkg.Pagination, err error) {
-	query := `SELECT id,product_name,product_desc,created_at,updated_at,product_img_src
-	FROM products ORDER BY created_at ASC LIMIT $1 OFFSET $2`
+// productField is a selectable field of a product, name is its JSON name.
+type productField struct {
+	name   string
+	column string
+	target func(prd *domain.Products) interface{}
+}
+
+var productFields = []productField{
+	{"id", "id", func(prd *domain.Products) interface{} { return &prd.ID }},
+	{"name", "product_name", func(prd *domain.Products) interface{} { return &prd.Name }},
+	{"desc", "product_desc", func(prd *domain.Products) interface{} { return &prd.Description }},
+	{"created_at", "created_at", func(prd *domain.Products) interface{} { return &prd.CreatedAt }},
+	{"updated_at", "updated_at", func(prd *domain.Products) interface{} { return &prd.UpdatedAt }},
+	{"product_img_src", "product_img_src", func(prd *domain.Products) interface{} { return &prd.ImageSrc }},
+	{"version", "version", func(prd *domain.Products) interface{} { return &prd.Version }},
+	{"deleted_at", "deleted_at", func(prd *domain.Products) interface{} { return &prd.DeletedAt }},
+	{"status", "status", func(prd *domain.Products) interface{} { return &prd.Status }},
+	{"publish_at", "publish_at", func(prd *domain.Products) interface{} { return &prd.PublishAt }},
+	{"unpublish_at", "unpublish_at", func(prd *domain.Products) interface{} { return &prd.UnpublishAt }},
+	{"product_type", "product_type", func(prd *domain.Products) interface{} { return &prd.ProductType }},
+	{"attributes", "attributes", func(prd *domain.Products) interface{} { return attributesColumn{&prd.Attributes} }},
+	{"tags", "tags", func(prd *domain.Products) interface{} { return pq.Array(&prd.Tags) }},
+	{"slug", "slug", func(prd *domain.Products) interface{} { return &prd.Slug }},
+}
+
+// attributesColumn scans the attributes JSONB of a product.
+type attributesColumn struct {
+	dst *map[string]interface{}
+}
+
+func (a attributesColumn) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case nil:
+		*a.dst = nil
+		return nil
+	case []byte:
+		return json.Unmarshal(v, a.dst)
+	case string:
+		return json.Unmarshal([]byte(v), a.dst)
+	default:
+		return fmt.Errorf("unsupported attributes type %T", src)
+	}
+}
+
+// attributesJSON renders the attributes of a product for its JSONB column.
+func attributesJSON(attrs map[string]interface{}) ([]byte, error) {
+	if attrs == nil {
+		return []byte(`{}`), nil
+	}
+	return json.Marshal(attrs)
+}
+
+// tagsArray renders the tags of a product, a product without tags gets an
+// empty array rather than NULL.
+func tagsArray(tags []string) interface{} {
+	if tags == nil {
+		tags = []string{}
+	}
+	return pq.Array(tags)
+}
+
+// productKeyFields are always read, the cursors and the ETag are built from
+// them whatever the client asked for.
+var productKeyFields = []string{"id", "created_at", "version"}
+
+// productProjection is the list of fields a read selects.
+type productProjection []productField
+
+// productProjectionAll selects every field.
+var productProjectionAll = productProjection(productFields)
+
+// projection resolves the requested JSON field names, every field is read
+// when none is asked for.
+func projection(fields []string) (productProjection, error) {
+	if len(fields) == 0 {
+		return productProjectionAll, nil
+	}
+
+	wanted := map[string]bool{}
+	for _, name := range productKeyFields {
+		wanted[name] = true
+	}
+	for _, name := range fields {
+		if !isProductField(name) {
+			return nil, domain.ErrBadParamInput
+		}
+		wanted[name] = true
+	}
+
+	pr := make(productProjection, 0, len(wanted))
+	for _, field := range productFields {
+		if wanted[field.name] {
+			pr = append(pr, field)
+		}
+	}
+
+	return pr, nil
+}
+
+func isProductField(name string) bool {
+	for _, field := range productFields {
+		if field.name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// columns renders the SELECT list of the projection.
+func (pr productProjection) columns() string {
+	cols := make([]string, len(pr))
+	for i, field := range pr {
+		cols[i] = field.column
+	}
+	return strings.Join(cols, ",")
+}
+
+// targets lists the scan destinations matching columns.
+func (pr productProjection) targets(prd *domain.Products) []interface{} {
+	targets := make([]interface{}, len(pr))
+	for i, field := range pr {
+		targets[i] = field.target(prd)
+	}
+	return targets
+}
+
+// queryArgs collects the positional arguments of a query being built.
+type queryArgs []interface{}
+
+// add appends v and returns its placeholder.
+func (a *queryArgs) add(v interface{}) string {
+	*a = append(*a, v)
+	return "$" + strconv.Itoa(len(*a))
+}
+
+// deletedCond narrows a lookup to the trash state asked by f.
+func deletedCond(f domain.ProductFilter) string {
+	switch {
+	case f.OnlyDeleted:
+		return "deleted_at IS NOT NULL"
+	case !f.IncludeDeleted:
+		return "deleted_at IS NULL"
+	}
+	return ""
+}
+
+// productFieldColumns maps the JSON names of the product fields to their
+// columns. Filters and sorts are only ever rendered from this allowlist.
+var productFieldColumns = map[string]string{
+	"id":              "id",
+	"name":            "product_name",
+	"desc":            "product_desc",
+	"created_at":      "created_at",
+	"updated_at":      "updated_at",
+	"product_img_src": "product_img_src",
+	"status":          "status",
+	"publish_at":      "publish_at",
+	"product_type":    "product_type",
+}
+
+// filterConds renders the predicates shared by the product list reads.
+func filterConds(f domain.ProductFilter, args *queryArgs) ([]string, error) {
+	var conds []string
+
+	if trash := deletedCond(f); trash != "" {
+		conds = append(conds, trash)
+	}
+
+	if f.PublishedOnly {
+		conds = append(conds, "status = "+args.add(domain.ProductPublished))
+	}
+
+	if f.CategoryID != "" {
+		conds = append(conds, fmt.Sprintf(`id IN (SELECT pc.product_id FROM product_categories pc
+	JOIN categories c ON c.id = pc.category_id
+	WHERE c.path LIKE (SELECT path FROM categories WHERE id = %s) || '%%')`, args.add(f.CategoryID)))
+	}
+
+	if len(f.Tags) > 0 {
+		switch f.TagsMode {
+		case domain.TagsAny, "":
+			conds = append(conds, "tags && "+args.add(pq.Array(f.Tags)))
+		case domain.TagsAll:
+			conds = append(conds, "tags @> "+args.add(pq.Array(f.Tags)))
+		default:
+			return nil, domain.ErrBadParamInput
+		}
+	}
+
+	for _, flt := range f.Attributes {
+		cond, err := attributeCond(flt, args)
+		if err != nil {
+			return nil, err
+		}
+		conds = append(conds, cond)
+	}
+
+	for _, flt := range f.Filters {
+		col, ok := productFieldColumns[flt.Field]
+		if !ok {
+			return nil, domain.ErrBadParamInput
+		}
+
+		switch flt.Op {
+		case pkg.FilterEq:
+			conds = append(conds, col+" = "+args.add(flt.Value))
+		case pkg.FilterContains:
+			v, _ := flt.Value.(string)
+			conds = append(conds, col+" ILIKE "+args.add("%"+escapeLike(v)+"%"))
+		case pkg.FilterAfter:
+			conds = append(conds, col+" > "+args.add(flt.Value))
+		case pkg.FilterBefore:
+			conds = append(conds, col+" < "+args.add(flt.Value))
+		case pkg.FilterHas:
+			if has, _ := flt.Value.(bool); has {
+				conds = append(conds, "COALESCE("+col+", '') <> ''")
+			} else {
+				conds = append(conds, "COALESCE("+col+", '') = ''")
+			}
+		default:
+			return nil, domain.ErrBadParamInput
+		}
+	}
+
+	return conds, nil
+}
+
+// attributeCond renders an attribute filter. The attribute name is bound as
+// a parameter like the value. Equality compares the text of the value, the
+// bounds compare numbers and the value of a unit attribute, other values
+// never match them.
+func attributeCond(flt pkg.Filter, args *queryArgs) (string, error) {
+	key := args.add(flt.Field) + "::text"
+	switch flt.Op {
+	case pkg.FilterEq:
+		return fmt.Sprintf("attributes->>(%s) = %s", key, args.add(flt.Value)), nil
+	case pkg.FilterGte, pkg.FilterLte:
+		op := ">="
+		if flt.Op == pkg.FilterLte {
+			op = "<="
+		}
+		number := fmt.Sprintf(`(CASE jsonb_typeof(attributes->(%[1]s))
+		WHEN 'number' THEN (attributes->>(%[1]s))::numeric
+		WHEN 'object' THEN (CASE jsonb_typeof(attributes->(%[1]s)->'value') WHEN 'number' THEN (attributes->(%[1]s)->>'value')::numeric END)
+	END)`, key)
+		return fmt.Sprintf("%s %s %s", number, op, args.add(flt.Value)), nil
+	default:
+		return "", domain.ErrBadParamInput
+	}
+}
+
+// orderClause renders the ORDER BY of a product list, id always breaks the
+// ties so pages are stable.
+func orderClause(sort []pkg.SortField) (string, error) {
+	if len(sort) == 0 {
+		return "created_at ASC, id ASC", nil
+	}
+
+	parts := make([]string, 0, len(sort)+1)
+	for _, s := range sort {
+		col, ok := productFieldColumns[s.Field]
+		if !ok || (s.Direction != pkg.SortingAsc && s.Direction != pkg.SortingDesc) {
+			return "", domain.ErrBadParamInput
+		}
+		parts = append(parts, col+" "+string(s.Direction))
+	}
+
+	return strings.Join(append(parts, "id ASC"), ", "), nil
+}
+
+// escapeLike makes the LIKE wildcards of a user value match literally.
+func escapeLike(v string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(v)
+}
+
+// whereAnd joins the non empty conditions.
+func whereAnd(conds ...string) string {
+	var nonEmpty []string
+	for _, cond := range conds {
+		if cond != "" {
+			nonEmpty = append(nonEmpty, cond)
+		}
+	}
+	return strings.Join(nonEmpty, " AND ")
+}
+
+func whereClause(conds []string) string {
+	where := whereAnd(conds...)
+	if where == "" {
+		return ""
+	}
+	return " WHERE " + where
+}
+
+// Fetch pages through the products ordered by (created_at, id). A cursor in
+// the pagination switches from LIMIT/OFFSET to keyset pagination, which stays
+// fast on deep pages and doesn't shift when rows are inserted meanwhile.
+func (p *productDBRepositories) Fetch(ctx context.Context, f domain.ProductFilter, pagination pkg.Pagination) (res []domain.Products, nextPagination pkg.Pagination, err error) {
+	if !pagination.Valid() || !pagination.GetCount().Valid() {
+		return nil, pkg.Pagination{}, domain.ErrBadParamInput
+	}
 
-	res, err = p.fetch(ctx, query, pagination.Limit, pagination.GetOffset())
+	pr, err := projection(f.Fields)
+	if err != nil {
+		return nil, pkg.Pagination{}, err
+	}
+	args := queryArgs{}
+	conds, err := filterConds(f, &args)
 	if err != nil {
 		return nil, pkg.Pagination{}, err
 	}
+	limit := pagination.GetLimit()
 
-	var total int64
-	p.Conn.QueryRow(`SELECT reltuples AS estimate FROM pg_class where relname = 'products'`).Scan(&total)
-	nextPagination.TotalRows = total
+	// the count runs with the filter only, not with the page bounds
+	countWhere, countArgs := whereClause(conds), append(queryArgs{}, args...)
 
-	totalPages := int(math.Ceil(float64(total) / float64(pagination.Limit)))
-	nextPagination.TotalPages = totalPages
+	var cursor *pkg.Cursor
+	if pagination.Cursor != "" {
+		c, err := pkg.DecodeCursor(pagination.Cursor)
+		if err != nil {
+			return nil, pkg.Pagination{}, domain.ErrBadParamInput
+		}
+		cursor = &c
+	}
 
-	nextPagination.Limit = pagination.Limit
+	var order, page string
+	if cursor == nil {
+		if order, err = orderClause(f.Sort); err != nil {
+			return nil, pkg.Pagination{}, err
+		}
+		page = fmt.Sprintf("LIMIT %s OFFSET %s", args.add(limit+1), args.add(pagination.GetOffset()))
+	} else {
+		// the cursor only encodes the creation order
+		if len(f.Sort) > 0 {
+			return nil, pkg.Pagination{}, domain.ErrBadParamInput
+		}
+		op, dir := ">", "ASC"
+		if cursor.Backward {
+			op, dir = "<", "DESC"
+		}
+		conds = append(conds, fmt.Sprintf("(created_at, id) %s (%s, %s)", op, args.add(cursor.CreatedAt), args.add(cursor.ID)))
+		order = fmt.Sprintf("created_at %s, id %s", dir, dir)
+		page = fmt.Sprintf("LIMIT %s", args.add(limit+1))
+	}
+
+	query := fmt.Sprintf(`SELECT %s
+	FROM products%s ORDER BY %s %s`, pr.columns(), whereClause(conds), order, page)
+
+	res, err = p.fetch(ctx, pr, query, args...)
+	if err != nil {
+		return nil, pkg.Pagination{}, err
+	}
+
+	// one row more than the limit is read to know whether a page follows
+	hasMore := len(res) > limit
+	if hasMore {
+		res = res[:limit]
+	}
+
+	switch {
+	case cursor == nil:
+		nextPagination.HasNext, nextPagination.HasPrev = hasMore, pagination.GetPage() > 1
+	case cursor.Backward:
+		for i, j := 0, len(res)-1; i < j; i, j = i+1, j-1 {
+			res[i], res[j] = res[j], res[i]
+		}
+		nextPagination.HasNext, nextPagination.HasPrev = true, hasMore
+	default:
+		nextPagination.HasNext, nextPagination.HasPrev = hasMore, true
+	}
+	nextPagination.NextCursor, nextPagination.PrevCursor = pageCursors(res, nextPagination.HasNext, nextPagination.HasPrev)
+
+	nextPagination.Limit = limit
 	nextPagination.Page = pagination.Page
+	nextPagination.Count = pagination.Count
+	total, err := p.count(ctx, pagination.Count, countWhere, countArgs)
+	if err != nil {
+		return nil, pkg.Pagination{}, err
+	}
+	nextPagination.SetTotal(total)
+
+	return
+}
+
+// count computes the number of products matching where with the given
+// strategy, CountNone always gives zero.
+func (p *productDBRepositories) count(ctx context.Context, strategy pkg.CountStrategy, where string, args []interface{}) (total int64, err error) {
+	switch strategy {
+	case pkg.CountExact:
+		err = p.Conn.QueryRowContext(ctx, `SELECT count(*) FROM products`+where, args...).Scan(&total)
+		return
+	case pkg.CountEstimate:
+		var plan []byte
+		err = p.Conn.QueryRowContext(ctx, `EXPLAIN (FORMAT JSON) SELECT 1 FROM products`+where, args...).Scan(&plan)
+		if err != nil {
+			return
+		}
+
+		var explain []struct {
+			Plan struct {
+				Rows float64 `json:"Plan Rows"`
+			} `json:"Plan"`
+		}
+		if err = json.Unmarshal(plan, &explain); err != nil {
+			return
+		}
+		if len(explain) > 0 {
+			total = int64(explain[0].Plan.Rows)
+		}
+		return
+	default:
+		return 0, nil
+	}
+}
+
+// pageCursors builds the cursors pointing after the last and before the
+// first product of a page.
+func pageCursors(res []domain.Products, hasNext, hasPrev bool) (next, prev string) {
+	if len(res) == 0 {
+		return
+	}
+
+	if hasNext {
+		last := res[len(res)-1]
+		next = pkg.EncodeCursor(pkg.Cursor{CreatedAt: last.CreatedAt, ID: last.ID})
+	}
+	if hasPrev {
+		first := res[0]
+		prev = pkg.EncodeCursor(pkg.Cursor{CreatedAt: first.CreatedAt, ID: first.ID, Backward: true})
+	}
+
+	return
+}
+
+func (p *productDBRepositories) GetByID(ctx context.Context, id string, f domain.ProductFilter) (res domain.Products, err error) {
+	pr, err := projection(f.Fields)
+	if err != nil {
+		return domain.Products{}, err
+	}
+	query := `SELECT ` + pr.columns() + ` from products WHERE ` + whereAnd("id=$1", deletedCond(f))
+	list, err := p.fetch(ctx, pr, query, id)
+	if err != nil {
+		return domain.Products{}, err
+	}
+
+	if len(list) > 0 {
+		res = list[0]
+	} else {
+		return res, domain.ErrNotFound
+	}
 
 	return
 }
 
-func (p *productDBRepositories) GetByID(ctx context.Context, id string) (res domain.Products, err error) {
-	query := `SELECT id,product_name,product_desc,created_at,updated_at,product_img_src from products WHERE id=$1`
-	list, err := p.fetch(ctx, query, id)
+func (p *productDBRepositories) GetByName(ctx context.Context, name string, f domain.ProductFilter) (res domain.Products, err error) {
+	pr, err := projection(f.Fields)
+	if err != nil {
+		return domain.Products{}, err
+	}
+	query := `SELECT ` + pr.columns() + ` from products WHERE ` + whereAnd("product_name=$1", deletedCond(f))
+	list, err := p.fetch(ctx, pr, query, name)
 	if err != nil {
 		return domain.Products{}, err
 	}
@@ -95,9 +528,15 @@ func (p *productDBRepositories) GetByID(ctx context.Context, id string) (res dom
 	return
 }
 
-func (p *productDBRepositories) GetByName(ctx context.Context, name string) (res domain.Products, err error) {
-	query := `SELECT id,product_name,product_desc,created_at,updated_at,product_img_src from products WHERE product_name=?`
-	list, err := p.fetch(ctx, query, name)
+// GetBySlug looks the slug up in the slug history, so the former slugs of a
+// product still find it.
+func (p *productDBRepositories) GetBySlug(ctx context.Context, slug string, f domain.ProductFilter) (res domain.Products, err error) {
+	pr, err := projection(f.Fields)
+	if err != nil {
+		return domain.Products{}, err
+	}
+	query := `SELECT ` + pr.columns() + ` from products WHERE ` + whereAnd("id=(SELECT product_id FROM product_slugs WHERE slug=$1)", deletedCond(f))
+	list, err := p.fetch(ctx, pr, query, slug)
 	if err != nil {
 		return domain.Products{}, err
 	}
@@ -111,77 +550,707 @@ func (p *productDBRepositories) GetByName(ctx context.Context, name string) (res
 	return
 }
 
-// insert a record
+// Search ranks the products matching every term of q as a prefix against the
+// search_vector column, which weights the name above the description.
+func (p *productDBRepositories) Search(ctx context.Context, q string, f domain.ProductFilter, pagination pkg.Pagination) (res []domain.ProductSearchHit, nextPagination pkg.Pagination, err error) {
+	tsQuery := prefixTSQuery(q)
+	if tsQuery == "" {
+		return nil, pkg.Pagination{}, domain.ErrBadParamInput
+	}
+
+	args := queryArgs{}
+	tsq := fmt.Sprintf("to_tsquery('simple', %s)", args.add(tsQuery))
+	filters, err := filterConds(f, &args)
+	if err != nil {
+		return nil, pkg.Pagination{}, err
+	}
+	conds := append([]string{"search_vector @@ " + tsq}, filters...)
+	where, countArgs := whereClause(conds), append(queryArgs{}, args...)
+
+	if !pagination.Valid() || !pagination.GetCount().Valid() {
+		return nil, pkg.Pagination{}, domain.ErrBadParamInput
+	}
+	limit, offset := pagination.GetLimit(), pagination.GetOffset()
+	query := fmt.Sprintf(`SELECT %s, ts_rank(search_vector, %s) AS rank,
+	ts_headline('simple', product_name, %s, '%s'),
+	ts_headline('simple', product_desc, %s, '%s')
+	FROM products%s ORDER BY rank DESC, created_at ASC LIMIT %s OFFSET %s`,
+		productProjectionAll.columns(), tsq, tsq, headlineOptions, tsq, headlineOptions, where, args.add(limit+1), args.add(offset))
+
+	rows, err := p.Conn.QueryContext(ctx, query, args...)
+	if err != nil {
+		logrus.Error(err)
+		return nil, pkg.Pagination{}, err
+	}
+
+	defer func() {
+		errRow := rows.Close()
+		if errRow != nil {
+			logrus.Error(err)
+		}
+	}()
+
+	res = make([]domain.ProductSearchHit, 0)
+	for rows.Next() {
+		hit := domain.ProductSearchHit{}
+		targets := append(productProjectionAll.targets(&hit.Products), &hit.Rank, &hit.Highlights.Name, &hit.Highlights.Description)
+		if err = rows.Scan(targets...); err != nil {
+			logrus.Error(err)
+			return nil, pkg.Pagination{}, err
+		}
+		hit.Highlights.Name = markHeadline(hit.Highlights.Name)
+		hit.Highlights.Description = markHeadline(hit.Highlights.Description)
+		res = append(res, hit)
+	}
+
+	hasMore := len(res) > limit
+	if hasMore {
+		res = res[:limit]
+	}
+	nextPagination.HasNext, nextPagination.HasPrev = hasMore, pagination.GetPage() > 1
+	nextPagination.Limit = limit
+	nextPagination.Page = pagination.GetPage()
+	nextPagination.Count = pagination.Count
+	total, err := p.count(ctx, pagination.Count, where, countArgs)
+	if err != nil {
+		return nil, pkg.Pagination{}, err
+	}
+	nextPagination.SetTotal(total)
+
+	return
+}
+
+// the search snippets mark the matched terms with private use characters,
+// markHeadline turns them into <mark> tags once the text is escaped.
+const (
+	headlineStart   = "\ue000"
+	headlineStop    = "\ue001"
+	headlineOptions = "StartSel=" + headlineStart + ", StopSel=" + headlineStop + ", MaxWords=35, MinWords=15, MaxFragments=2"
+)
+
+// markHeadline escapes a search snippet for HTML, the only markup left in
+// it are the <mark> tags around the matched terms.
+func markHeadline(headline string) string {
+	return strings.NewReplacer(headlineStart, "<mark>", headlineStop, "</mark>").Replace(html.EscapeString(headline))
+}
+
+// prefixTSQuery turns free text into a tsquery where every word has to
+// match as a prefix, anything but letters and digits is dropped so the
+// user input can't inject tsquery operators.
+func prefixTSQuery(q string) string {
+	words := strings.FieldsFunc(strings.ToLower(q), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	terms := make([]string, 0, len(words))
+	for _, w := range words {
+		terms = append(terms, w+":*")
+	}
+	return strings.Join(terms, " & ")
+}
+
+// Store takes prd.Slug, or the first of prd.Slug-2, prd.Slug-3... that no
+// product ever had, together with the product. Like every write of a
+// product it leaves its event in the outbox and its revision in the same
+// transaction.
 func (p *productDBRepositories) Store(ctx context.Context, prd *domain.Products) (err error) {
-	query := `INSERT INTO products (product_name,product_desc,created_at,updated_at,product_img_src) VALUES ($1, $2, $3, $4, $5) RETURNING id`
-	stmt, err := p.Conn.PrepareContext(ctx, query)
+	return p.StoreBatch(ctx, []*domain.Products{prd})
+}
+
+// StoreBatch stores every product like Store, all of them or none.
+func (p *productDBRepositories) StoreBatch(ctx context.Context, products []*domain.Products) (err error) {
+	query := `INSERT INTO products (product_name,product_desc,created_at,updated_at,product_img_src,status,publish_at,unpublish_at,product_type,attributes,tags,slug) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12) RETURNING ` + productProjectionAll.columns()
+
+	tx, err := p.Conn.BeginTx(ctx, nil)
 	if err != nil {
 		return
 	}
+	defer func() {
+		if err != nil {
+			if errRollback := tx.Rollback(); errRollback != nil {
+				logrus.Error(errRollback)
+			}
+		}
+	}()
 
-	row := stmt.QueryRowContext(
-		ctx,
-		prd.Name,
-		prd.Description,
-		prd.CreatedAt,
-		prd.UpdatedAt,
-		prd.ImageSrc,
-	)
-	var id string
-	if err = row.Scan(&id); err != nil {
+	stmt, err := tx.PrepareContext(ctx, query)
+	if err != nil {
 		return
 	}
+	defer stmt.Close()
 
-	prd.ID = id
+	created := make([]domain.Products, len(products))
+	for i, prd := range products {
+		attrs, err := attributesJSON(prd.Attributes)
+		if err != nil {
+			return err
+		}
+		slug, _, err := freeSlug(ctx, tx, "", prd.Slug)
+		if err != nil {
+			return err
+		}
+
+		row := stmt.QueryRowContext(
+			ctx,
+			prd.Name,
+			prd.Description,
+			prd.CreatedAt,
+			prd.UpdatedAt,
+			prd.ImageSrc,
+			prd.Status,
+			prd.PublishAt,
+			prd.UnpublishAt,
+			prd.ProductType,
+			attrs,
+			tagsArray(prd.Tags),
+			slug,
+		)
+		if err = row.Scan(productProjectionAll.targets(&created[i])...); err != nil {
+			return slugWriteError(err)
+		}
+		if err = claimSlug(ctx, tx, created[i].ID, slug, prd.CreatedAt); err != nil {
+			return err
+		}
+		if err = storeEvent(ctx, tx, domain.ProductCreated, created[i]); err != nil {
+			return err
+		}
+		if err = storeRevision(ctx, tx, domain.RevisionCreate, domain.Products{}, created[i]); err != nil {
+			return err
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return
+	}
+
+	for i, prd := range products {
+		*prd = created[i]
+	}
 	return
 }
 
-func (p *productDBRepositories) Update(ctx context.Context, prd *domain.Products) (err error) {
-	query := `UPDATE products SET product_name=$1 , product_desc=$2 , created_at=$3 , updated_at=$4 , product_img_src=$5  WHERE id $6`
+// Update only succeeds when prd.Version still matches the stored row, the
+// version is bumped in the same statement. A new prd.Slug is taken like in
+// Store and the former one stays in the history, an empty one is left as
+// it is.
+func (p *productDBRepositories) Update(ctx context.Context, prd *domain.Products, action domain.RevisionAction) (err error) {
+	query := `UPDATE products SET product_name=$1 , product_desc=$2 , updated_at=$3 , product_img_src=$4 , status=$5 , publish_at=$6 , unpublish_at=$7 , product_type=$8 , attributes=$9 , tags=$10 , slug=COALESCE(NULLIF($11, ''), slug) , version=version+1  WHERE id=$12 AND version=$13 AND deleted_at IS NULL RETURNING ` + productProjectionAll.columns()
 
-	stmt, err := p.Conn.PrepareContext(ctx, query)
+	attrs, err := attributesJSON(prd.Attributes)
 	if err != nil {
 		return
 	}
 
-	res, err := stmt.ExecContext(ctx, prd.Name, prd.Description, prd.CreatedAt, prd.UpdatedAt, prd.ImageSrc, prd.ID)
+	tx, err := p.Conn.BeginTx(ctx, nil)
 	if err != nil {
 		return
 	}
-	affect, err := res.RowsAffected()
+	defer func() {
+		if err != nil {
+			if errRollback := tx.Rollback(); errRollback != nil {
+				logrus.Error(errRollback)
+			}
+		}
+	}()
+
+	prev, err := lockProduct(ctx, tx, prd.ID)
 	if err != nil {
 		return
 	}
-	if affect != 1 {
-		err = fmt.Errorf("weird  Behavior. Total Affected: %d", affect)
+	if prev.DeletedAt != nil {
+		return domain.ErrNotFound
+	}
+	if prev.Version != prd.Version {
+		return domain.ErrPreconditionFailed
+	}
+
+	slug, owned := "", true
+	if prd.Slug != "" {
+		if slug, owned, err = freeSlug(ctx, tx, prd.ID, prd.Slug); err != nil {
+			return
+		}
+	}
+
+	stmt, err := tx.PrepareContext(ctx, query)
+	if err != nil {
 		return
 	}
+	defer stmt.Close()
 
+	var updated domain.Products
+	err = stmt.QueryRowContext(ctx, prd.Name, prd.Description, prd.UpdatedAt, prd.ImageSrc, prd.Status, prd.PublishAt, prd.UnpublishAt, prd.ProductType, attrs, tagsArray(prd.Tags), slug, prd.ID, prd.Version).Scan(productProjectionAll.targets(&updated)...)
+	if err == sql.ErrNoRows {
+		err = domain.ErrPreconditionFailed
+		return
+	}
+	if err != nil {
+		return slugWriteError(err)
+	}
+	if !owned {
+		if err = claimSlug(ctx, tx, prd.ID, updated.Slug, prd.UpdatedAt); err != nil {
+			return
+		}
+	}
+	if err = storeEvent(ctx, tx, domain.ProductUpdated, updated); err != nil {
+		return
+	}
+	if err = storeRevision(ctx, tx, action, prev, updated); err != nil {
+		return
+	}
+
+	if err = tx.Commit(); err != nil {
+		return
+	}
+
+	*prd = updated
+	return
+}
+
+// lockProduct reads the product as it is before a write and locks its row
+// until the transaction ends.
+func lockProduct(ctx context.Context, tx *sql.Tx, id string) (res domain.Products, err error) {
+	query := `SELECT ` + productProjectionAll.columns() + ` FROM products WHERE id = $1 FOR UPDATE`
+
+	err = tx.QueryRowContext(ctx, query, id).Scan(productProjectionAll.targets(&res)...)
+	if err == sql.ErrNoRows {
+		return res, domain.ErrNotFound
+	}
 	return
 }
 
-func (p *productDBRepositories) Delete(ctx context.Context, id string) (err error) {
-	query := "DELETE FROM products WHERE id = $1"
+// storeEvent writes the change of the product to the outbox, the payload is
+// the product as written.
+func storeEvent(ctx context.Context, tx *sql.Tx, eventType domain.ProductEventType, prd domain.Products) error {
+	return storeEventAt(ctx, tx, eventType, prd, prd.UpdatedAt)
+}
+
+// storeEventAt is storeEvent for the changes that leave no row to take the
+// time from, like a purge.
+func storeEventAt(ctx context.Context, tx *sql.Tx, eventType domain.ProductEventType, prd domain.Products, at time.Time) error {
+	payload, err := json.Marshal(prd)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, `INSERT INTO product_events (product_id,type,payload,created_at) VALUES ($1, $2, $3, $4)`, prd.ID, eventType, payload, at)
+	return err
+}
+
+// freeSlug returns base, or base with the lowest numeric suffix, that no
+// other product than productID ever had. owned tells whether the product
+// already had it.
+func freeSlug(ctx context.Context, tx *sql.Tx, productID, base string) (slug string, owned bool, err error) {
+	rows, err := tx.QueryContext(ctx, `SELECT slug, product_id FROM product_slugs WHERE slug = $1 OR slug LIKE $2`, base, escapeLike(base)+"-%")
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+
+	taken := map[string]bool{}
+	mine := map[string]bool{}
+	for rows.Next() {
+		var s, owner string
+		if err = rows.Scan(&s, &owner); err != nil {
+			return
+		}
+		if owner == productID {
+			mine[s] = true
+		} else {
+			taken[s] = true
+		}
+	}
+	if err = rows.Err(); err != nil {
+		return
+	}
+
+	slug = base
+	for n := 2; taken[slug]; n++ {
+		slug = fmt.Sprintf("%s-%d", base, n)
+	}
+	return slug, mine[slug], nil
+}
+
+// claimSlug records the slug in the history of the product.
+func claimSlug(ctx context.Context, tx *sql.Tx, productID, slug string, now time.Time) error {
+	_, err := tx.ExecContext(ctx, `INSERT INTO product_slugs (slug,product_id,created_at) VALUES ($1, $2, $3)`, slug, productID, now)
+	return slugWriteError(err)
+}
+
+// slugWriteError maps a slug taken by a concurrent write to ErrConflict,
+// retrying picks the next free one.
+func slugWriteError(err error) error {
+	if pkg.IsUniqueViolation(err) {
+		return domain.ErrConflict
+	}
+	return err
+}
+
+// Delete moves the product to the trash, it is only removed for good by
+// PurgeDeleted once the retention has passed.
+func (p *productDBRepositories) Delete(ctx context.Context, id string, version int64) (res domain.Products, err error) {
+	query := "UPDATE products SET deleted_at = now(), updated_at = now(), version = version + 1 WHERE id = $1 AND version = $2 AND deleted_at IS NULL RETURNING " + productProjectionAll.columns()
+
+	tx, err := p.Conn.BeginTx(ctx, nil)
+	if err != nil {
+		return
+	}
+	defer func() {
+		if err != nil {
+			if errRollback := tx.Rollback(); errRollback != nil {
+				logrus.Error(errRollback)
+			}
+		}
+	}()
+
+	prev, err := lockProduct(ctx, tx, id)
+	if err != nil {
+		return
+	}
+	if prev.DeletedAt != nil {
+		return res, domain.ErrNotFound
+	}
+	if prev.Version != version {
+		return res, domain.ErrPreconditionFailed
+	}
 
-	stmt, err := p.Conn.PrepareContext(ctx, query)
+	err = tx.QueryRowContext(ctx, query, id, version).Scan(productProjectionAll.targets(&res)...)
+	if err == sql.ErrNoRows {
+		err = domain.ErrPreconditionFailed
+		return
+	}
 	if err != nil {
 		return
 	}
+	if err = storeEvent(ctx, tx, domain.ProductDeleted, res); err != nil {
+		return
+	}
+	if err = storeRevision(ctx, tx, domain.RevisionDelete, prev, res); err != nil {
+		return
+	}
 
-	res, err := stmt.ExecContext(ctx, id)
+	err = tx.Commit()
+	return
+}
+
+// Restore takes the product out of the trash, to the downstream services it
+// is updated.
+func (p *productDBRepositories) Restore(ctx context.Context, id string) (res domain.Products, err error) {
+	query := "UPDATE products SET deleted_at = NULL, updated_at = now(), version = version + 1 WHERE id = $1 AND deleted_at IS NOT NULL RETURNING " + productProjectionAll.columns()
+
+	tx, err := p.Conn.BeginTx(ctx, nil)
 	if err != nil {
 		return
 	}
+	defer func() {
+		if err != nil {
+			if errRollback := tx.Rollback(); errRollback != nil {
+				logrus.Error(errRollback)
+			}
+		}
+	}()
 
-	rowsAfected, err := res.RowsAffected()
+	prev, err := lockProduct(ctx, tx, id)
 	if err != nil {
 		return
 	}
+	if prev.DeletedAt == nil {
+		return res, domain.ErrNotFound
+	}
 
-	if rowsAfected != 1 {
-		err = fmt.Errorf("weird  Behavior. Total Affected: %d", rowsAfected)
+	err = tx.QueryRowContext(ctx, query, id).Scan(productProjectionAll.targets(&res)...)
+	if err == sql.ErrNoRows {
+		err = domain.ErrNotFound
+		return
+	}
+	if err != nil {
+		return
+	}
+	if err = storeEvent(ctx, tx, domain.ProductUpdated, res); err != nil {
+		return
+	}
+	if err = storeRevision(ctx, tx, domain.RevisionRestore, prev, res); err != nil {
 		return
 	}
 
+	err = tx.Commit()
 	return
 }
+
+// PurgeDeleted permanently removes the products trashed before the given
+// time, with a purge event for each. The images of the purged products and
+// of their variants are returned unless another product still links to them.
+func (p *productDBRepositories) PurgeDeleted(ctx context.Context, before, now time.Time) (res []domain.Products, images []string, err error) {
+	query := `WITH purged AS (
+		DELETE FROM products WHERE deleted_at IS NOT NULL AND deleted_at < $1 RETURNING ` + productProjectionAll.columns() + `
+	), variant_images AS (
+		SELECT v.img_src FROM product_variants v JOIN purged ON v.product_id = purged.id
+	) SELECT ` + productProjectionAll.columns() + `, ARRAY(SELECT img_src FROM variant_images) FROM purged`
+
+	tx, err := p.Conn.BeginTx(ctx, nil)
+	if err != nil {
+		return
+	}
+	defer func() {
+		if err != nil {
+			if errRollback := tx.Rollback(); errRollback != nil {
+				logrus.Error(errRollback)
+			}
+		}
+	}()
+
+	rows, err := tx.QueryContext(ctx, query, before)
+	if err != nil {
+		return
+	}
+	var candidates []string
+	for rows.Next() {
+		var prd domain.Products
+		var variantImages []string
+		if err = rows.Scan(append(productProjectionAll.targets(&prd), pq.Array(&variantImages))...); err != nil {
+			rows.Close()
+			return
+		}
+		res = append(res, prd)
+		candidates = append(candidates, prd.ImageSrc)
+		candidates = append(candidates, variantImages...)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return
+	}
+
+	for _, prd := range res {
+		if err = storeEventAt(ctx, tx, domain.ProductPurged, prd, now); err != nil {
+			return
+		}
+	}
+
+	images, err = unlinkedImages(ctx, tx, candidates)
+	if err != nil {
+		return
+	}
+
+	if err = tx.Commit(); err != nil {
+		return
+	}
+	return res, images, nil
+}
+
+// unlinkedImages returns the distinct images of srcs no product or variant
+// links to.
+func unlinkedImages(ctx context.Context, tx *sql.Tx, srcs []string) (res []string, err error) {
+	seen := map[string]bool{"": true}
+	unique := make([]string, 0, len(srcs))
+	for _, src := range srcs {
+		if !seen[src] {
+			seen[src] = true
+			unique = append(unique, src)
+		}
+	}
+	if len(unique) == 0 {
+		return nil, nil
+	}
+
+	query := `SELECT src FROM unnest($1::text[]) AS u(src)
+	WHERE NOT EXISTS (SELECT 1 FROM products WHERE product_img_src = u.src)
+	AND NOT EXISTS (SELECT 1 FROM product_variants WHERE img_src = u.src)`
+
+	rows, err := tx.QueryContext(ctx, query, pq.Array(unique))
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var src string
+		if err = rows.Scan(&src); err != nil {
+			return
+		}
+		res = append(res, src)
+	}
+	return res, rows.Err()
+}
+
+// FetchDue reads the live products the scheduler has to move.
+func (p *productDBRepositories) FetchDue(ctx context.Context, now time.Time, limit int) (res []domain.Products, err error) {
+	query := `SELECT ` + productProjectionAll.columns() + ` FROM products
+	WHERE deleted_at IS NULL AND ((status = $1 AND publish_at <= $2) OR (status = $3 AND unpublish_at <= $2))
+	ORDER BY created_at, id LIMIT $4`
+	return p.fetch(ctx, productProjectionAll, query, domain.ProductScheduled, now, domain.ProductPublished, limit)
+}
+
+// Export declares a cursor over the products matching f in a read only
+// transaction, the rows are then fetched a batch at a time so the table is
+// never held in memory. The transaction ends with the ctx or on Close.
+func (p *productDBRepositories) Export(ctx context.Context, f domain.ProductFilter, batchSize int) (res domain.ProductExport, err error) {
+	if batchSize <= 0 {
+		return nil, domain.ErrBadParamInput
+	}
+	pr, err := projection(f.Fields)
+	if err != nil {
+		return nil, err
+	}
+	args := queryArgs{}
+	conds, err := filterConds(f, &args)
+	if err != nil {
+		return nil, err
+	}
+	order, err := orderClause(f.Sort)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := p.Conn.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return nil, err
+	}
+
+	query := fmt.Sprintf(`DECLARE product_export NO SCROLL CURSOR FOR SELECT %s
+	FROM products%s ORDER BY %s`, pr.columns(), whereClause(conds), order)
+	if _, err = tx.ExecContext(ctx, query, args...); err != nil {
+		logrus.Error(err)
+		if errRollback := tx.Rollback(); errRollback != nil {
+			logrus.Error(errRollback)
+		}
+		return nil, err
+	}
+
+	return &productExport{tx: tx, pr: pr, batchSize: batchSize}, nil
+}
+
+// productExport fetches from the cursor declared by Export.
+type productExport struct {
+	tx        *sql.Tx
+	pr        productProjection
+	batchSize int
+}
+
+func (e *productExport) Next(ctx context.Context) (res []domain.Products, err error) {
+	rows, err := e.tx.QueryContext(ctx, fmt.Sprintf("FETCH FORWARD %d FROM product_export", e.batchSize))
+	if err != nil {
+		logrus.Error(err)
+		return nil, err
+	}
+
+	defer func() {
+		errRow := rows.Close()
+		if errRow != nil {
+			logrus.Error(err)
+		}
+	}()
+
+	res = make([]domain.Products, 0, e.batchSize)
+	for rows.Next() {
+		prd := domain.Products{}
+		if err = rows.Scan(e.pr.targets(&prd)...); err != nil {
+			logrus.Error(err)
+			return nil, err
+		}
+		res = append(res, prd)
+	}
+
+	return res, rows.Err()
+}
+
+// Close ends the transaction, which closes the cursor. Nothing was written
+// so it is rolled back, unless the ctx of Export already did.
+func (e *productExport) Close() error {
+	if err := e.tx.Rollback(); err != nil && err != sql.ErrTxDone {
+		return err
+	}
+	return nil
+}
+
+// FetchTags counts the tags over the live products, the most used first.
+func (p *productDBRepositories) FetchTags(ctx context.Context) (res []domain.ProductTag, err error) {
+	query := `SELECT t, count(*) FROM products, unnest(tags) AS t WHERE deleted_at IS NULL GROUP BY t ORDER BY count(*) DESC, t`
+	rows, err := p.Conn.QueryContext(ctx, query)
+	if err != nil {
+		logrus.Error(err)
+		return nil, err
+	}
+
+	defer func() {
+		errRow := rows.Close()
+		if errRow != nil {
+			logrus.Error(err)
+		}
+	}()
+
+	res = make([]domain.ProductTag, 0)
+	for rows.Next() {
+		tag := domain.ProductTag{}
+		if err = rows.Scan(&tag.Tag, &tag.Count); err != nil {
+			logrus.Error(err)
+			return nil, err
+		}
+		res = append(res, tag)
+	}
+
+	return res, nil
+}
+
+// RenameTags replaces the From tags with To and folds the duplicates it
+// creates, the tags keep the place of their first occurrence. The products
+// changed get a new version so pending writes based on the old tags fail,
+// and a revision with their tags before the rename.
+func (p *productDBRepositories) RenameTags(ctx context.Context, r domain.TagRename, now time.Time) (res []domain.Products, err error) {
+	query := `UPDATE products SET tags = ARRAY(
+		SELECT t FROM (
+			SELECT CASE WHEN u.t = ANY($1) THEN $2 ELSE u.t END AS t, u.ord
+			FROM unnest(tags) WITH ORDINALITY AS u(t, ord)
+		) renamed GROUP BY t ORDER BY min(ord)
+	), updated_at=$3 , version=version+1
+	FROM (SELECT id AS old_id, tags AS old_tags FROM products WHERE tags && $1 FOR UPDATE) old
+	WHERE id = old.old_id RETURNING ` + productProjectionAll.columns() + `, old.old_tags`
+
+	tx, err := p.Conn.BeginTx(ctx, nil)
+	if err != nil {
+		return
+	}
+	defer func() {
+		if err != nil {
+			if errRollback := tx.Rollback(); errRollback != nil {
+				logrus.Error(errRollback)
+			}
+		}
+	}()
+
+	rows, err := tx.QueryContext(ctx, query, pq.Array(r.From), r.To, now)
+	if err != nil {
+		return
+	}
+	var updated, prev []domain.Products
+	for rows.Next() {
+		var prd domain.Products
+		var oldTags []string
+		if err = rows.Scan(append(productProjectionAll.targets(&prd), pq.Array(&oldTags))...); err != nil {
+			rows.Close()
+			return
+		}
+		before := prd
+		before.Tags = oldTags
+		updated = append(updated, prd)
+		prev = append(prev, before)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return
+	}
+
+	// the events and revisions go out once every row was read, the
+	// connection of the transaction is busy with the rows until then
+	for i, prd := range updated {
+		if err = storeEvent(ctx, tx, domain.ProductUpdated, prd); err != nil {
+			return
+		}
+		if err = storeRevision(ctx, tx, domain.RevisionUpdate, prev[i], prd); err != nil {
+			return
+		}
+	}
+	if err = tx.Commit(); err != nil {
+		return
+	}
+
+	return updated, nil
+}
@@ -2,6 +2,7 @@ package repositories_test
 
 import (
 	"context"
+	"fmt"
 	"testing"
 	"time"
 
@@ -27,6 +28,7 @@ func TestFetchRepositoryProduct(t *testing.T) {
 			CreatedAt:   now,
 			UpdatedAt:   now,
 			ImageSrc:    "img_src_1",
+			Version:     1,
 		},
 		{
 			ID:          "2ag3vagvs",
@@ -35,33 +37,285 @@ func TestFetchRepositoryProduct(t *testing.T) {
 			CreatedAt:   now,
 			UpdatedAt:   now,
 			ImageSrc:    "img_src_2",
+			Version:     3,
 		},
 	}
 
-	rows := sqlmock.NewRows([]string{"id", "product_name", "product_desc", "created_at", "updated_at", "product_img_src"}).
-		AddRow(mockProducts[0].ID, mockProducts[0].Name, mockProducts[0].Description, mockProducts[0].CreatedAt, mockProducts[0].UpdatedAt, mockProducts[0].ImageSrc).
-		AddRow(mockProducts[1].ID, mockProducts[1].Name, mockProducts[1].Description, mockProducts[1].CreatedAt, mockProducts[1].UpdatedAt, mockProducts[1].ImageSrc)
+	rows := sqlmock.NewRows([]string{"id", "product_name", "product_desc", "created_at", "updated_at", "product_img_src", "version", "deleted_at", "status", "publish_at", "unpublish_at", "product_type", "attributes", "tags", "slug"}).
+		AddRow(mockProducts[0].ID, mockProducts[0].Name, mockProducts[0].Description, mockProducts[0].CreatedAt, mockProducts[0].UpdatedAt, mockProducts[0].ImageSrc, mockProducts[0].Version, nil, "published", nil, nil, "", []byte("{}"), []byte("{}"), "product-1").
+		AddRow(mockProducts[1].ID, mockProducts[1].Name, mockProducts[1].Description, mockProducts[1].CreatedAt, mockProducts[1].UpdatedAt, mockProducts[1].ImageSrc, mockProducts[1].Version, nil, "published", nil, nil, "", []byte("{}"), []byte("{}"), "product-1")
 
-	query := `SELECT id,product_name,product_desc,created_at,updated_at,product_img_src
-	FROM products ORDER BY created_at ASC LIMIT \$1 OFFSET \$2`
+	query := `SELECT id,product_name,product_desc,created_at,updated_at,product_img_src,version,deleted_at,status,publish_at,unpublish_at,product_type,attributes,tags,slug
+	FROM products WHERE deleted_at IS NULL ORDER BY created_at ASC, id ASC LIMIT \$1 OFFSET \$2`
 
-	mock.ExpectQuery(query).WillReturnRows(rows)
+	mock.ExpectQuery(query).WithArgs(3, 0).WillReturnRows(rows)
+	mock.ExpectQuery(`SELECT count\(\*\) FROM products WHERE deleted_at IS NULL`).WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(2))
 	a := repositories.NewProductDBRepository(db)
 	pg := pkg.Pagination{
 		Limit: 2,
 		Page:  1,
 	}
-	list, nextPg, err := a.Fetch(context.TODO(), pg)
+	list, nextPg, err := a.Fetch(context.TODO(), domain.ProductFilter{}, pg)
 	assert.NotEmpty(t, nextPg.Limit)
 	assert.NotEmpty(t, nextPg.Page)
 	assert.NoError(t, err)
 	assert.Len(t, list, 2)
+	assert.Equal(t, int64(2), nextPg.TotalRows)
+	assert.Equal(t, 1, nextPg.TotalPages)
+	assert.False(t, nextPg.HasNext)
+	assert.False(t, nextPg.HasPrev)
+	assert.Empty(t, nextPg.NextCursor)
+	assert.Empty(t, nextPg.PrevCursor)
+}
+
+func TestFetchRepositoryProductHasNext(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	now := time.Now()
+
+	rows := sqlmock.NewRows([]string{"id", "product_name", "product_desc", "created_at", "updated_at", "product_img_src", "version", "deleted_at", "status", "publish_at", "unpublish_at", "product_type", "attributes", "tags", "slug"}).
+		AddRow("1", "product 1", "description 1", now, now, "img_src_1", 1, nil, "published", nil, nil, "", []byte("{}"), []byte("{}"), "product-1").
+		AddRow("2", "product 2", "description 2", now, now, "img_src_2", 1, nil, "published", nil, nil, "", []byte("{}"), []byte("{}"), "product-1")
+
+	query := `FROM products WHERE deleted_at IS NULL ORDER BY created_at ASC, id ASC LIMIT \$1 OFFSET \$2`
+	mock.ExpectQuery(query).WithArgs(2, 1).WillReturnRows(rows)
+
+	a := repositories.NewProductDBRepository(db)
+	list, nextPg, err := a.Fetch(context.TODO(), domain.ProductFilter{}, pkg.Pagination{Limit: 1, Page: 2, Count: pkg.CountNone})
+	assert.NoError(t, err)
+	assert.Len(t, list, 1)
+	assert.True(t, nextPg.HasNext)
+	assert.True(t, nextPg.HasPrev)
+	assert.NotEmpty(t, nextPg.NextCursor)
+	assert.NotEmpty(t, nextPg.PrevCursor)
+	assert.Equal(t, int64(0), nextPg.TotalRows)
+}
+
+func TestFetchRepositoryProductEstimateCount(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	now := time.Now()
+
+	rows := sqlmock.NewRows([]string{"id", "product_name", "product_desc", "created_at", "updated_at", "product_img_src", "version", "deleted_at", "status", "publish_at", "unpublish_at", "product_type", "attributes", "tags", "slug"}).
+		AddRow("1", "product 1", "description 1", now, now, "img_src_1", 1, nil, "published", nil, nil, "", []byte("{}"), []byte("{}"), "product-1")
+
+	mock.ExpectQuery(`FROM products WHERE deleted_at IS NULL ORDER BY`).WillReturnRows(rows)
+	mock.ExpectQuery(`EXPLAIN \(FORMAT JSON\) SELECT 1 FROM products WHERE deleted_at IS NULL`).
+		WillReturnRows(sqlmock.NewRows([]string{"QUERY PLAN"}).AddRow([]byte(`[{"Plan": {"Node Type": "Seq Scan", "Plan Rows": 1200}}]`)))
+
+	a := repositories.NewProductDBRepository(db)
+	_, nextPg, err := a.Fetch(context.TODO(), domain.ProductFilter{}, pkg.Pagination{Limit: 10, Count: pkg.CountEstimate})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1200), nextPg.TotalRows)
+	assert.Equal(t, 120, nextPg.TotalPages)
+}
+
+func TestFetchRepositoryProductUnknownCount(t *testing.T) {
+	db, _, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	a := repositories.NewProductDBRepository(db)
+	_, _, err = a.Fetch(context.TODO(), domain.ProductFilter{}, pkg.Pagination{Count: "guess"})
+	assert.Equal(t, domain.ErrBadParamInput, err)
+}
+
+func TestFetchRepositoryProductNegativeLimit(t *testing.T) {
+	db, _, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	a := repositories.NewProductDBRepository(db)
+	_, _, err = a.Fetch(context.TODO(), domain.ProductFilter{}, pkg.Pagination{Limit: -1})
+	assert.Equal(t, domain.ErrBadParamInput, err)
+
+	_, _, err = a.Search(context.TODO(), "shirt", domain.ProductFilter{}, pkg.Pagination{Limit: 10, Page: -1})
+	assert.Equal(t, domain.ErrBadParamInput, err)
+}
+
+func TestFetchRepositoryProductWithCursor(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	now := time.Now()
+
+	rows := sqlmock.NewRows([]string{"id", "product_name", "product_desc", "created_at", "updated_at", "product_img_src", "version", "deleted_at", "status", "publish_at", "unpublish_at", "product_type", "attributes", "tags", "slug"}).
+		AddRow("2", "product 2", "description 2", now, now, "img_src_2", 1, nil, "published", nil, nil, "", []byte("{}"), []byte("{}"), "product-1").
+		AddRow("3", "product 3", "description 3", now, now, "img_src_3", 1, nil, "published", nil, nil, "", []byte("{}"), []byte("{}"), "product-1")
+
+	after := pkg.Cursor{CreatedAt: now.Add(-time.Minute).UTC().Round(0), ID: "1"}
+	query := `FROM products WHERE deleted_at IS NULL AND \(created_at, id\) > \(\$1, \$2\) ORDER BY created_at ASC, id ASC LIMIT \$3`
+	mock.ExpectQuery(query).WithArgs(after.CreatedAt, after.ID, 2).WillReturnRows(rows)
+	mock.ExpectQuery(`SELECT count\(\*\) FROM products WHERE deleted_at IS NULL`).WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(3))
+
+	a := repositories.NewProductDBRepository(db)
+	list, nextPg, err := a.Fetch(context.TODO(), domain.ProductFilter{}, pkg.Pagination{Limit: 1, Cursor: pkg.EncodeCursor(after)})
+	assert.NoError(t, err)
+	assert.Len(t, list, 1)
+	assert.Equal(t, "2", list[0].ID)
+
+	next, err := pkg.DecodeCursor(nextPg.NextCursor)
+	assert.NoError(t, err)
+	assert.Equal(t, "2", next.ID)
+	assert.False(t, next.Backward)
+
+	prev, err := pkg.DecodeCursor(nextPg.PrevCursor)
+	assert.NoError(t, err)
+	assert.Equal(t, "2", prev.ID)
+	assert.True(t, prev.Backward)
+}
+
+func TestFetchRepositoryProductWithTamperedCursor(t *testing.T) {
+	db, _, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	token := pkg.EncodeCursor(pkg.Cursor{CreatedAt: time.Now(), ID: "1"})
+
+	a := repositories.NewProductDBRepository(db)
+	_, _, err = a.Fetch(context.TODO(), domain.ProductFilter{}, pkg.Pagination{Cursor: "x" + token})
+	assert.Equal(t, domain.ErrBadParamInput, err)
+}
+
+func TestFetchRepositoryProductWithFiltersAndSort(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	now := time.Now()
+	after := now.Add(-24 * time.Hour).UTC().Round(0)
+
+	rows := sqlmock.NewRows([]string{"id", "product_name", "product_desc", "created_at", "updated_at", "product_img_src", "version", "deleted_at", "status", "publish_at", "unpublish_at", "product_type", "attributes", "tags", "slug"}).
+		AddRow("1", "blue_shirt", "description 1", now, now, "img_src_1", 1, nil, "published", nil, nil, "", []byte("{}"), []byte("{}"), "product-1")
+
+	where := `WHERE deleted_at IS NULL AND product_name ILIKE \$1 AND created_at > \$2 AND COALESCE\(product_img_src, ''\) <> ''`
+	mock.ExpectQuery(`FROM products `+where+` ORDER BY updated_at DESC, product_name ASC, id ASC LIMIT \$3 OFFSET \$4`).
+		WithArgs(`%blue\_shirt%`, after, 11, 0).WillReturnRows(rows)
+	mock.ExpectQuery(`SELECT count\(\*\) FROM products `+where).
+		WithArgs(`%blue\_shirt%`, after).WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+
+	f := domain.ProductFilter{
+		Filters: []pkg.Filter{
+			{Field: "name", Op: pkg.FilterContains, Value: "blue_shirt"},
+			{Field: "created_at", Op: pkg.FilterAfter, Value: after},
+			{Field: "product_img_src", Op: pkg.FilterHas, Value: true},
+		},
+		Sort: []pkg.SortField{
+			{Field: "updated_at", Direction: pkg.SortingDesc},
+			{Field: "name", Direction: pkg.SortingAsc},
+		},
+	}
+
+	a := repositories.NewProductDBRepository(db)
+	list, nextPg, err := a.Fetch(context.TODO(), f, pkg.Pagination{Limit: 10})
+	assert.NoError(t, err)
+	assert.Len(t, list, 1)
+	assert.Equal(t, int64(1), nextPg.TotalRows)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestFetchRepositoryProductByCategory(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	now := time.Now()
+
+	rows := sqlmock.NewRows([]string{"id", "product_name", "product_desc", "created_at", "updated_at", "product_img_src", "version", "deleted_at", "status", "publish_at", "unpublish_at", "product_type", "attributes", "tags", "slug"}).
+		AddRow("1", "product 1", "description 1", now, now, "img_src_1", 1, nil, "published", nil, nil, "", []byte("{}"), []byte("{}"), "product-1")
+
+	where := `WHERE deleted_at IS NULL AND id IN \(SELECT pc.product_id FROM product_categories pc
+	JOIN categories c ON c.id = pc.category_id
+	WHERE c.path LIKE \(SELECT path FROM categories WHERE id = \$1\) \|\| '%'\)`
+	mock.ExpectQuery(`FROM products `+where+` ORDER BY created_at ASC, id ASC LIMIT \$2 OFFSET \$3`).
+		WithArgs("clothing", 11, 0).WillReturnRows(rows)
+	mock.ExpectQuery(`SELECT count\(\*\) FROM products ` + where).
+		WithArgs("clothing").WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+
+	a := repositories.NewProductDBRepository(db)
+	list, _, err := a.Fetch(context.TODO(), domain.ProductFilter{CategoryID: "clothing"}, pkg.Pagination{Limit: 10})
+	assert.NoError(t, err)
+	assert.Len(t, list, 1)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestFetchRepositoryProductRejectsUnknownColumns(t *testing.T) {
+	db, _, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	a := repositories.NewProductDBRepository(db)
+
+	_, _, err = a.Fetch(context.TODO(), domain.ProductFilter{
+		Sort: []pkg.SortField{{Field: "version; DROP TABLE products", Direction: pkg.SortingAsc}},
+	}, pkg.Pagination{})
+	assert.Equal(t, domain.ErrBadParamInput, err)
+
+	_, _, err = a.Fetch(context.TODO(), domain.ProductFilter{
+		Filters: []pkg.Filter{{Field: "password", Op: pkg.FilterEq, Value: "x"}},
+	}, pkg.Pagination{})
+	assert.Equal(t, domain.ErrBadParamInput, err)
+
+	token := pkg.EncodeCursor(pkg.Cursor{CreatedAt: time.Now(), ID: "1"})
+	_, _, err = a.Fetch(context.TODO(), domain.ProductFilter{
+		Sort: []pkg.SortField{{Field: "name", Direction: pkg.SortingAsc}},
+	}, pkg.Pagination{Cursor: token})
+	assert.Equal(t, domain.ErrBadParamInput, err)
+}
+
+func TestFetchRepositoryProductWithFields(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	now := time.Now()
+
+	rows := sqlmock.NewRows([]string{"id", "product_name", "created_at", "product_img_src", "version"}).
+		AddRow("1", "product 1", now, "img_src_1", 2)
+
+	query := `SELECT id,product_name,created_at,product_img_src,version
+	FROM products WHERE deleted_at IS NULL ORDER BY created_at ASC, id ASC LIMIT \$1 OFFSET \$2`
+	mock.ExpectQuery(query).WithArgs(11, 0).WillReturnRows(rows)
+	mock.ExpectQuery(`SELECT count\(\*\) FROM products WHERE deleted_at IS NULL`).WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+
+	a := repositories.NewProductDBRepository(db)
+	f := domain.ProductFilter{Fields: []string{"id", "name", "product_img_src"}}
+	list, _, err := a.Fetch(context.TODO(), f, pkg.Pagination{Limit: 10})
+	assert.NoError(t, err)
+	assert.Len(t, list, 1)
+	assert.Equal(t, "img_src_1", list[0].ImageSrc)
+	assert.Empty(t, list[0].Description)
+	assert.Equal(t, int64(2), list[0].Version)
+}
+
+func TestFetchRepositoryProductWithUnknownField(t *testing.T) {
+	db, _, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	a := repositories.NewProductDBRepository(db)
+	f := domain.ProductFilter{Fields: []string{"id", "password"}}
+	_, _, err = a.Fetch(context.TODO(), f, pkg.Pagination{})
+	assert.Equal(t, domain.ErrBadParamInput, err)
+
+	_, err = a.GetByID(context.TODO(), "1", f)
+	assert.Equal(t, domain.ErrBadParamInput, err)
 }
 
 func TestInsertRepositoryProduct(t *testing.T) {
 	now := time.Now()
 	ar := &domain.Products{
 		Name:        "product test",
+		Slug:        "product-test",
 		Description: "description test",
 		CreatedAt:   now,
 		UpdatedAt:   now,
@@ -72,15 +326,68 @@ func TestInsertRepositoryProduct(t *testing.T) {
 		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
 	}
 
-	query := `INSERT INTO products \(product_name,product_desc,created_at,updated_at,product_img_src\) VALUES \(\$1, \$2, \$3, \$4, \$5\)`
+	mock.ExpectBegin()
+	query := `INSERT INTO products \(product_name,product_desc,created_at,updated_at,product_img_src,status,publish_at,unpublish_at,product_type,attributes,tags,slug\) VALUES \(\$1, \$2, \$3, \$4, \$5, \$6, \$7, \$8, \$9, \$10, \$11, \$12\) RETURNING id,product_name,(.+),slug`
 	prep := mock.ExpectPrepare(query)
-	prep.ExpectExec().WithArgs(ar.Name, ar.Description, ar.CreatedAt, ar.UpdatedAt, ar.ImageSrc).WillReturnResult(sqlmock.NewResult(12, 1))
+	mock.ExpectQuery(`SELECT slug, product_id FROM product_slugs WHERE slug = \$1 OR slug LIKE \$2`).
+		WithArgs("product-test", "product-test-%").
+		WillReturnRows(sqlmock.NewRows([]string{"slug", "product_id"}).AddRow("product-test", "7").AddRow("product-test-3", "8"))
+	prep.ExpectQuery().WithArgs(ar.Name, ar.Description, ar.CreatedAt, ar.UpdatedAt, ar.ImageSrc, ar.Status, ar.PublishAt, ar.UnpublishAt, ar.ProductType, []byte("{}"), "{}", "product-test-2").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "product_name", "product_desc", "created_at", "updated_at", "product_img_src", "version", "deleted_at", "status", "publish_at", "unpublish_at", "product_type", "attributes", "tags", "slug"}).AddRow("12", ar.Name, ar.Description, now, now, ar.ImageSrc, 1, nil, "", nil, nil, "", []byte("{}"), []byte("{}"), "product-test-2"))
+	mock.ExpectExec(`INSERT INTO product_slugs \(slug,product_id,created_at\) VALUES \(\$1, \$2, \$3\)`).
+		WithArgs("product-test-2", "12", now).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`INSERT INTO product_events \(product_id,type,payload,created_at\) VALUES \(\$1, \$2, \$3, \$4\)`).
+		WithArgs("12", domain.ProductCreated, sqlmock.AnyArg(), now).WillReturnResult(sqlmock.NewResult(0, 1))
+	// the revision is written in the transaction of the product
+	mock.ExpectExec(`INSERT INTO product_revisions \(product_id,revision,action,actor,changed_fields,snapshot,created_at\)`).
+		WithArgs("12", 1, domain.RevisionCreate, "", sqlmock.AnyArg(), sqlmock.AnyArg(), now).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
 
 	a := repositories.NewProductDBRepository(db)
 
 	err = a.Store(context.TODO(), ar)
 	assert.NoError(t, err)
 	assert.Equal(t, "12", ar.ID)
+	assert.Equal(t, int64(1), ar.Version)
+	assert.Equal(t, "product-test-2", ar.Slug)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestInsertBatchRepositoryProduct(t *testing.T) {
+	now := time.Now()
+	batch := []*domain.Products{
+		{Name: "product a", Slug: "product-a", CreatedAt: now, UpdatedAt: now},
+		{Name: "product b", Slug: "product-b", CreatedAt: now, UpdatedAt: now},
+	}
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	mock.ExpectBegin()
+	query := `INSERT INTO products \(product_name,product_desc,created_at,updated_at,product_img_src,status,publish_at,unpublish_at,product_type,attributes,tags,slug\) VALUES \(\$1, \$2, \$3, \$4, \$5, \$6, \$7, \$8, \$9, \$10, \$11, \$12\) RETURNING id,product_name,(.+),slug`
+	prep := mock.ExpectPrepare(query)
+	for i, prd := range batch {
+		id := fmt.Sprint(20 + i)
+		mock.ExpectQuery(`SELECT slug, product_id FROM product_slugs WHERE slug = \$1 OR slug LIKE \$2`).
+			WithArgs(prd.Slug, prd.Slug+"-%").
+			WillReturnRows(sqlmock.NewRows([]string{"slug", "product_id"}))
+		prep.ExpectQuery().WithArgs(prd.Name, prd.Description, prd.CreatedAt, prd.UpdatedAt, prd.ImageSrc, prd.Status, prd.PublishAt, prd.UnpublishAt, prd.ProductType, []byte("{}"), "{}", prd.Slug).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "product_name", "product_desc", "created_at", "updated_at", "product_img_src", "version", "deleted_at", "status", "publish_at", "unpublish_at", "product_type", "attributes", "tags", "slug"}).AddRow(id, prd.Name, "", now, now, "", 1, nil, "", nil, nil, "", []byte("{}"), []byte("{}"), prd.Slug))
+		mock.ExpectExec(`INSERT INTO product_slugs \(slug,product_id,created_at\) VALUES \(\$1, \$2, \$3\)`).
+			WithArgs(prd.Slug, id, now).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(`INSERT INTO product_events`).WithArgs(id, domain.ProductCreated, sqlmock.AnyArg(), now).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(`INSERT INTO product_revisions`).WithArgs(id, 1, domain.RevisionCreate, "", sqlmock.AnyArg(), sqlmock.AnyArg(), now).WillReturnResult(sqlmock.NewResult(0, 1))
+	}
+	mock.ExpectCommit()
+
+	a := repositories.NewProductDBRepository(db)
+
+	err = a.StoreBatch(context.TODO(), batch)
+	assert.NoError(t, err)
+	assert.Equal(t, "20", batch[0].ID)
+	assert.Equal(t, "21", batch[1].ID)
+	assert.NoError(t, mock.ExpectationsWereMet())
 }
 
 func TestUpdateRepositoryProduct(t *testing.T) {
@@ -92,20 +399,109 @@ func TestUpdateRepositoryProduct(t *testing.T) {
 		CreatedAt:   now,
 		UpdatedAt:   now,
 		ImageSrc:    "img_url_3",
+		Version:     2,
 	}
 	db, mock, err := sqlmock.New()
 	if err != nil {
 		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
 	}
 
-	query := `UPDATE products SET product_name=\$1 , product_desc=\$2 , created_at=\$3 , updated_at=\$4 , product_img_src=\$5  WHERE id \$6`
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT id,product_name,(.+),slug FROM products WHERE id = \$1 FOR UPDATE`).WithArgs("12").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "product_name", "product_desc", "created_at", "updated_at", "product_img_src", "version", "deleted_at", "status", "publish_at", "unpublish_at", "product_type", "attributes", "tags", "slug"}).AddRow("12", "product test", "description test", now, now, "", 2, nil, "", nil, nil, "", []byte("{}"), []byte("{}"), "product-test"))
+	query := `UPDATE products SET product_name=\$1 , product_desc=\$2 , updated_at=\$3 , product_img_src=\$4 , status=\$5 , publish_at=\$6 , unpublish_at=\$7 , product_type=\$8 , attributes=\$9 , tags=\$10 , slug=COALESCE\(NULLIF\(\$11, ''\), slug\) , version=version\+1  WHERE id=\$12 AND version=\$13 AND deleted_at IS NULL RETURNING id,product_name,(.+),slug`
+	rows := sqlmock.NewRows([]string{"id", "product_name", "product_desc", "created_at", "updated_at", "product_img_src", "version", "deleted_at", "status", "publish_at", "unpublish_at", "product_type", "attributes", "tags", "slug"}).
+		AddRow("12", "product test", "description test", now, now, "img_url_3", 3, nil, "", nil, nil, "", []byte("{}"), []byte("{}"), "product-test")
 	prep := mock.ExpectPrepare(query)
-	prep.ExpectExec().WithArgs(ar.Name, ar.Description, ar.CreatedAt, ar.UpdatedAt, ar.ImageSrc, ar.ID).WillReturnResult(sqlmock.NewResult(12, 1))
+	prep.ExpectQuery().WithArgs(ar.Name, ar.Description, ar.UpdatedAt, ar.ImageSrc, ar.Status, ar.PublishAt, ar.UnpublishAt, ar.ProductType, []byte("{}"), "{}", "", ar.ID, ar.Version).WillReturnRows(rows)
+	mock.ExpectExec(`INSERT INTO product_events \(product_id,type,payload,created_at\)`).
+		WithArgs("12", domain.ProductUpdated, sqlmock.AnyArg(), now).WillReturnResult(sqlmock.NewResult(0, 1))
+	// only the image changed since the locked row
+	mock.ExpectExec(`INSERT INTO product_revisions`).
+		WithArgs("12", 3, domain.RevisionUpdate, "", []byte(`["product_img_src"]`), sqlmock.AnyArg(), now).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	a := repositories.NewProductDBRepository(db)
+
+	err = a.Update(context.TODO(), ar, domain.RevisionUpdate)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), ar.Version)
+	assert.Equal(t, "product-test", ar.Slug)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUpdateRepositoryProductRenamed(t *testing.T) {
+	now := time.Now()
+	ar := &domain.Products{
+		ID:          "12",
+		Name:        "blue shirt",
+		Slug:        "blue-shirt",
+		Description: "description test",
+		UpdatedAt:   now,
+		Version:     2,
+	}
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT id,product_name,(.+),slug FROM products WHERE id = \$1 FOR UPDATE`).WithArgs("12").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "product_name", "product_desc", "created_at", "updated_at", "product_img_src", "version", "deleted_at", "status", "publish_at", "unpublish_at", "product_type", "attributes", "tags", "slug"}).AddRow("12", "product test", "description test", now, now, "", 2, nil, "", nil, nil, "", []byte("{}"), []byte("{}"), "product-test"))
+	mock.ExpectQuery(`SELECT slug, product_id FROM product_slugs`).
+		WithArgs("blue-shirt", "blue-shirt-%").
+		WillReturnRows(sqlmock.NewRows([]string{"slug", "product_id"}).AddRow("blue-shirt", "7"))
+	prep := mock.ExpectPrepare(`UPDATE products SET .* WHERE id=\$12 AND version=\$13 AND deleted_at IS NULL RETURNING`)
+	prep.ExpectQuery().WithArgs(ar.Name, ar.Description, ar.UpdatedAt, ar.ImageSrc, ar.Status, ar.PublishAt, ar.UnpublishAt, ar.ProductType, []byte("{}"), "{}", "blue-shirt-2", ar.ID, ar.Version).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "product_name", "product_desc", "created_at", "updated_at", "product_img_src", "version", "deleted_at", "status", "publish_at", "unpublish_at", "product_type", "attributes", "tags", "slug"}).AddRow("12", "blue shirt", "description test", now, now, "", 3, nil, "", nil, nil, "", []byte("{}"), []byte("{}"), "blue-shirt-2"))
+	mock.ExpectExec(`INSERT INTO product_slugs`).WithArgs("blue-shirt-2", "12", now).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`INSERT INTO product_events`).WithArgs("12", domain.ProductUpdated, sqlmock.AnyArg(), now).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`INSERT INTO product_revisions`).WithArgs("12", 3, domain.RevisionUpdate, "", sqlmock.AnyArg(), sqlmock.AnyArg(), now).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	a := repositories.NewProductDBRepository(db)
+
+	err = a.Update(context.TODO(), ar, domain.RevisionUpdate)
+	assert.NoError(t, err)
+	assert.Equal(t, "blue-shirt-2", ar.Slug)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUpdateRepositoryProductFormerSlug(t *testing.T) {
+	now := time.Now()
+	ar := &domain.Products{
+		ID:          "12",
+		Name:        "blue shirt",
+		Slug:        "blue-shirt",
+		Description: "description test",
+		UpdatedAt:   now,
+		Version:     2,
+	}
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	// the product had the slug before, it gets it back without a new entry
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT id,product_name,(.+),slug FROM products WHERE id = \$1 FOR UPDATE`).WithArgs("12").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "product_name", "product_desc", "created_at", "updated_at", "product_img_src", "version", "deleted_at", "status", "publish_at", "unpublish_at", "product_type", "attributes", "tags", "slug"}).AddRow("12", "product test", "description test", now, now, "", 2, nil, "", nil, nil, "", []byte("{}"), []byte("{}"), "product-test"))
+	mock.ExpectQuery(`SELECT slug, product_id FROM product_slugs`).
+		WithArgs("blue-shirt", "blue-shirt-%").
+		WillReturnRows(sqlmock.NewRows([]string{"slug", "product_id"}).AddRow("blue-shirt", "12"))
+	prep := mock.ExpectPrepare(`UPDATE products SET .* RETURNING`)
+	prep.ExpectQuery().WithArgs(ar.Name, ar.Description, ar.UpdatedAt, ar.ImageSrc, ar.Status, ar.PublishAt, ar.UnpublishAt, ar.ProductType, []byte("{}"), "{}", "blue-shirt", ar.ID, ar.Version).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "product_name", "product_desc", "created_at", "updated_at", "product_img_src", "version", "deleted_at", "status", "publish_at", "unpublish_at", "product_type", "attributes", "tags", "slug"}).AddRow("12", "blue shirt", "description test", now, now, "", 3, nil, "", nil, nil, "", []byte("{}"), []byte("{}"), "blue-shirt"))
+	mock.ExpectExec(`INSERT INTO product_events`).WithArgs("12", domain.ProductUpdated, sqlmock.AnyArg(), now).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`INSERT INTO product_revisions`).WithArgs("12", 3, domain.RevisionUpdate, "", sqlmock.AnyArg(), sqlmock.AnyArg(), now).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
 
 	a := repositories.NewProductDBRepository(db)
 
-	err = a.Update(context.TODO(), ar)
+	err = a.Update(context.TODO(), ar, domain.RevisionUpdate)
 	assert.NoError(t, err)
+	assert.Equal(t, "blue-shirt", ar.Slug)
+	assert.NoError(t, mock.ExpectationsWereMet())
 }
 
 func TestDeleteRepositoryProduct(t *testing.T) {
@@ -114,16 +510,28 @@ func TestDeleteRepositoryProduct(t *testing.T) {
 		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
 	}
 
-	query := `DELETE FROM products WHERE id = \$1`
+	now := time.Now()
+
+	query := `UPDATE products SET deleted_at = now\(\), updated_at = now\(\), version = version \+ 1 WHERE id = \$1 AND version = \$2 AND deleted_at IS NULL RETURNING`
+	rows := sqlmock.NewRows([]string{"id", "product_name", "product_desc", "created_at", "updated_at", "product_img_src", "version", "deleted_at", "status", "publish_at", "unpublish_at", "product_type", "attributes", "tags", "slug"}).
+		AddRow("12", "product 1", "description 1", now, now, "img_src_1", 5, now, "published", nil, nil, "", []byte("{}"), []byte("{}"), "product-1")
 
-	prep := mock.ExpectPrepare(query)
-	prep.ExpectExec().WithArgs("12").WillReturnResult(sqlmock.NewResult(12, 1))
+	// the event and the revision go out with the trashed product
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT (.+) FROM products WHERE id = \$1 FOR UPDATE`).WithArgs("12").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "product_name", "product_desc", "created_at", "updated_at", "product_img_src", "version", "deleted_at", "status", "publish_at", "unpublish_at", "product_type", "attributes", "tags", "slug"}).AddRow("12", "product 1", "description 1", now, now, "img_src_1", 4, nil, "published", nil, nil, "", []byte("{}"), []byte("{}"), "product-1"))
+	mock.ExpectQuery(query).WithArgs("12", 4).WillReturnRows(rows)
+	mock.ExpectExec(`INSERT INTO product_events`).WithArgs("12", domain.ProductDeleted, sqlmock.AnyArg(), now).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`INSERT INTO product_revisions`).WithArgs("12", 5, domain.RevisionDelete, "", []byte(`["deleted_at"]`), sqlmock.AnyArg(), now).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
 
 	a := repositories.NewProductDBRepository(db)
 
 	num := "12"
-	err = a.Delete(context.TODO(), num)
+	deleted, err := a.Delete(context.TODO(), num, 4)
 	assert.NoError(t, err)
+	assert.NotNil(t, deleted.DeletedAt)
+	assert.NoError(t, mock.ExpectationsWereMet())
 }
 
 func TestGetByIdRepositoryProduct(t *testing.T) {
@@ -135,9 +543,9 @@ func TestGetByIdRepositoryProduct(t *testing.T) {
 	now := time.Now().Add(1)
 
 	rows := sqlmock.NewRows([]string{
-		"id", "product_name", "product_desc", "created_at", "updated_at", "product_img_src",
+		"id", "product_name", "product_desc", "created_at", "updated_at", "product_img_src", "version", "deleted_at", "status", "publish_at", "unpublish_at", "product_type", "attributes", "tags", "slug",
 	}).AddRow(
-		"3", "product 1", "desc 1", now, now, "img_src",
+		"3", "product 1", "desc 1", now, now, "img_src", 1, nil, "published", nil, nil, "shirt", []byte(`{"color": "blue"}`), []byte("{summer,cotton}"), "product-1",
 	)
 
 	mockData := domain.Products{
@@ -147,16 +555,410 @@ func TestGetByIdRepositoryProduct(t *testing.T) {
 		CreatedAt:   now,
 		UpdatedAt:   now,
 		ImageSrc:    "img_src",
+		Version:     1,
+		Status:      domain.ProductPublished,
+		ProductType: "shirt",
+		Attributes:  map[string]interface{}{"color": "blue"},
+		Tags:        []string{"summer", "cotton"},
+		Slug:        "product-1",
 	}
 
-	query := `SELECT id,product_name,product_desc,created_at,updated_at,product_img_src from products WHERE id=\$1`
+	query := `SELECT id,product_name,product_desc,created_at,updated_at,product_img_src,version,deleted_at,status,publish_at,unpublish_at,product_type,attributes,tags,slug from products WHERE id=\$1 AND deleted_at IS NULL`
 
 	mock.ExpectQuery(query).WillReturnRows(rows)
 	a := repositories.NewProductDBRepository(db)
 
 	num := "3"
-	aProduct, err := a.GetByID(context.TODO(), num)
+	aProduct, err := a.GetByID(context.TODO(), num, domain.ProductFilter{})
 	assert.NoError(t, err)
 	assert.NotNil(t, aProduct)
 	assert.Equal(t, mockData, aProduct)
 }
+
+func TestUpdateRepositoryProductNotFound(t *testing.T) {
+	now := time.Now()
+	ar := &domain.Products{
+		ID:          "404",
+		Name:        "product test",
+		Description: "description test",
+		UpdatedAt:   now,
+		Version:     1,
+	}
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT (.+) FROM products WHERE id = \$1 FOR UPDATE`).WithArgs(ar.ID).WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	mock.ExpectRollback()
+
+	a := repositories.NewProductDBRepository(db)
+
+	err = a.Update(context.TODO(), ar, domain.RevisionUpdate)
+	assert.Equal(t, domain.ErrNotFound, err)
+}
+
+func TestUpdateRepositoryProductStaleVersion(t *testing.T) {
+	now := time.Now()
+	ar := &domain.Products{
+		ID:          "12",
+		Name:        "product test",
+		Description: "description test",
+		UpdatedAt:   now,
+		Version:     1,
+	}
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	// the row locked is at version 2 already
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT id,product_name,(.+),slug FROM products WHERE id = \$1 FOR UPDATE`).WithArgs("12").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "product_name", "product_desc", "created_at", "updated_at", "product_img_src", "version", "deleted_at", "status", "publish_at", "unpublish_at", "product_type", "attributes", "tags", "slug"}).AddRow("12", "product test", "description test", now, now, "", 2, nil, "", nil, nil, "", []byte("{}"), []byte("{}"), "product-test"))
+	mock.ExpectRollback()
+
+	a := repositories.NewProductDBRepository(db)
+
+	err = a.Update(context.TODO(), ar, domain.RevisionUpdate)
+	assert.Equal(t, domain.ErrPreconditionFailed, err)
+	assert.Equal(t, int64(1), ar.Version)
+}
+
+func TestFetchTrashedRepositoryProduct(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	now := time.Now()
+
+	rows := sqlmock.NewRows([]string{"id", "product_name", "product_desc", "created_at", "updated_at", "product_img_src", "version", "deleted_at", "status", "publish_at", "unpublish_at", "product_type", "attributes", "tags", "slug"}).
+		AddRow("1asfgret3", "product 1", "description 1", now, now, "img_src_1", 2, now, "published", nil, nil, "", []byte("{}"), []byte("{}"), "product-1")
+
+	query := `FROM products WHERE deleted_at IS NOT NULL ORDER BY created_at ASC, id ASC LIMIT \$1 OFFSET \$2`
+
+	mock.ExpectQuery(query).WillReturnRows(rows)
+	mock.ExpectQuery(`SELECT count\(\*\) FROM products WHERE deleted_at IS NOT NULL`).WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+	a := repositories.NewProductDBRepository(db)
+	list, _, err := a.Fetch(context.TODO(), domain.ProductFilter{OnlyDeleted: true}, pkg.Pagination{Limit: 10, Page: 1})
+	assert.NoError(t, err)
+	assert.Len(t, list, 1)
+	assert.NotNil(t, list[0].DeletedAt)
+}
+
+func TestRestoreRepositoryProduct(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	now := time.Now()
+
+	query := `UPDATE products SET deleted_at = NULL, updated_at = now\(\), version = version \+ 1 WHERE id = \$1 AND deleted_at IS NOT NULL RETURNING`
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT (.+) FROM products WHERE id = \$1 FOR UPDATE`).WithArgs("12").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "product_name", "product_desc", "created_at", "updated_at", "product_img_src", "version", "deleted_at", "status", "publish_at", "unpublish_at", "product_type", "attributes", "tags", "slug"}).AddRow("12", "product 1", "description 1", now, now, "img_src_1", 5, now, "published", nil, nil, "", []byte("{}"), []byte("{}"), "product-1"))
+	mock.ExpectQuery(query).WithArgs("12").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "product_name", "product_desc", "created_at", "updated_at", "product_img_src", "version", "deleted_at", "status", "publish_at", "unpublish_at", "product_type", "attributes", "tags", "slug"}).AddRow("12", "product 1", "description 1", now, now, "img_src_1", 6, nil, "published", nil, nil, "", []byte("{}"), []byte("{}"), "product-1"))
+	mock.ExpectExec(`INSERT INTO product_events`).WithArgs("12", domain.ProductUpdated, sqlmock.AnyArg(), now).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`INSERT INTO product_revisions`).WithArgs("12", 6, domain.RevisionRestore, "", []byte(`["deleted_at"]`), sqlmock.AnyArg(), now).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	a := repositories.NewProductDBRepository(db)
+
+	restored, err := a.Restore(context.TODO(), "12")
+	assert.NoError(t, err)
+	assert.Nil(t, restored.DeletedAt)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRestoreRepositoryProductNotTrashed(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	now := time.Now()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT (.+) FROM products WHERE id = \$1 FOR UPDATE`).WithArgs("12").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "product_name", "product_desc", "created_at", "updated_at", "product_img_src", "version", "deleted_at", "status", "publish_at", "unpublish_at", "product_type", "attributes", "tags", "slug"}).AddRow("12", "product 1", "description 1", now, now, "img_src_1", 5, nil, "published", nil, nil, "", []byte("{}"), []byte("{}"), "product-1"))
+	mock.ExpectRollback()
+
+	a := repositories.NewProductDBRepository(db)
+
+	_, err = a.Restore(context.TODO(), "12")
+	assert.Equal(t, domain.ErrNotFound, err)
+}
+
+func TestPurgeDeletedRepositoryProduct(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	now := time.Now()
+	before := now.Add(-time.Hour)
+
+	rows := sqlmock.NewRows([]string{"id", "product_name", "product_desc", "created_at", "updated_at", "product_img_src", "version", "deleted_at", "status", "publish_at", "unpublish_at", "product_type", "attributes", "tags", "slug", "variant_images"}).
+		AddRow("1", "product 1", "description 1", now, now, "http://localhost/api/v1/download/a.png", 3, before, "published", nil, nil, "", []byte("{}"), []byte("{}"), "product-1", []byte("{http://localhost/api/v1/download/b.png}")).
+		AddRow("2", "product 2", "description 2", now, now, "http://localhost/api/v1/download/shared.png", 2, before, "published", nil, nil, "", []byte("{}"), []byte("{}"), "product-2", []byte("{}"))
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`WITH purged AS \(\s+DELETE FROM products WHERE deleted_at IS NOT NULL AND deleted_at < \$1 RETURNING (.+)\s+\), variant_images AS`).
+		WithArgs(before).WillReturnRows(rows)
+	mock.ExpectExec(`INSERT INTO product_events`).WithArgs("1", domain.ProductPurged, sqlmock.AnyArg(), now).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`INSERT INTO product_events`).WithArgs("2", domain.ProductPurged, sqlmock.AnyArg(), now).WillReturnResult(sqlmock.NewResult(0, 1))
+	// another product still links to shared.png
+	mock.ExpectQuery(`SELECT src FROM unnest\(\$1::text\[\]\) AS u\(src\)\s+WHERE NOT EXISTS`).
+		WithArgs(`{"http://localhost/api/v1/download/a.png","http://localhost/api/v1/download/b.png","http://localhost/api/v1/download/shared.png"}`).
+		WillReturnRows(sqlmock.NewRows([]string{"src"}).AddRow("http://localhost/api/v1/download/a.png").AddRow("http://localhost/api/v1/download/b.png"))
+	mock.ExpectCommit()
+
+	a := repositories.NewProductDBRepository(db)
+
+	purged, images, err := a.PurgeDeleted(context.TODO(), before, now)
+	assert.NoError(t, err)
+	assert.Len(t, purged, 2)
+	assert.Equal(t, []string{"http://localhost/api/v1/download/a.png", "http://localhost/api/v1/download/b.png"}, images)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetByNameRepositoryProduct(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	now := time.Now()
+
+	rows := sqlmock.NewRows([]string{"id", "product_name", "product_desc", "created_at", "updated_at", "product_img_src", "version", "deleted_at", "status", "publish_at", "unpublish_at", "product_type", "attributes", "tags", "slug"}).
+		AddRow("3", "product 1", "desc 1", now, now, "img_src", 1, nil, "published", nil, nil, "", []byte("{}"), []byte("{}"), "product-1")
+
+	query := `from products WHERE product_name=\$1 AND deleted_at IS NULL`
+	mock.ExpectQuery(query).WithArgs("product 1").WillReturnRows(rows)
+
+	a := repositories.NewProductDBRepository(db)
+
+	aProduct, err := a.GetByName(context.TODO(), "product 1", domain.ProductFilter{})
+	assert.NoError(t, err)
+	assert.Equal(t, "3", aProduct.ID)
+}
+
+func TestSearchRepositoryProduct(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	now := time.Now()
+
+	rows := sqlmock.NewRows([]string{"id", "product_name", "product_desc", "created_at", "updated_at", "product_img_src", "version", "deleted_at", "status", "publish_at", "unpublish_at", "product_type", "attributes", "tags", "slug", "rank", "name_headline", "desc_headline"}).
+		AddRow("1", "blue cotton shirt", "a shirt", now, now, "img_src_1", 1, nil, "published", nil, nil, "", []byte("{}"), []byte("{}"), "product-1", 0.6, "\ue000blue\ue001 \ue000cotton\ue001 shirt <b>", "a shirt")
+
+	query := `SELECT .*, ts_rank\(search_vector, to_tsquery\('simple', \$1\)\) AS rank, .* FROM products WHERE search_vector @@ to_tsquery\('simple', \$1\) AND deleted_at IS NULL ORDER BY rank DESC, created_at ASC LIMIT \$2 OFFSET \$3`
+	mock.ExpectQuery(query).WithArgs("blue:* & cott:*", 11, 0).WillReturnRows(rows)
+	mock.ExpectQuery(`SELECT count\(\*\) FROM products WHERE search_vector @@ to_tsquery\('simple', \$1\) AND deleted_at IS NULL`).
+		WithArgs("blue:* & cott:*").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+
+	a := repositories.NewProductDBRepository(db)
+
+	hits, nextPg, err := a.Search(context.TODO(), "Blue cott!", domain.ProductFilter{}, pkg.Pagination{})
+	assert.NoError(t, err)
+	assert.Len(t, hits, 1)
+	assert.Equal(t, "1", hits[0].ID)
+	assert.Equal(t, 0.6, hits[0].Rank)
+	assert.Equal(t, "<mark>blue</mark> <mark>cotton</mark> shirt &lt;b&gt;", hits[0].Highlights.Name)
+	assert.Equal(t, int64(1), nextPg.TotalRows)
+}
+
+func TestFetchRepositoryProductPublishedOnly(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	now := time.Now()
+
+	rows := sqlmock.NewRows([]string{"id", "product_name", "product_desc", "created_at", "updated_at", "product_img_src", "version", "deleted_at", "status", "publish_at", "unpublish_at", "product_type", "attributes", "tags", "slug"}).
+		AddRow("1", "product 1", "description 1", now, now, "img_src_1", 1, nil, "published", now, nil, "", []byte("{}"), []byte("{}"), "product-1")
+
+	where := `WHERE deleted_at IS NULL AND status = \$1`
+	mock.ExpectQuery(`FROM products `+where+` ORDER BY created_at ASC, id ASC LIMIT \$2 OFFSET \$3`).
+		WithArgs(domain.ProductPublished, 11, 0).WillReturnRows(rows)
+	mock.ExpectQuery(`SELECT count\(\*\) FROM products ` + where).
+		WithArgs(domain.ProductPublished).WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+
+	a := repositories.NewProductDBRepository(db)
+	list, _, err := a.Fetch(context.TODO(), domain.ProductFilter{PublishedOnly: true}, pkg.Pagination{Limit: 10, Page: 1})
+	assert.NoError(t, err)
+	assert.Len(t, list, 1)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestFetchRepositoryProductByAttributes(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	now := time.Now()
+
+	rows := sqlmock.NewRows([]string{"id", "product_name", "product_desc", "created_at", "updated_at", "product_img_src", "version", "deleted_at", "status", "publish_at", "unpublish_at", "product_type", "attributes", "tags", "slug"}).
+		AddRow("1", "product 1", "description 1", now, now, "img_src_1", 1, nil, "published", now, nil, "shirt", []byte(`{"color": "blue", "weight": {"value": 1.5, "unit": "kg"}}`), []byte("{}"), "product-1")
+
+	mock.ExpectQuery(`WHERE deleted_at IS NULL AND attributes->>\(\$1::text\) = \$2 AND \(CASE jsonb_typeof\(attributes->\(\$3::text\)\)(.|\n)* >= \$4 ORDER BY`).
+		WithArgs("color", "blue", "weight", 1.0, 11, 0).WillReturnRows(rows)
+	mock.ExpectQuery(`SELECT count\(\*\) FROM products WHERE deleted_at IS NULL AND attributes->>`).
+		WithArgs("color", "blue", "weight", 1.0).WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+
+	a := repositories.NewProductDBRepository(db)
+	list, _, err := a.Fetch(context.TODO(), domain.ProductFilter{Attributes: []pkg.Filter{
+		{Field: "color", Op: pkg.FilterEq, Value: "blue"},
+		{Field: "weight", Op: pkg.FilterGte, Value: 1.0},
+	}}, pkg.Pagination{Limit: 10, Page: 1})
+	assert.NoError(t, err)
+	assert.Len(t, list, 1)
+	assert.Equal(t, "blue", list[0].Attributes["color"])
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestFetchDueRepositoryProduct(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	now := time.Now()
+
+	rows := sqlmock.NewRows([]string{"id", "product_name", "product_desc", "created_at", "updated_at", "product_img_src", "version", "deleted_at", "status", "publish_at", "unpublish_at", "product_type", "attributes", "tags", "slug"}).
+		AddRow("1", "product 1", "description 1", now, now, "img_src_1", 1, nil, "scheduled", now.Add(-time.Minute), nil, "", []byte("{}"), []byte("{}"), "product-1")
+
+	mock.ExpectQuery(`WHERE deleted_at IS NULL AND \(\(status = \$1 AND publish_at <= \$2\) OR \(status = \$3 AND unpublish_at <= \$2\)\)`).
+		WithArgs(domain.ProductScheduled, now, domain.ProductPublished, 100).WillReturnRows(rows)
+
+	a := repositories.NewProductDBRepository(db)
+	list, err := a.FetchDue(context.TODO(), now, 100)
+	assert.NoError(t, err)
+	assert.Len(t, list, 1)
+	assert.Equal(t, domain.ProductScheduled, list[0].Status)
+}
+
+func TestExportRepositoryProduct(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	now := time.Now()
+
+	rows := sqlmock.NewRows([]string{"id", "product_name", "product_desc", "created_at", "updated_at", "product_img_src", "version", "deleted_at", "status", "publish_at", "unpublish_at", "product_type", "attributes", "tags", "slug"}).
+		AddRow("1", "product 1", "description 1", now, now, "img_src_1", 1, nil, "published", now, nil, "", []byte("{}"), []byte("{summer}"), "product-1").
+		AddRow("2", "product 2", "description 2", now, now, "img_src_2", 1, nil, "published", now, nil, "", []byte("{}"), []byte("{}"), "product-2")
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`DECLARE product_export NO SCROLL CURSOR FOR SELECT (.+) FROM products WHERE deleted_at IS NULL AND status = \$1 ORDER BY created_at ASC, id ASC`).
+		WithArgs(domain.ProductPublished).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(`FETCH FORWARD 2 FROM product_export`).WillReturnRows(rows)
+	mock.ExpectQuery(`FETCH FORWARD 2 FROM product_export`).WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	mock.ExpectRollback()
+
+	a := repositories.NewProductDBRepository(db)
+	export, err := a.Export(context.TODO(), domain.ProductFilter{PublishedOnly: true}, 2)
+	assert.NoError(t, err)
+
+	list, err := export.Next(context.TODO())
+	assert.NoError(t, err)
+	assert.Len(t, list, 2)
+	assert.Equal(t, []string{"summer"}, list[0].Tags)
+
+	list, err = export.Next(context.TODO())
+	assert.NoError(t, err)
+	assert.Len(t, list, 0)
+
+	assert.NoError(t, export.Close())
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestFetchRepositoryProductByTags(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	now := time.Now()
+
+	rows := sqlmock.NewRows([]string{"id", "product_name", "product_desc", "created_at", "updated_at", "product_img_src", "version", "deleted_at", "status", "publish_at", "unpublish_at", "product_type", "attributes", "tags", "slug"}).
+		AddRow("1", "product 1", "description 1", now, now, "img_src_1", 1, nil, "published", now, nil, "", []byte("{}"), []byte("{summer,cotton}"), "product-1")
+
+	where := `WHERE deleted_at IS NULL AND tags @> \$1`
+	mock.ExpectQuery(`FROM products `+where+` ORDER BY created_at ASC, id ASC LIMIT \$2 OFFSET \$3`).
+		WithArgs("{\"summer\",\"cotton\"}", 11, 0).WillReturnRows(rows)
+	mock.ExpectQuery(`SELECT count\(\*\) FROM products ` + where).
+		WithArgs("{\"summer\",\"cotton\"}").WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+
+	a := repositories.NewProductDBRepository(db)
+	list, _, err := a.Fetch(context.TODO(), domain.ProductFilter{Tags: []string{"summer", "cotton"}, TagsMode: domain.TagsAll}, pkg.Pagination{Limit: 10, Page: 1})
+	assert.NoError(t, err)
+	assert.Len(t, list, 1)
+	assert.Equal(t, []string{"summer", "cotton"}, list[0].Tags)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestFetchTagsRepositoryProduct(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	rows := sqlmock.NewRows([]string{"t", "count"}).AddRow("summer", 3).AddRow("cotton", 1)
+	mock.ExpectQuery(`SELECT t, count\(\*\) FROM products, unnest\(tags\) AS t WHERE deleted_at IS NULL GROUP BY t`).WillReturnRows(rows)
+
+	a := repositories.NewProductDBRepository(db)
+	list, err := a.FetchTags(context.TODO())
+	assert.NoError(t, err)
+	assert.Equal(t, []domain.ProductTag{{Tag: "summer", Count: 3}, {Tag: "cotton", Count: 1}}, list)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRenameTagsRepositoryProduct(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	now := time.Now()
+
+	rows := sqlmock.NewRows([]string{"id", "product_name", "product_desc", "created_at", "updated_at", "product_img_src", "version", "deleted_at", "status", "publish_at", "unpublish_at", "product_type", "attributes", "tags", "slug", "old_tags"}).
+		AddRow("1", "product 1", "description 1", now, now, "img_src_1", 3, nil, "published", nil, nil, "", []byte("{}"), []byte("{tshirt,summer}"), "product-1", []byte("{tee,summer,t-shirt}")).
+		AddRow("2", "product 2", "description 2", now, now, "img_src_2", 2, nil, "published", nil, nil, "", []byte("{}"), []byte("{tshirt}"), "product-2", []byte("{tee}"))
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`UPDATE products SET tags = ARRAY\((.|\n)*\), updated_at=\$3 , version=version\+1\s+FROM \(SELECT id AS old_id, tags AS old_tags FROM products WHERE tags && \$1 FOR UPDATE\) old\s+WHERE id = old.old_id RETURNING (.+), old.old_tags`).
+		WithArgs("{\"tee\",\"t-shirt\"}", "tshirt", now).WillReturnRows(rows)
+	mock.ExpectExec(`INSERT INTO product_events`).WithArgs("1", domain.ProductUpdated, sqlmock.AnyArg(), now).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`INSERT INTO product_revisions`).WithArgs("1", 3, domain.RevisionUpdate, "", []byte(`["tags"]`), sqlmock.AnyArg(), now).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`INSERT INTO product_events`).WithArgs("2", domain.ProductUpdated, sqlmock.AnyArg(), now).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`INSERT INTO product_revisions`).WithArgs("2", 2, domain.RevisionUpdate, "", []byte(`["tags"]`), sqlmock.AnyArg(), now).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	a := repositories.NewProductDBRepository(db)
+	changed, err := a.RenameTags(context.TODO(), domain.TagRename{From: []string{"tee", "t-shirt"}, To: "tshirt"}, now)
+	assert.NoError(t, err)
+	assert.Len(t, changed, 2)
+	assert.Equal(t, []string{"tshirt", "summer"}, changed[0].Tags)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetBySlugRepositoryProduct(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	now := time.Now()
+
+	rows := sqlmock.NewRows([]string{"id", "product_name", "product_desc", "created_at", "updated_at", "product_img_src", "version", "deleted_at", "status", "publish_at", "unpublish_at", "product_type", "attributes", "tags", "slug"}).
+		AddRow("1", "blue cotton shirt", "description 1", now, now, "img_src_1", 2, nil, "published", now, nil, "", []byte("{}"), []byte("{}"), "blue-cotton-shirt")
+
+	mock.ExpectQuery(`from products WHERE id=\(SELECT product_id FROM product_slugs WHERE slug=\$1\) AND deleted_at IS NULL`).
+		WithArgs("blue-shirt").WillReturnRows(rows)
+
+	a := repositories.NewProductDBRepository(db)
+	prd, err := a.GetBySlug(context.TODO(), "blue-shirt", domain.ProductFilter{})
+	assert.NoError(t, err)
+	assert.Equal(t, "blue-cotton-shirt", prd.Slug)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,170 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+
+	"github.com/fahmilukis/go-product-svc/domain"
+	pkg "github.com/fahmilukis/go-product-svc/pkg/utils"
+	"github.com/sirupsen/logrus"
+)
+
+type productRevisionDBRepositories struct {
+	Conn *sql.DB
+}
+
+func NewProductRevisionDBRepository(conn *sql.DB) *productRevisionDBRepositories {
+	return &productRevisionDBRepositories{Conn: conn}
+}
+
+// fetch to DB
+func (p *productRevisionDBRepositories) fetch(ctx context.Context, query string, args ...interface{}) (res []domain.ProductRevision, err error) {
+	rows, err := p.Conn.QueryContext(ctx, query, args...)
+	if err != nil {
+		logrus.Error(err)
+		return nil, err
+	}
+
+	defer func() {
+		errRow := rows.Close()
+		if errRow != nil {
+			logrus.Error(err)
+		}
+	}()
+
+	res = make([]domain.ProductRevision, 0)
+	for rows.Next() {
+		rev := domain.ProductRevision{}
+		var changedFields, snapshot []byte
+		err = rows.Scan(
+			&rev.ProductID,
+			&rev.Revision,
+			&rev.Action,
+			&rev.Actor,
+			&changedFields,
+			&snapshot,
+			&rev.CreatedAt,
+		)
+		if err != nil {
+			logrus.Error(err)
+			return nil, err
+		}
+		if err = json.Unmarshal(changedFields, &rev.ChangedFields); err != nil {
+			logrus.Error(err)
+			return nil, err
+		}
+		if err = json.Unmarshal(snapshot, &rev.Snapshot); err != nil {
+			logrus.Error(err)
+			return nil, err
+		}
+		res = append(res, rev)
+	}
+
+	return res, nil
+}
+
+func (p *productRevisionDBRepositories) Fetch(ctx context.Context, productID string, pagination pkg.Pagination) (res []domain.ProductRevision, nextPagination pkg.Pagination, err error) {
+	if !pagination.Valid() {
+		return nil, pkg.Pagination{}, domain.ErrBadParamInput
+	}
+
+	query := `SELECT product_id,revision,action,actor,changed_fields,snapshot,created_at
+	FROM product_revisions WHERE product_id=$1 ORDER BY revision DESC LIMIT $2 OFFSET $3`
+
+	limit, offset := pagination.GetLimit(), pagination.GetOffset()
+	res, err = p.fetch(ctx, query, productID, limit, offset)
+	if err != nil {
+		return nil, pkg.Pagination{}, err
+	}
+
+	var total int64
+	err = p.Conn.QueryRowContext(ctx, `SELECT count(*) FROM product_revisions WHERE product_id=$1`, productID).Scan(&total)
+	if err != nil {
+		return nil, pkg.Pagination{}, err
+	}
+	nextPagination.Limit = limit
+	nextPagination.Page = pagination.GetPage()
+	nextPagination.Count = pkg.CountExact
+	nextPagination.SetTotal(total)
+	nextPagination.HasNext = int64(offset+len(res)) < total
+	nextPagination.HasPrev = offset > 0
+
+	return
+}
+
+func (p *productRevisionDBRepositories) GetByRevision(ctx context.Context, productID string, rev int64) (res domain.ProductRevision, err error) {
+	query := `SELECT product_id,revision,action,actor,changed_fields,snapshot,created_at
+	FROM product_revisions WHERE product_id=$1 AND revision=$2`
+	list, err := p.fetch(ctx, query, productID, rev)
+	if err != nil {
+		return domain.ProductRevision{}, err
+	}
+
+	if len(list) > 0 {
+		res = list[0]
+	} else {
+		return res, domain.ErrNotFound
+	}
+
+	return
+}
+
+// Store appends a revision, revisions are never updated once written.
+func (p *productRevisionDBRepositories) Store(ctx context.Context, rev *domain.ProductRevision) (err error) {
+	return insertRevision(ctx, p.Conn, rev)
+}
+
+// storeRevision appends the revision of a product write in the transaction
+// of the write, the snapshot is the product as written and the changed
+// fields the ones it differs from prev in.
+func storeRevision(ctx context.Context, tx *sql.Tx, action domain.RevisionAction, prev, cur domain.Products) error {
+	changes, err := domain.DiffProducts(prev, cur)
+	if err != nil {
+		return err
+	}
+	changedFields := make([]string, 0, len(changes))
+	for _, change := range changes {
+		changedFields = append(changedFields, change.Field)
+	}
+
+	return insertRevision(ctx, tx, &domain.ProductRevision{
+		ProductID:     cur.ID,
+		Revision:      cur.Version,
+		Action:        action,
+		Actor:         domain.ActorFromContext(ctx),
+		ChangedFields: changedFields,
+		Snapshot:      cur,
+		CreatedAt:     cur.UpdatedAt,
+	})
+}
+
+// execer is a connection or a transaction.
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
+func insertRevision(ctx context.Context, db execer, rev *domain.ProductRevision) (err error) {
+	query := `INSERT INTO product_revisions (product_id,revision,action,actor,changed_fields,snapshot,created_at)
+	VALUES ($1, $2, $3, $4, $5, $6, $7) ON CONFLICT (product_id, revision) DO NOTHING`
+
+	changedFields, err := json.Marshal(rev.ChangedFields)
+	if err != nil {
+		return
+	}
+	snapshot, err := json.Marshal(rev.Snapshot)
+	if err != nil {
+		return
+	}
+
+	_, err = db.ExecContext(ctx, query,
+		rev.ProductID,
+		rev.Revision,
+		rev.Action,
+		rev.Actor,
+		changedFields,
+		snapshot,
+		rev.CreatedAt,
+	)
+	return
+}
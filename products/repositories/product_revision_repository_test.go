@@ -0,0 +1,80 @@
+package repositories_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/fahmilukis/go-product-svc/domain"
+	"github.com/fahmilukis/go-product-svc/products/repositories"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/DATA-DOG/go-sqlmock.v1"
+)
+
+func TestInsertRepositoryProductRevision(t *testing.T) {
+	now := time.Now()
+	rev := &domain.ProductRevision{
+		ProductID:     "12",
+		Revision:      2,
+		Action:        domain.RevisionUpdate,
+		Actor:         "admin",
+		ChangedFields: []string{"desc"},
+		Snapshot:      domain.Products{ID: "12", Name: "product 1", Description: "new desc", Version: 2},
+		CreatedAt:     now,
+	}
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	query := `INSERT INTO product_revisions \(product_id,revision,action,actor,changed_fields,snapshot,created_at\)
+	VALUES \(\$1, \$2, \$3, \$4, \$5, \$6, \$7\) ON CONFLICT \(product_id, revision\) DO NOTHING`
+	mock.ExpectExec(query).
+		WithArgs(rev.ProductID, rev.Revision, rev.Action, rev.Actor, []byte(`["desc"]`), sqlmock.AnyArg(), rev.CreatedAt).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	a := repositories.NewProductRevisionDBRepository(db)
+
+	err = a.Store(context.TODO(), rev)
+	assert.NoError(t, err)
+}
+
+func TestGetByRevisionRepositoryProductRevision(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	now := time.Now()
+
+	rows := sqlmock.NewRows([]string{"product_id", "revision", "action", "actor", "changed_fields", "snapshot", "created_at"}).
+		AddRow("12", 2, "update", "admin", []byte(`["desc"]`), []byte(`{"id":"12","name":"product 1","desc":"new desc","version":2}`), now)
+
+	query := `SELECT product_id,revision,action,actor,changed_fields,snapshot,created_at
+	FROM product_revisions WHERE product_id=\$1 AND revision=\$2`
+	mock.ExpectQuery(query).WithArgs("12", 2).WillReturnRows(rows)
+
+	a := repositories.NewProductRevisionDBRepository(db)
+
+	rev, err := a.GetByRevision(context.TODO(), "12", 2)
+	assert.NoError(t, err)
+	assert.Equal(t, domain.RevisionUpdate, rev.Action)
+	assert.Equal(t, []string{"desc"}, rev.ChangedFields)
+	assert.Equal(t, "new desc", rev.Snapshot.Description)
+	assert.Equal(t, int64(2), rev.Snapshot.Version)
+}
+
+func TestGetByRevisionRepositoryProductRevisionNotFound(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	query := `FROM product_revisions WHERE product_id=\$1 AND revision=\$2`
+	mock.ExpectQuery(query).WithArgs("12", 9).
+		WillReturnRows(sqlmock.NewRows([]string{"product_id", "revision", "action", "actor", "changed_fields", "snapshot", "created_at"}))
+
+	a := repositories.NewProductRevisionDBRepository(db)
+
+	_, err = a.GetByRevision(context.TODO(), "12", 9)
+	assert.Equal(t, domain.ErrNotFound, err)
+}
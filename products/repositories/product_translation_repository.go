@@ -0,0 +1,94 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/fahmilukis/go-product-svc/domain"
+	pkg "github.com/fahmilukis/go-product-svc/pkg/utils"
+	"github.com/lib/pq"
+	"github.com/sirupsen/logrus"
+)
+
+const translationColumns = `product_id,locale,name,description,updated_at`
+
+type productTranslationDBRepositories struct {
+	Conn *sql.DB
+}
+
+func NewProductTranslationDBRepository(conn *sql.DB) *productTranslationDBRepositories {
+	return &productTranslationDBRepositories{Conn: conn}
+}
+
+// fetch to DB
+func (p *productTranslationDBRepositories) fetch(ctx context.Context, query string, args ...interface{}) (res []domain.ProductTranslation, err error) {
+	rows, err := p.Conn.QueryContext(ctx, query, args...)
+	if err != nil {
+		logrus.Error(err)
+		return nil, err
+	}
+
+	defer func() {
+		errRow := rows.Close()
+		if errRow != nil {
+			logrus.Error(err)
+		}
+	}()
+
+	res = make([]domain.ProductTranslation, 0)
+	for rows.Next() {
+		t := domain.ProductTranslation{}
+		err = rows.Scan(
+			&t.ProductID,
+			&t.Locale,
+			&t.Name,
+			&t.Description,
+			&t.UpdatedAt,
+		)
+		if err != nil {
+			logrus.Error(err)
+			return nil, err
+		}
+		res = append(res, t)
+	}
+
+	return res, nil
+}
+
+func (p *productTranslationDBRepositories) Fetch(ctx context.Context, productID string) (res []domain.ProductTranslation, err error) {
+	query := `SELECT ` + translationColumns + ` FROM product_translations WHERE product_id = $1 ORDER BY locale`
+	return p.fetch(ctx, query, productID)
+}
+
+func (p *productTranslationDBRepositories) FetchFor(ctx context.Context, productIDs []string, locales []string) (res []domain.ProductTranslation, err error) {
+	query := `SELECT ` + translationColumns + ` FROM product_translations WHERE product_id = ANY($1) AND locale = ANY($2)`
+	return p.fetch(ctx, query, pq.Array(productIDs), pq.Array(locales))
+}
+
+func (p *productTranslationDBRepositories) Upsert(ctx context.Context, t *domain.ProductTranslation) (err error) {
+	query := `INSERT INTO product_translations (` + translationColumns + `) VALUES ($1, $2, $3, $4, $5)
+	ON CONFLICT (product_id, locale) DO UPDATE SET name = EXCLUDED.name, description = EXCLUDED.description, updated_at = EXCLUDED.updated_at`
+
+	_, err = p.Conn.ExecContext(ctx, query, t.ProductID, t.Locale, t.Name, t.Description, t.UpdatedAt)
+	if pkg.IsForeignKeyViolation(err) {
+		return domain.ErrNotFound
+	}
+	return
+}
+
+func (p *productTranslationDBRepositories) Delete(ctx context.Context, productID, locale string) (err error) {
+	res, err := p.Conn.ExecContext(ctx, `DELETE FROM product_translations WHERE product_id = $1 AND locale = $2`, productID, locale)
+	if err != nil {
+		return
+	}
+
+	rowsAfected, err := res.RowsAffected()
+	if err != nil {
+		return
+	}
+	if rowsAfected == 0 {
+		return domain.ErrNotFound
+	}
+
+	return
+}
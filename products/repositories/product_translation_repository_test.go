@@ -0,0 +1,79 @@
+package repositories_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/fahmilukis/go-product-svc/domain"
+	"github.com/fahmilukis/go-product-svc/products/repositories"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/DATA-DOG/go-sqlmock.v1"
+)
+
+func TestFetchForRepositoryProductTranslation(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	now := time.Now()
+
+	rows := sqlmock.NewRows([]string{"product_id", "locale", "name", "description", "updated_at"}).
+		AddRow("1", "id", "kemeja katun biru", "kemeja", now).
+		AddRow("2", "id-ID", "kaos", "kaos polos", now)
+
+	mock.ExpectQuery(`SELECT product_id,locale,name,description,updated_at FROM product_translations WHERE product_id = ANY\(\$1\) AND locale = ANY\(\$2\)`).
+		WithArgs(`{"1","2"}`, `{"id-ID","id"}`).WillReturnRows(rows)
+
+	a := repositories.NewProductTranslationDBRepository(db)
+	list, err := a.FetchFor(context.TODO(), []string{"1", "2"}, []string{"id-ID", "id"})
+	assert.NoError(t, err)
+	assert.Len(t, list, 2)
+	assert.Equal(t, "kemeja katun biru", list[0].Name)
+	assert.Equal(t, "id-ID", list[1].Locale)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUpsertRepositoryProductTranslation(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	tr := &domain.ProductTranslation{ProductID: "1", Locale: "id", Name: "kemeja", Description: "kemeja katun", UpdatedAt: time.Now()}
+
+	mock.ExpectExec(`INSERT INTO product_translations \(product_id,locale,name,description,updated_at\) VALUES \(\$1, \$2, \$3, \$4, \$5\)\s+ON CONFLICT \(product_id, locale\) DO UPDATE`).
+		WithArgs(tr.ProductID, tr.Locale, tr.Name, tr.Description, tr.UpdatedAt).WillReturnResult(sqlmock.NewResult(0, 1))
+
+	a := repositories.NewProductTranslationDBRepository(db)
+	assert.NoError(t, a.Upsert(context.TODO(), tr))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUpsertRepositoryProductTranslationUnknownProduct(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	tr := &domain.ProductTranslation{ProductID: "404", Locale: "id", Name: "kemeja", UpdatedAt: time.Now()}
+
+	mock.ExpectExec(`INSERT INTO product_translations`).WillReturnError(&pq.Error{Code: "23503"})
+
+	a := repositories.NewProductTranslationDBRepository(db)
+	assert.Equal(t, domain.ErrNotFound, a.Upsert(context.TODO(), tr))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDeleteRepositoryProductTranslationNotFound(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	mock.ExpectExec(`DELETE FROM product_translations WHERE product_id = \$1 AND locale = \$2`).
+		WithArgs("1", "fr").WillReturnResult(sqlmock.NewResult(0, 0))
+
+	a := repositories.NewProductTranslationDBRepository(db)
+	assert.Equal(t, domain.ErrNotFound, a.Delete(context.TODO(), "1", "fr"))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
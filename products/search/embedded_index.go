@@ -0,0 +1,633 @@
+package search
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"html"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+
+	"github.com/fahmilukis/go-product-svc/domain"
+	pkg "github.com/fahmilukis/go-product-svc/pkg/utils"
+)
+
+const (
+	nameWeight = 2.0
+	descWeight = 1.0
+
+	exactScore  = 1.0
+	prefixScore = 0.8
+	fuzzyScore  = 0.6
+
+	// maxEdits is the largest typo tolerance of allowedEdits, the terms are
+	// indexed with up to that many runes deleted.
+	maxEdits = 2
+	// maxFuzzyLen bounds the terms looked up by edit distance, longer ones
+	// only match exactly or as a prefix.
+	maxFuzzyLen = 24
+	// compactMinLines keeps small logs from being compacted on every write.
+	compactMinLines = 1024
+)
+
+const (
+	opIndex  = "index"
+	opRemove = "remove"
+)
+
+// logEntry is a line of the index file.
+type logEntry struct {
+	Op      string           `json:"op"`
+	Product *domain.Products `json:"product,omitempty"`
+	ID      string           `json:"id,omitempty"`
+}
+
+// embeddedIndex is an in-process inverted index over the product name and
+// description. Query terms match exactly, as a prefix or within a small edit
+// distance so typos still find the product.
+//
+// Every write is appended to a log on local disk that is replayed on start,
+// the log is compacted once it holds twice as many lines as products. Only
+// live products are indexed and the index keeps no categories or attributes,
+// so the filters it can't answer fail with domain.ErrBadParamInput instead of
+// being ignored.
+type embeddedIndex struct {
+	mu       sync.RWMutex
+	path     string
+	log      *os.File
+	logLines int
+	docs     map[string]domain.Products
+	postings map[string]map[string]float64
+	// terms is the sorted vocabulary the prefixes are looked up in, it is
+	// sorted again on the next search once terms were added or removed.
+	terms      []string
+	termsDirty bool
+	// deletes maps a term with up to maxEdits runes deleted to the terms it
+	// comes from, a query term only has to be compared to the terms sharing
+	// one of its deletes.
+	deletes map[string][]string
+}
+
+func NewEmbeddedIndex(path string) (domain.ProductSearchIndex, error) {
+	idx := &embeddedIndex{
+		path:     path,
+		docs:     map[string]domain.Products{},
+		postings: map[string]map[string]float64{},
+		deletes:  map[string][]string{},
+	}
+	if err := idx.load(); err != nil {
+		return nil, err
+	}
+
+	return idx, nil
+}
+
+func (e *embeddedIndex) Index(ctx context.Context, products ...domain.Products) error {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	for i := range products {
+		if err := enc.Encode(logEntry{Op: opIndex, Product: &products[i]}); err != nil {
+			return err
+		}
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if err := e.append(buf.Bytes(), len(products)); err != nil {
+		return err
+	}
+	for _, p := range products {
+		e.remove(p.ID)
+		e.add(p)
+	}
+
+	return e.compactIfNeeded()
+}
+
+func (e *embeddedIndex) Remove(ctx context.Context, id string) error {
+	line, err := json.Marshal(logEntry{Op: opRemove, ID: id})
+	if err != nil {
+		return err
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if err := e.append(append(line, '\n'), 1); err != nil {
+		return err
+	}
+	e.remove(id)
+
+	return e.compactIfNeeded()
+}
+
+func (e *embeddedIndex) Reset(ctx context.Context) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.docs = map[string]domain.Products{}
+	e.postings = map[string]map[string]float64{}
+	e.deletes = map[string][]string{}
+	e.terms = nil
+	e.termsDirty = false
+
+	return e.compact()
+}
+
+func (e *embeddedIndex) Search(ctx context.Context, query string, f domain.ProductFilter, pagination pkg.Pagination) (res []domain.ProductSearchHit, nextPagination pkg.Pagination, err error) {
+	queryTerms := tokenize(query)
+	if len(queryTerms) == 0 {
+		return nil, pkg.Pagination{}, domain.ErrBadParamInput
+	}
+	if !pagination.Valid() {
+		return nil, pkg.Pagination{}, domain.ErrBadParamInput
+	}
+	if err = checkFilter(f); err != nil {
+		return nil, pkg.Pagination{}, err
+	}
+
+	e.rlockSorted()
+	defer e.mu.RUnlock()
+
+	// every query term has to match, a document scores the best match of
+	// each term weighted by the field it was found in. The filter is checked
+	// once per document before it is scored.
+	var scores map[string]float64
+	matched := map[string]bool{}
+	kept := map[string]bool{}
+	for _, qt := range queryTerms {
+		best := map[string]float64{}
+		for term, s := range e.matchTerms(qt) {
+			matched[term] = true
+			for id, w := range e.postings[term] {
+				keep, ok := kept[id]
+				if !ok {
+					keep = matchFilter(e.docs[id], f)
+					kept[id] = keep
+				}
+				if keep && s*w > best[id] {
+					best[id] = s * w
+				}
+			}
+		}
+
+		if scores == nil {
+			scores = best
+			continue
+		}
+		for id := range scores {
+			if b, ok := best[id]; ok {
+				scores[id] += b
+			} else {
+				delete(scores, id)
+			}
+		}
+	}
+
+	hits := make([]domain.ProductSearchHit, 0, len(scores))
+	for id, score := range scores {
+		doc := e.docs[id]
+		hits = append(hits, domain.ProductSearchHit{
+			Products: doc,
+			Rank:     score,
+			Highlights: domain.ProductHighlights{
+				Name:        highlight(doc.Name, matched),
+				Description: highlight(doc.Description, matched),
+			},
+		})
+	}
+	sort.Slice(hits, func(i, j int) bool {
+		if hits[i].Rank != hits[j].Rank {
+			return hits[i].Rank > hits[j].Rank
+		}
+		return hits[i].CreatedAt.Before(hits[j].CreatedAt)
+	})
+
+	limit, offset := pagination.GetLimit(), pagination.GetOffset()
+	total := len(hits)
+	if offset > total {
+		offset = total
+	}
+	end := offset + limit
+	if end > total {
+		end = total
+	}
+
+	res = hits[offset:end]
+	nextPagination.Limit = limit
+	nextPagination.Page = pagination.GetPage()
+	nextPagination.Count = pkg.CountExact
+	nextPagination.SetTotal(int64(total))
+	nextPagination.HasNext = end < total
+	nextPagination.HasPrev = offset > 0
+
+	return
+}
+
+// checkFilter rejects the filters that need data the index doesn't keep.
+func checkFilter(f domain.ProductFilter) error {
+	if f.CategoryID != "" || len(f.Attributes) > 0 || len(f.Filters) > 0 {
+		return domain.ErrBadParamInput
+	}
+	switch f.TagsMode {
+	case "", domain.TagsAny, domain.TagsAll:
+		return nil
+	default:
+		return domain.ErrBadParamInput
+	}
+}
+
+// matchFilter tells whether an indexed product passes the filter, the index
+// only holds live products so none of them is in the trash.
+func matchFilter(p domain.Products, f domain.ProductFilter) bool {
+	if f.OnlyDeleted {
+		return false
+	}
+	if f.PublishedOnly && p.Status != domain.ProductPublished {
+		return false
+	}
+	if len(f.Tags) == 0 {
+		return true
+	}
+
+	tags := make(map[string]bool, len(p.Tags))
+	for _, t := range p.Tags {
+		tags[t] = true
+	}
+	for _, t := range f.Tags {
+		if tags[t] && f.TagsMode != domain.TagsAll {
+			return true
+		}
+		if !tags[t] && f.TagsMode == domain.TagsAll {
+			return false
+		}
+	}
+
+	return f.TagsMode == domain.TagsAll
+}
+
+// matchTerms returns the indexed terms matching a query term with their
+// score.
+func (e *embeddedIndex) matchTerms(query string) map[string]float64 {
+	res := map[string]float64{}
+	if _, ok := e.postings[query]; ok {
+		res[query] = exactScore
+	}
+
+	for i := sort.SearchStrings(e.terms, query); i < len(e.terms) && strings.HasPrefix(e.terms[i], query); i++ {
+		if _, ok := e.postings[e.terms[i]]; ok && e.terms[i] != query {
+			res[e.terms[i]] = prefixScore
+		}
+	}
+
+	q := []rune(query)
+	edits := allowedEdits(len(q))
+	if edits == 0 || len(q) > maxFuzzyLen+edits {
+		return res
+	}
+	for _, d := range deletions(query, edits) {
+		for _, term := range e.deletes[d] {
+			if _, ok := res[term]; ok {
+				continue
+			}
+			if dist := levenshtein(q, []rune(term)); dist <= edits {
+				res[term] = fuzzyScore / float64(dist)
+			}
+		}
+	}
+
+	return res
+}
+
+// rlockSorted read locks the index with the vocabulary sorted.
+func (e *embeddedIndex) rlockSorted() {
+	for {
+		e.mu.RLock()
+		if !e.termsDirty {
+			return
+		}
+		e.mu.RUnlock()
+
+		e.mu.Lock()
+		if e.termsDirty {
+			e.sortTerms()
+		}
+		e.mu.Unlock()
+	}
+}
+
+// sortTerms drops the removed and repeated terms and sorts the rest.
+func (e *embeddedIndex) sortTerms() {
+	sort.Strings(e.terms)
+	terms := e.terms[:0]
+	for i, term := range e.terms {
+		if _, ok := e.postings[term]; !ok || (i > 0 && e.terms[i-1] == term) {
+			continue
+		}
+		terms = append(terms, term)
+	}
+	e.terms = terms
+	e.termsDirty = false
+}
+
+func (e *embeddedIndex) add(p domain.Products) {
+	e.docs[p.ID] = p
+	for _, term := range tokenize(p.Name) {
+		e.post(term, p.ID, nameWeight)
+	}
+	for _, term := range tokenize(p.Description) {
+		e.post(term, p.ID, descWeight)
+	}
+}
+
+func (e *embeddedIndex) post(term, id string, weight float64) {
+	docs, ok := e.postings[term]
+	if !ok {
+		docs = map[string]float64{}
+		e.postings[term] = docs
+		e.terms = append(e.terms, term)
+		e.termsDirty = true
+		if len([]rune(term)) <= maxFuzzyLen {
+			for _, d := range deletions(term, maxEdits) {
+				e.deletes[d] = append(e.deletes[d], term)
+			}
+		}
+	}
+	docs[id] += weight
+}
+
+func (e *embeddedIndex) remove(id string) {
+	doc, ok := e.docs[id]
+	if !ok {
+		return
+	}
+
+	for _, term := range append(tokenize(doc.Name), tokenize(doc.Description)...) {
+		docs, ok := e.postings[term]
+		if !ok {
+			continue
+		}
+		delete(docs, id)
+		if len(docs) > 0 {
+			continue
+		}
+
+		delete(e.postings, term)
+		e.termsDirty = true
+		if len([]rune(term)) <= maxFuzzyLen {
+			for _, d := range deletions(term, maxEdits) {
+				e.unlinkDelete(d, term)
+			}
+		}
+	}
+	delete(e.docs, id)
+}
+
+func (e *embeddedIndex) unlinkDelete(d, term string) {
+	terms := e.deletes[d]
+	for i, t := range terms {
+		if t == term {
+			terms = append(terms[:i], terms[i+1:]...)
+			break
+		}
+	}
+	if len(terms) == 0 {
+		delete(e.deletes, d)
+	} else {
+		e.deletes[d] = terms
+	}
+}
+
+// load replays the log and opens it for appending. A torn last line, left
+// by a crash in the middle of a write, is cut off.
+func (e *embeddedIndex) load() error {
+	if err := os.MkdirAll(filepath.Dir(e.path), 0o755); err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(e.path, os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return err
+	}
+
+	var size int64
+	r := bufio.NewReader(f)
+	for {
+		line, err := r.ReadBytes('\n')
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			f.Close()
+			return err
+		}
+		size += int64(len(line))
+
+		var entry logEntry
+		if err := json.Unmarshal(line, &entry); err != nil {
+			f.Close()
+			return fmt.Errorf("search index %s: %w", e.path, err)
+		}
+		switch {
+		case entry.Op == opIndex && entry.Product != nil:
+			e.remove(entry.Product.ID)
+			e.add(*entry.Product)
+		case entry.Op == opRemove:
+			e.remove(entry.ID)
+		default:
+			f.Close()
+			return fmt.Errorf("search index %s: unknown entry %q", e.path, entry.Op)
+		}
+		e.logLines++
+	}
+
+	if err := f.Truncate(size); err != nil {
+		f.Close()
+		return err
+	}
+	if _, err := f.Seek(size, io.SeekStart); err != nil {
+		f.Close()
+		return err
+	}
+	e.log = f
+
+	return nil
+}
+
+func (e *embeddedIndex) append(lines []byte, n int) error {
+	if _, err := e.log.Write(lines); err != nil {
+		return err
+	}
+	e.logLines += n
+
+	return nil
+}
+
+func (e *embeddedIndex) compactIfNeeded() error {
+	if e.logLines < compactMinLines || e.logLines <= 2*len(e.docs) {
+		return nil
+	}
+	return e.compact()
+}
+
+// compact rewrites the log with a line per indexed product. It writes to a
+// temporary file first so a crash never leaves a half written log behind.
+func (e *embeddedIndex) compact() error {
+	dir := filepath.Dir(e.path)
+	tmp, err := os.CreateTemp(dir, filepath.Base(e.path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	w := bufio.NewWriter(tmp)
+	enc := json.NewEncoder(w)
+	for id := range e.docs {
+		p := e.docs[id]
+		if err := enc.Encode(logEntry{Op: opIndex, Product: &p}); err != nil {
+			tmp.Close()
+			return err
+		}
+	}
+	if err := w.Flush(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), e.path); err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(e.path, os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	e.log.Close()
+	e.log = f
+	e.logLines = len(e.docs)
+
+	return nil
+}
+
+func tokenize(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), isSeparator)
+}
+
+func isSeparator(r rune) bool {
+	return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+}
+
+// deletions returns the term with up to edits runes deleted, the term
+// itself included. Two terms within edits of each other share one of them.
+func deletions(term string, edits int) []string {
+	seen := map[string]bool{term: true}
+	res := []string{term}
+	level := []string{term}
+	for i := 0; i < edits; i++ {
+		var next []string
+		for _, w := range level {
+			r := []rune(w)
+			if len(r) <= 1 {
+				continue
+			}
+			for j := range r {
+				d := string(r[:j]) + string(r[j+1:])
+				if !seen[d] {
+					seen[d] = true
+					next = append(next, d)
+				}
+			}
+		}
+		res = append(res, next...)
+		level = next
+	}
+
+	return res
+}
+
+// allowedEdits grows the typo tolerance with the length of the term, short
+// terms have to match exactly.
+func allowedEdits(n int) int {
+	switch {
+	case n >= 8:
+		return 2
+	case n >= 4:
+		return 1
+	default:
+		return 0
+	}
+}
+
+func levenshtein(a, b []rune) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = minInt(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+
+	return prev[len(b)]
+}
+
+// highlight wraps the words of text that matched the query in <mark> tags,
+// the text itself is escaped for HTML.
+func highlight(text string, matched map[string]bool) string {
+	var b strings.Builder
+	word := make([]rune, 0)
+
+	flush := func() {
+		if len(word) == 0 {
+			return
+		}
+		w := string(word)
+		if matched[strings.ToLower(w)] {
+			b.WriteString("<mark>" + html.EscapeString(w) + "</mark>")
+		} else {
+			b.WriteString(html.EscapeString(w))
+		}
+		word = word[:0]
+	}
+
+	for _, r := range text {
+		if isSeparator(r) {
+			flush()
+			b.WriteString(html.EscapeString(string(r)))
+			continue
+		}
+		word = append(word, r)
+	}
+	flush()
+
+	return b.String()
+}
+
+func minInt(values ...int) int {
+	m := values[0]
+	for _, v := range values[1:] {
+		if v < m {
+			m = v
+		}
+	}
+	return m
+}
@@ -0,0 +1,135 @@
+package search_test
+
+import (
+	"bufio"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/fahmilukis/go-product-svc/domain"
+	pkg "github.com/fahmilukis/go-product-svc/pkg/utils"
+	"github.com/fahmilukis/go-product-svc/products/search"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestIndex(t *testing.T, path string) domain.ProductSearchIndex {
+	idx, err := search.NewEmbeddedIndex(path)
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening the index", err)
+	}
+	return idx
+}
+
+func TestEmbeddedIndexSearch(t *testing.T) {
+	idx := newTestIndex(t, filepath.Join(t.TempDir(), "index.jsonl"))
+	now := time.Now()
+
+	err := idx.Index(context.TODO(),
+		domain.Products{ID: "1", Name: "Blue Cotton Shirt", Description: "soft <b>shirt</b> for summer", CreatedAt: now},
+		domain.Products{ID: "2", Name: "Red Wool Sweater", Description: "warm sweater, goes well with a shirt", CreatedAt: now},
+	)
+	assert.NoError(t, err)
+
+	hits, nextPg, err := idx.Search(context.TODO(), "shirt", domain.ProductFilter{}, pkg.Pagination{})
+	assert.NoError(t, err)
+	assert.Len(t, hits, 2)
+	assert.Equal(t, "1", hits[0].ID)
+	assert.Equal(t, int64(2), nextPg.TotalRows)
+	assert.Equal(t, "Blue Cotton <mark>Shirt</mark>", hits[0].Highlights.Name)
+	assert.Equal(t, "soft &lt;b&gt;<mark>shirt</mark>&lt;/b&gt; for summer", hits[0].Highlights.Description)
+
+	// typo in "cotton" and a prefix of "shirt"
+	hits, _, err = idx.Search(context.TODO(), "coton shi", domain.ProductFilter{}, pkg.Pagination{})
+	assert.NoError(t, err)
+	assert.Len(t, hits, 1)
+	assert.Equal(t, "1", hits[0].ID)
+
+	hits, _, err = idx.Search(context.TODO(), "leather", domain.ProductFilter{}, pkg.Pagination{})
+	assert.NoError(t, err)
+	assert.Len(t, hits, 0)
+}
+
+func TestEmbeddedIndexRemoveAndReload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "index.jsonl")
+	idx := newTestIndex(t, path)
+
+	assert.NoError(t, idx.Index(context.TODO(),
+		domain.Products{ID: "1", Name: "Blue Cotton Shirt"},
+		domain.Products{ID: "2", Name: "Blue Denim Jacket"},
+	))
+	assert.NoError(t, idx.Remove(context.TODO(), "1"))
+
+	reloaded := newTestIndex(t, path)
+	hits, _, err := reloaded.Search(context.TODO(), "blue", domain.ProductFilter{}, pkg.Pagination{})
+	assert.NoError(t, err)
+	assert.Len(t, hits, 1)
+	assert.Equal(t, "2", hits[0].ID)
+}
+
+func TestEmbeddedIndexSearchFilter(t *testing.T) {
+	idx := newTestIndex(t, filepath.Join(t.TempDir(), "index.jsonl"))
+
+	assert.NoError(t, idx.Index(context.TODO(),
+		domain.Products{ID: "1", Name: "Blue Cotton Shirt", Status: domain.ProductPublished, Tags: []string{"summer", "cotton"}},
+		domain.Products{ID: "2", Name: "Blue Linen Shirt", Status: domain.ProductDraft, Tags: []string{"summer"}},
+		domain.Products{ID: "3", Name: "Blue Denim Shirt", Status: domain.ProductArchived},
+	))
+
+	hits, nextPg, err := idx.Search(context.TODO(), "shirt", domain.ProductFilter{PublishedOnly: true}, pkg.Pagination{})
+	assert.NoError(t, err)
+	assert.Len(t, hits, 1)
+	assert.Equal(t, "1", hits[0].ID)
+	assert.Equal(t, int64(1), nextPg.TotalRows)
+
+	hits, _, err = idx.Search(context.TODO(), "shirt", domain.ProductFilter{Tags: []string{"summer"}}, pkg.Pagination{})
+	assert.NoError(t, err)
+	assert.Len(t, hits, 2)
+
+	hits, _, err = idx.Search(context.TODO(), "shirt", domain.ProductFilter{Tags: []string{"summer", "cotton"}, TagsMode: domain.TagsAll}, pkg.Pagination{})
+	assert.NoError(t, err)
+	assert.Len(t, hits, 1)
+	assert.Equal(t, "1", hits[0].ID)
+
+	hits, _, err = idx.Search(context.TODO(), "shirt", domain.ProductFilter{OnlyDeleted: true}, pkg.Pagination{})
+	assert.NoError(t, err)
+	assert.Len(t, hits, 0)
+
+	_, _, err = idx.Search(context.TODO(), "shirt", domain.ProductFilter{CategoryID: "c1"}, pkg.Pagination{})
+	assert.ErrorIs(t, err, domain.ErrBadParamInput)
+
+	_, _, err = idx.Search(context.TODO(), "shirt", domain.ProductFilter{}, pkg.Pagination{Limit: -1})
+	assert.ErrorIs(t, err, domain.ErrBadParamInput)
+}
+
+func TestEmbeddedIndexCompactsTheLog(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "index.jsonl")
+	idx := newTestIndex(t, path)
+
+	for i := 0; i < 3000; i++ {
+		assert.NoError(t, idx.Index(context.TODO(), domain.Products{ID: "1", Name: "Blue Cotton Shirt"}))
+	}
+
+	f, err := os.Open(path)
+	assert.NoError(t, err)
+	defer f.Close()
+	lines := 0
+	for s := bufio.NewScanner(f); s.Scan(); {
+		lines++
+	}
+	assert.Less(t, lines, 1100)
+
+	// a torn last line is dropped on reload
+	out, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0o644)
+	assert.NoError(t, err)
+	_, err = out.WriteString(`{"op":"index","product":{"id":"2"`)
+	assert.NoError(t, err)
+	assert.NoError(t, out.Close())
+
+	reloaded := newTestIndex(t, path)
+	hits, _, err := reloaded.Search(context.TODO(), "cotton", domain.ProductFilter{}, pkg.Pagination{})
+	assert.NoError(t, err)
+	assert.Len(t, hits, 1)
+	assert.Equal(t, "1", hits[0].ID)
+}
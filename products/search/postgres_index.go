@@ -0,0 +1,35 @@
+package search
+
+import (
+	"context"
+
+	"github.com/fahmilukis/go-product-svc/domain"
+	pkg "github.com/fahmilukis/go-product-svc/pkg/utils"
+)
+
+// postgresIndex answers searches with the full-text search of the product
+// repository. The search vector is a generated column, so writes need no
+// syncing.
+type postgresIndex struct {
+	productRepository domain.ProductRepository
+}
+
+func NewPostgresIndex(p domain.ProductRepository) domain.ProductSearchIndex {
+	return &postgresIndex{productRepository: p}
+}
+
+func (p *postgresIndex) Index(ctx context.Context, products ...domain.Products) error {
+	return nil
+}
+
+func (p *postgresIndex) Remove(ctx context.Context, id string) error {
+	return nil
+}
+
+func (p *postgresIndex) Reset(ctx context.Context) error {
+	return nil
+}
+
+func (p *postgresIndex) Search(ctx context.Context, query string, f domain.ProductFilter, pg pkg.Pagination) ([]domain.ProductSearchHit, pkg.Pagination, error) {
+	return p.productRepository.Search(ctx, query, f, pg)
+}
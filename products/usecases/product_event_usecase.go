@@ -0,0 +1,39 @@
+package usecases
+
+import (
+	"context"
+	"time"
+
+	"github.com/fahmilukis/go-product-svc/domain"
+)
+
+// eventBatchSize is the number of events published at a time.
+const eventBatchSize = 100
+
+type productEventUsecase struct {
+	eventRepository domain.ProductEventRepository
+	publisher       domain.EventPublisher
+	ctxTimeout      time.Duration
+}
+
+func NewProductEventUsecase(e domain.ProductEventRepository, pub domain.EventPublisher, to time.Duration) domain.ProductEventUsecase {
+	return &productEventUsecase{
+		eventRepository: e,
+		publisher:       pub,
+		ctxTimeout:      to,
+	}
+}
+
+// Relay publishes the events batch after batch until the outbox is empty, a
+// batch that fails stays pending and is published again on the next run.
+func (u *productEventUsecase) Relay(c context.Context) (total int, err error) {
+	for {
+		ctx, cancel := context.WithTimeout(c, u.ctxTimeout)
+		delivered, err := u.eventRepository.Relay(ctx, eventBatchSize, u.publisher.Publish)
+		cancel()
+		total += delivered
+		if err != nil || delivered < eventBatchSize {
+			return total, err
+		}
+	}
+}
@@ -0,0 +1,188 @@
+package usecases
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fahmilukis/go-product-svc/domain"
+)
+
+// exportBatchSize is the number of products an export fetches per round
+// trip.
+const exportBatchSize = 500
+
+// Export has no timeout of its own, the whole catalog may take long to be
+// written out. Each batch gets the usual timeout instead.
+func (p *productUsecase) Export(c context.Context, f domain.ProductFilter) (domain.ProductExport, error) {
+	export, err := p.productRepository.Export(c, f, exportBatchSize)
+	if err != nil {
+		return nil, err
+	}
+
+	return &timedExport{ProductExport: export, ctxTimeout: p.ctxTimeout}, nil
+}
+
+// timedExport bounds every batch read of an export.
+type timedExport struct {
+	domain.ProductExport
+	ctxTimeout time.Duration
+}
+
+func (e *timedExport) Next(c context.Context) ([]domain.Products, error) {
+	ctx, cancel := context.WithTimeout(c, e.ctxTimeout)
+	defer cancel()
+
+	return e.ProductExport.Next(ctx)
+}
+
+// WriteExport writes the products as they are read, a failure midway leaves
+// w cut short.
+func (p *productUsecase) WriteExport(c context.Context, w io.Writer, export domain.ProductExport, format string, fields []string) (rows int64, err error) {
+	var enc exportEncoder
+	switch format {
+	case domain.ProductExportCSV:
+		if len(fields) == 0 {
+			fields = domain.ProductExportFields
+		}
+		for _, field := range fields {
+			if _, ok := exportValues[field]; !ok {
+				return 0, domain.ErrBadParamInput
+			}
+		}
+		enc = &csvExportEncoder{w: csv.NewWriter(w), fields: fields}
+	case domain.ProductExportJSONL:
+		enc = &jsonlExportEncoder{enc: json.NewEncoder(w), fields: fields}
+	default:
+		return 0, domain.ErrBadParamInput
+	}
+
+	if err = enc.header(); err != nil {
+		return
+	}
+	for {
+		list, err := export.Next(c)
+		if err != nil {
+			return rows, err
+		}
+		if len(list) == 0 {
+			break
+		}
+		for _, prd := range list {
+			if err = enc.encode(prd); err != nil {
+				return rows, err
+			}
+			rows++
+		}
+	}
+
+	return rows, enc.flush()
+}
+
+// exportValues renders a product field as a CSV value. The times are RFC
+// 3339 and the tags comma separated, as an import reads them.
+var exportValues = map[string]func(prd domain.Products) string{
+	"id":              func(prd domain.Products) string { return prd.ID },
+	"name":            func(prd domain.Products) string { return prd.Name },
+	"slug":            func(prd domain.Products) string { return prd.Slug },
+	"desc":            func(prd domain.Products) string { return prd.Description },
+	"product_img_src": func(prd domain.Products) string { return prd.ImageSrc },
+	"status":          func(prd domain.Products) string { return string(prd.Status) },
+	"publish_at":      func(prd domain.Products) string { return exportTime(prd.PublishAt) },
+	"unpublish_at":    func(prd domain.Products) string { return exportTime(prd.UnpublishAt) },
+	"deleted_at":      func(prd domain.Products) string { return exportTime(prd.DeletedAt) },
+	"product_type":    func(prd domain.Products) string { return prd.ProductType },
+	"attributes":      exportAttributes,
+	"tags":            func(prd domain.Products) string { return strings.Join(prd.Tags, ",") },
+	"version":         func(prd domain.Products) string { return strconv.FormatInt(prd.Version, 10) },
+	"created_at":      func(prd domain.Products) string { return prd.CreatedAt.Format(time.RFC3339) },
+	"updated_at":      func(prd domain.Products) string { return prd.UpdatedAt.Format(time.RFC3339) },
+}
+
+func exportTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.Format(time.RFC3339)
+}
+
+func exportAttributes(prd domain.Products) string {
+	if len(prd.Attributes) == 0 {
+		return ""
+	}
+	raw, err := json.Marshal(prd.Attributes)
+	if err != nil {
+		return ""
+	}
+	return string(raw)
+}
+
+// exportEncoder writes the products of an export in one format.
+type exportEncoder interface {
+	header() error
+	encode(prd domain.Products) error
+	flush() error
+}
+
+type csvExportEncoder struct {
+	w      *csv.Writer
+	fields []string
+}
+
+func (e *csvExportEncoder) header() error {
+	return e.w.Write(e.fields)
+}
+
+func (e *csvExportEncoder) encode(prd domain.Products) error {
+	record := make([]string, len(e.fields))
+	for i, field := range e.fields {
+		record[i] = exportValues[field](prd)
+	}
+	return e.w.Write(record)
+}
+
+func (e *csvExportEncoder) flush() error {
+	e.w.Flush()
+	return e.w.Error()
+}
+
+// jsonlExportEncoder writes a product per line, trimmed down to fields when
+// set.
+type jsonlExportEncoder struct {
+	enc    *json.Encoder
+	fields []string
+}
+
+func (e *jsonlExportEncoder) header() error {
+	return nil
+}
+
+func (e *jsonlExportEncoder) encode(prd domain.Products) error {
+	if len(e.fields) == 0 {
+		return e.enc.Encode(prd)
+	}
+
+	raw, err := json.Marshal(prd)
+	if err != nil {
+		return err
+	}
+	all := map[string]json.RawMessage{}
+	if err = json.Unmarshal(raw, &all); err != nil {
+		return err
+	}
+	trimmed := make(map[string]json.RawMessage, len(e.fields))
+	for _, field := range e.fields {
+		if v, ok := all[field]; ok {
+			trimmed[field] = v
+		}
+	}
+	return e.enc.Encode(trimmed)
+}
+
+func (e *jsonlExportEncoder) flush() error {
+	return nil
+}
@@ -0,0 +1,236 @@
+package usecases
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/fahmilukis/go-product-svc/domain"
+	pkg "github.com/fahmilukis/go-product-svc/pkg/utils"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+// importBatchSize is the number of rows Import stores per transaction.
+const importBatchSize = 500
+
+// importRejectionLimit is the number of rejected rows an import keeps, the
+// result of a file full of bad rows would otherwise grow with the file.
+const importRejectionLimit = 1000
+
+// Import streams the CSV, only a batch of rows and the first rejections are
+// held at a time. A batch the database refuses is rejected as a whole with
+// the error as reason.
+func (p *productUsecase) Import(c context.Context, r io.Reader, mapping domain.ProductImportMapping, dryRun bool) (res domain.ProductImportResult, err error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return res, domain.ErrBadParamInput
+	}
+	columns, err := importColumns(header, mapping)
+	if err != nil {
+		return
+	}
+
+	res = domain.ProductImportResult{
+		DryRun:   dryRun,
+		Header:   header,
+		Rejected: make([]domain.ProductImportRejection, 0),
+	}
+	reject := func(line int, record []string, reasons ...string) {
+		res.RejectedTotal++
+		if len(res.Rejected) < importRejectionLimit {
+			res.Rejected = append(res.Rejected, domain.ProductImportRejection{Line: line, Reasons: reasons, Record: record})
+		}
+	}
+
+	// the definitions are read once per product type
+	defs := map[string][]domain.AttributeDefinition{}
+	batch := make([]*domain.Products, 0, importBatchSize)
+	lines := make([]int, 0, importBatchSize)
+	records := make([][]string, 0, importBatchSize)
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+		if err := p.storeImportBatch(c, batch); err != nil {
+			for i := range batch {
+				reject(lines[i], records[i], err.Error())
+			}
+		} else {
+			res.Imported += len(batch)
+		}
+		batch, lines, records = batch[:0], lines[:0], records[:0]
+	}
+
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			res.Total++
+			reject(parseErr.StartLine, record, parseErr.Err.Error())
+			continue
+		}
+		if err != nil {
+			return res, err
+		}
+		res.Total++
+		line, _ := reader.FieldPos(0)
+
+		prd, reasons, err := p.importRow(c, record, columns, defs)
+		if err != nil {
+			return res, err
+		}
+		if len(reasons) > 0 {
+			reject(line, record, reasons...)
+			continue
+		}
+		if dryRun {
+			res.Imported++
+			continue
+		}
+
+		batch, lines, records = append(batch, prd), append(lines, line), append(records, record)
+		if len(batch) == importBatchSize {
+			flush()
+		}
+	}
+	flush()
+
+	return res, nil
+}
+
+// importColumns resolves the column index of every importable field found
+// in the header. The name is the only column required.
+func importColumns(header []string, mapping domain.ProductImportMapping) (map[string]int, error) {
+	index := make(map[string]int, len(header))
+	for i, column := range header {
+		index[strings.TrimSpace(column)] = i
+	}
+
+	columns := map[string]int{}
+	for _, field := range domain.ProductImportFields {
+		column, mapped := mapping[field]
+		if !mapped {
+			column = field
+		}
+		i, ok := index[column]
+		if !ok {
+			if mapped {
+				return nil, domain.ErrBadParamInput
+			}
+			continue
+		}
+		columns[field] = i
+	}
+
+	for field := range mapping {
+		if _, ok := columns[field]; !ok {
+			return nil, domain.ErrBadParamInput
+		}
+	}
+	if _, ok := columns["name"]; !ok {
+		return nil, domain.ErrBadParamInput
+	}
+
+	return columns, nil
+}
+
+// importRow builds the product of a row the way Store would and returns the
+// reasons it can't be stored. err is only set when the row couldn't be
+// checked at all.
+func (p *productUsecase) importRow(c context.Context, record []string, columns map[string]int, defs map[string][]domain.AttributeDefinition) (prd *domain.Products, reasons []string, err error) {
+	get := func(field string) string {
+		if i, ok := columns[field]; ok {
+			return strings.TrimSpace(record[i])
+		}
+		return ""
+	}
+
+	now := time.Now()
+	prd = &domain.Products{
+		ID:          uuid.NewString(),
+		CreatedAt:   now,
+		UpdatedAt:   now,
+		Name:        get("name"),
+		Description: get("desc"),
+		ImageSrc:    get("product_img_src"),
+		Status:      domain.ProductStatus(strings.ToLower(get("status"))),
+		ProductType: get("product_type"),
+		Tags:        domain.NormalizeTags(pkg.ParseList(get("tags"))),
+	}
+	prd.Slug = productSlug(prd.Name)
+	if prd.Status == "" {
+		prd.Status = domain.ProductDraft
+	}
+
+	reasons = pkg.ValidateStruct(prd)
+	for field, target := range map[string]**time.Time{"publish_at": &prd.PublishAt, "unpublish_at": &prd.UnpublishAt} {
+		if raw := get(field); raw != "" {
+			t, err := time.Parse(time.RFC3339, raw)
+			if err != nil {
+				reasons = append(reasons, field+": must be an RFC 3339 time")
+				continue
+			}
+			*target = &t
+		}
+	}
+	if raw := get("attributes"); raw != "" {
+		if err := json.Unmarshal([]byte(raw), &prd.Attributes); err != nil {
+			reasons = append(reasons, "attributes: must be a JSON object")
+		}
+	}
+	if len(reasons) > 0 {
+		return prd, reasons, nil
+	}
+
+	if checkLifecycle(prd, now) != nil {
+		reasons = append(reasons, "status: unknown status or inconsistent schedule")
+	}
+
+	applicable, ok := defs[prd.ProductType]
+	if !ok {
+		ctx, cancel := context.WithTimeout(c, p.ctxTimeout)
+		applicable, err = p.attributeRepository.FetchApplicable(ctx, "", prd.ProductType)
+		cancel()
+		if err != nil {
+			return nil, nil, err
+		}
+		defs[prd.ProductType] = applicable
+	}
+	if domain.ValidateAttributes(applicable, prd.Attributes) != nil {
+		reasons = append(reasons, "attributes: don't match the attribute definitions")
+	}
+
+	return prd, reasons, nil
+}
+
+// storeImportBatch stores the batch together with the creation revisions
+// and indexes the batch at once.
+func (p *productUsecase) storeImportBatch(c context.Context, batch []*domain.Products) error {
+	ctx, cancel := context.WithTimeout(c, p.ctxTimeout)
+	defer cancel()
+
+	if err := p.productRepository.StoreBatch(ctx, batch); err != nil {
+		return err
+	}
+
+	stored := make([]domain.Products, 0, len(batch))
+	for _, prd := range batch {
+		stored = append(stored, *prd)
+	}
+	if err := p.searchIndex.Index(ctx, stored...); err != nil {
+		logrus.Errorf("failed to index %d imported products: %v", len(stored), err)
+	}
+
+	return nil
+}
@@ -0,0 +1,126 @@
+package usecases
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/fahmilukis/go-product-svc/domain"
+)
+
+// RegisterProductJobs registers the catalog operations that run as jobs.
+func RegisterProductJobs(jobs domain.JobUsecase, p domain.ProductUsecase) {
+	jobs.Register(domain.JobProductImport, func(ctx context.Context, run domain.JobRun) (interface{}, error) {
+		req := domain.ProductImportRequest{}
+		if err := json.Unmarshal(run.Payload, &req); err != nil {
+			return nil, domain.ErrBadParamInput
+		}
+
+		input, size, err := run.OpenInput()
+		if err != nil {
+			return nil, err
+		}
+		defer input.Close()
+
+		// the progress is the share of the CSV read
+		r := &progressReader{r: input, total: size, progress: run.Progress}
+		res, err := p.Import(ctx, r, req.Mapping, req.DryRun)
+		if err != nil && !req.DryRun && !errors.Is(err, domain.ErrBadParamInput) {
+			// the batches stored before the error would be imported twice
+			return nil, fmt.Errorf("%w: %v", domain.ErrJobFatal, err)
+		}
+		return res, err
+	})
+
+	jobs.Register(domain.JobProductExport, func(ctx context.Context, run domain.JobRun) (interface{}, error) {
+		req := domain.ProductExportRequest{}
+		if err := json.Unmarshal(run.Payload, &req); err != nil {
+			return nil, domain.ErrBadParamInput
+		}
+		return exportJob(ctx, p, run, req)
+	})
+
+	jobs.Register(domain.JobProductReindex, func(ctx context.Context, run domain.JobRun) (interface{}, error) {
+		total, err := p.Reindex(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return map[string]int{"total": total}, nil
+	})
+}
+
+// exportJob writes the export to the output of the job, the progress is the
+// number of products written as the total isn't known.
+func exportJob(ctx context.Context, p domain.ProductUsecase, run domain.JobRun, req domain.ProductExportRequest) (res domain.ProductExportResult, err error) {
+	export, err := p.Export(ctx, req.Filter)
+	if err != nil {
+		return res, err
+	}
+	defer export.Close()
+
+	res = domain.ProductExportResult{Format: req.Format, File: "products." + req.Format}
+	if req.Gzip {
+		res.File += ".gz"
+	}
+	out, err := run.CreateOutput(res.File)
+	if err != nil {
+		return res, err
+	}
+	defer out.Close()
+
+	var w io.Writer = out
+	var zw *gzip.Writer
+	if req.Gzip {
+		zw = gzip.NewWriter(out)
+		w = zw
+	}
+	res.Rows, err = p.WriteExport(ctx, w, &progressExport{ProductExport: export, progress: run.Progress}, req.Format, req.Filter.Fields)
+	if err != nil {
+		return res, err
+	}
+	if zw != nil {
+		if err = zw.Close(); err != nil {
+			return res, err
+		}
+	}
+
+	return res, out.Close()
+}
+
+// progressReader reports the bytes read so far, its reads fail once the
+// progress does.
+type progressReader struct {
+	r        io.Reader
+	read     int64
+	total    int64
+	progress func(done, total int64) error
+}
+
+func (r *progressReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	r.read += int64(n)
+	if errProgress := r.progress(r.read, r.total); errProgress != nil {
+		return n, errProgress
+	}
+	return n, err
+}
+
+// progressExport reports the products read so far, the export stops once
+// the progress fails.
+type progressExport struct {
+	domain.ProductExport
+	read     int64
+	progress func(done, total int64) error
+}
+
+func (e *progressExport) Next(ctx context.Context) ([]domain.Products, error) {
+	if err := e.progress(e.read, 0); err != nil {
+		return nil, err
+	}
+	list, err := e.ProductExport.Next(ctx)
+	e.read += int64(len(list))
+	return list, err
+}
@@ -0,0 +1,106 @@
+package usecases
+
+import (
+	"context"
+	"time"
+
+	"github.com/fahmilukis/go-product-svc/domain"
+	pkg "github.com/fahmilukis/go-product-svc/pkg/utils"
+)
+
+type productRevisionUsecase struct {
+	productRepository   domain.ProductRepository
+	revisionRepository  domain.ProductRevisionRepository
+	searchIndex         domain.ProductSearchIndex
+	attributeRepository domain.AttributeRepository
+	ctxTimeout          time.Duration
+}
+
+func NewProductRevisionUsecase(p domain.ProductRepository, r domain.ProductRevisionRepository, idx domain.ProductSearchIndex, a domain.AttributeRepository, to time.Duration) domain.ProductRevisionUsecase {
+	return &productRevisionUsecase{
+		productRepository:   p,
+		revisionRepository:  r,
+		searchIndex:         idx,
+		attributeRepository: a,
+		ctxTimeout:          to,
+	}
+}
+
+func (p *productRevisionUsecase) Fetch(c context.Context, productID string, pg pkg.Pagination) (res []domain.ProductRevision, nextPg pkg.Pagination, err error) {
+	ctx, cancel := context.WithTimeout(c, p.ctxTimeout)
+	defer cancel()
+
+	res, nextPg, err = p.revisionRepository.Fetch(ctx, productID, pg)
+	if err != nil {
+		return nil, pkg.Pagination{}, err
+	}
+	if len(res) == 0 {
+		return res, pkg.Pagination{}, domain.ErrNotFound
+	}
+
+	return
+}
+
+func (p *productRevisionUsecase) GetByRevision(c context.Context, productID string, rev int64) (res domain.ProductRevision, err error) {
+	ctx, cancel := context.WithTimeout(c, p.ctxTimeout)
+	defer cancel()
+
+	return p.revisionRepository.GetByRevision(ctx, productID, rev)
+}
+
+func (p *productRevisionUsecase) Diff(c context.Context, productID string, from, to int64) (res []domain.FieldChange, err error) {
+	ctx, cancel := context.WithTimeout(c, p.ctxTimeout)
+	defer cancel()
+
+	fromRev, err := p.revisionRepository.GetByRevision(ctx, productID, from)
+	if err != nil {
+		return nil, err
+	}
+	toRev, err := p.revisionRepository.GetByRevision(ctx, productID, to)
+	if err != nil {
+		return nil, err
+	}
+
+	return domain.DiffProducts(fromRev.Snapshot, toRev.Snapshot)
+}
+
+// Revert writes the snapshot of a prior revision back as the current state,
+// which is itself recorded as a new revision. The snapshot is validated like
+// an Update, it may predate the attribute definitions or the schedule that
+// apply now.
+func (p *productRevisionUsecase) Revert(c context.Context, productID string, rev int64, version int64) (res domain.Products, err error) {
+	ctx, cancel := context.WithTimeout(c, p.ctxTimeout)
+	defer cancel()
+
+	target, err := p.revisionRepository.GetByRevision(ctx, productID, rev)
+	if err != nil {
+		return domain.Products{}, err
+	}
+	prev, err := p.productRepository.GetByID(ctx, productID, domain.ProductFilter{})
+	if err != nil {
+		return domain.Products{}, err
+	}
+
+	res = target.Snapshot
+	res.ID = productID
+	res.CreatedAt = prev.CreatedAt
+	res.UpdatedAt = time.Now()
+	res.Version = version
+	// the snapshots made before the lifecycle existed have no status
+	if res.Status == "" {
+		res.Status, res.PublishAt, res.UnpublishAt = prev.Status, prev.PublishAt, prev.UnpublishAt
+	}
+	if err = checkLifecycle(&res, res.UpdatedAt); err != nil {
+		return domain.Products{}, err
+	}
+	res.Tags = domain.NormalizeTags(res.Tags)
+	if err = checkAttributes(ctx, p.attributeRepository, productID, &res); err != nil {
+		return domain.Products{}, err
+	}
+	if err = p.productRepository.Update(ctx, &res, domain.RevisionRevert); err != nil {
+		return domain.Products{}, err
+	}
+	syncSearchIndex(ctx, p.searchIndex, res)
+
+	return
+}
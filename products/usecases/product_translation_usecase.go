@@ -0,0 +1,165 @@
+package usecases
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/fahmilukis/go-product-svc/domain"
+	pkg "github.com/fahmilukis/go-product-svc/pkg/utils"
+)
+
+type productTranslationUsecase struct {
+	productRepository     domain.ProductRepository
+	translationRepository domain.ProductTranslationRepository
+	defaultLocale         string
+	// locales are the supported locales other than the default one
+	locales    []string
+	ctxTimeout time.Duration
+}
+
+// NewProductTranslationUsecase takes the locale the products are written in
+// and the locales they are sold in, the default locale among them or not.
+func NewProductTranslationUsecase(p domain.ProductRepository, t domain.ProductTranslationRepository, defaultLocale string, locales []string, to time.Duration) domain.ProductTranslationUsecase {
+	u := &productTranslationUsecase{
+		productRepository:     p,
+		translationRepository: t,
+		defaultLocale:         pkg.NormalizeLocale(defaultLocale),
+		locales:               make([]string, 0, len(locales)),
+		ctxTimeout:            to,
+	}
+	for _, l := range locales {
+		if l = pkg.NormalizeLocale(l); l != "" && l != u.defaultLocale && !u.supports(l) {
+			u.locales = append(u.locales, l)
+		}
+	}
+	return u
+}
+
+func (u *productTranslationUsecase) supports(locale string) bool {
+	for _, l := range u.locales {
+		if l == locale {
+			return true
+		}
+	}
+	return false
+}
+
+func (u *productTranslationUsecase) Fetch(c context.Context, productID string) (res []domain.ProductTranslation, err error) {
+	ctx, cancel := context.WithTimeout(c, u.ctxTimeout)
+	defer cancel()
+
+	return u.translationRepository.Fetch(ctx, productID)
+}
+
+// Upsert only takes the supported locales, the default locale is edited on
+// the product itself.
+func (u *productTranslationUsecase) Upsert(c context.Context, t *domain.ProductTranslation) (err error) {
+	ctx, cancel := context.WithTimeout(c, u.ctxTimeout)
+	defer cancel()
+
+	t.Locale = pkg.NormalizeLocale(t.Locale)
+	if !u.supports(t.Locale) {
+		return domain.ErrBadParamInput
+	}
+	t.Name, t.Description = strings.TrimSpace(t.Name), strings.TrimSpace(t.Description)
+	if t.Name == "" || len(t.Name) > 255 || len(t.Description) > 255 {
+		return domain.ErrBadParamInput
+	}
+	t.UpdatedAt = time.Now()
+
+	return u.translationRepository.Upsert(ctx, t)
+}
+
+func (u *productTranslationUsecase) Delete(c context.Context, productID, locale string) (err error) {
+	ctx, cancel := context.WithTimeout(c, u.ctxTimeout)
+	defer cancel()
+
+	return u.translationRepository.Delete(ctx, productID, pkg.NormalizeLocale(locale))
+}
+
+func (u *productTranslationUsecase) Missing(c context.Context, productID string) (res []string, err error) {
+	ctx, cancel := context.WithTimeout(c, u.ctxTimeout)
+	defer cancel()
+
+	if _, err = u.productRepository.GetByID(ctx, productID, domain.ProductFilter{}); err != nil {
+		return nil, err
+	}
+	list, err := u.translationRepository.Fetch(ctx, productID)
+	if err != nil {
+		return nil, err
+	}
+
+	have := make(map[string]bool, len(list))
+	for _, t := range list {
+		have[t.Locale] = true
+	}
+	res = make([]string, 0)
+	for _, l := range u.locales {
+		if !have[l] {
+			res = append(res, l)
+		}
+	}
+
+	return res, nil
+}
+
+func (u *productTranslationUsecase) Localize(c context.Context, prefs []string, products ...*domain.Products) (err error) {
+	ctx, cancel := context.WithTimeout(c, u.ctxTimeout)
+	defer cancel()
+
+	for _, prd := range products {
+		prd.Locale = u.defaultLocale
+	}
+	locales := u.candidates(prefs)
+	if len(locales) == 0 || len(products) == 0 {
+		return nil
+	}
+
+	ids := make([]string, 0, len(products))
+	for _, prd := range products {
+		ids = append(ids, prd.ID)
+	}
+	list, err := u.translationRepository.FetchFor(ctx, ids, locales)
+	if err != nil {
+		return err
+	}
+
+	translations := make(map[string]map[string]domain.ProductTranslation, len(products))
+	for _, t := range list {
+		if translations[t.ProductID] == nil {
+			translations[t.ProductID] = map[string]domain.ProductTranslation{}
+		}
+		translations[t.ProductID][t.Locale] = t
+	}
+	for _, prd := range products {
+		for _, l := range locales {
+			if t, ok := translations[prd.ID][l]; ok {
+				prd.Name, prd.Description, prd.Locale = t.Name, t.Description, t.Locale
+				break
+			}
+		}
+	}
+
+	return nil
+}
+
+// candidates lists the locales worth a translation lookup, the most wanted
+// first. A preference falls back to its parent locales before the next one
+// is tried, and the lookup stops at the default locale.
+func (u *productTranslationUsecase) candidates(prefs []string) []string {
+	res := make([]string, 0)
+	seen := map[string]bool{}
+	for _, pref := range prefs {
+		for _, l := range pkg.LocaleFallbacks(pref) {
+			if l == u.defaultLocale {
+				return res
+			}
+			if !seen[l] {
+				seen[l] = true
+				res = append(res, l)
+			}
+		}
+	}
+	return res
+}
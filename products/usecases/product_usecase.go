@@ -2,29 +2,49 @@ package usecases
 
 import (
 	"context"
+	"fmt"
+	"strings"
 	"time"
 
 	"github.com/fahmilukis/go-product-svc/domain"
 	pkg "github.com/fahmilukis/go-product-svc/pkg/utils"
+	"github.com/sirupsen/logrus"
 )
 
+// reindexBatchSize is the number of products loaded per page when the
+// search index is rebuilt.
+const reindexBatchSize = 500
+
+// scheduleBatchSize is the number of due products moved per scheduler run,
+// the rest waits for the next run.
+const scheduleBatchSize = 100
+
+// schedulerActor is the actor recorded on the revisions of the scheduler.
+const schedulerActor = "scheduler"
+
 type productUsecase struct {
-	productRepository domain.ProductRepository
-	ctxTimeout        time.Duration
+	productRepository   domain.ProductRepository
+	searchIndex         domain.ProductSearchIndex
+	attributeRepository domain.AttributeRepository
+	imageStore          domain.ProductImageStore
+	ctxTimeout          time.Duration
 }
 
-func NewProductUsecase(p domain.ProductRepository, to time.Duration) domain.ProductUsecase {
+func NewProductUsecase(p domain.ProductRepository, idx domain.ProductSearchIndex, a domain.AttributeRepository, img domain.ProductImageStore, to time.Duration) domain.ProductUsecase {
 	return &productUsecase{
-		productRepository: p,
-		ctxTimeout:        to,
+		productRepository:   p,
+		searchIndex:         idx,
+		attributeRepository: a,
+		imageStore:          img,
+		ctxTimeout:          to,
 	}
 }
 
-func (p *productUsecase) Fetch(c context.Context, pg pkg.Pagination) (res []domain.Products, nextPkg pkg.Pagination, err error) {
+func (p *productUsecase) Fetch(c context.Context, f domain.ProductFilter, pg pkg.Pagination) (res []domain.Products, nextPkg pkg.Pagination, err error) {
 	ctx, cancel := context.WithTimeout(c, p.ctxTimeout)
 	defer cancel()
 
-	res, nextPkg, err = p.productRepository.Fetch(ctx, pg)
+	res, nextPkg, err = p.productRepository.Fetch(ctx, f, pg)
 	if err != nil {
 		return nil, pkg.Pagination{}, err
 	}
@@ -35,11 +55,11 @@ func (p *productUsecase) Fetch(c context.Context, pg pkg.Pagination) (res []doma
 	return
 }
 
-func (p *productUsecase) GetByID(c context.Context, id string) (res domain.Products, err error) {
+func (p *productUsecase) GetByID(c context.Context, id string, f domain.ProductFilter) (res domain.Products, err error) {
 	ctx, cancel := context.WithTimeout(c, p.ctxTimeout)
 	defer cancel()
 
-	res, err = p.productRepository.GetByID(ctx, id)
+	res, err = p.productRepository.GetByID(ctx, id, f)
 	if err != nil {
 		return domain.Products{}, err
 	}
@@ -47,11 +67,11 @@ func (p *productUsecase) GetByID(c context.Context, id string) (res domain.Produ
 	return
 }
 
-func (p *productUsecase) GetByName(c context.Context, name string) (res domain.Products, err error) {
+func (p *productUsecase) GetByName(c context.Context, name string, f domain.ProductFilter) (res domain.Products, err error) {
 	ctx, cancel := context.WithTimeout(c, p.ctxTimeout)
 	defer cancel()
 
-	res, err = p.productRepository.GetByName(ctx, name)
+	res, err = p.productRepository.GetByName(ctx, name, f)
 	if err != nil {
 		return domain.Products{}, err
 	}
@@ -59,6 +79,67 @@ func (p *productUsecase) GetByName(c context.Context, name string) (res domain.P
 	return
 }
 
+func (p *productUsecase) GetBySlug(c context.Context, slug string, f domain.ProductFilter) (res domain.Products, err error) {
+	ctx, cancel := context.WithTimeout(c, p.ctxTimeout)
+	defer cancel()
+
+	res, err = p.productRepository.GetBySlug(ctx, strings.ToLower(slug), f)
+	if err != nil {
+		return domain.Products{}, err
+	}
+
+	return
+}
+
+func (p *productUsecase) Search(c context.Context, query string, f domain.ProductFilter, pg pkg.Pagination) (res []domain.ProductSearchHit, nextPg pkg.Pagination, err error) {
+	ctx, cancel := context.WithTimeout(c, p.ctxTimeout)
+	defer cancel()
+
+	if strings.TrimSpace(query) == "" {
+		return nil, pkg.Pagination{}, domain.ErrBadParamInput
+	}
+
+	res, nextPg, err = p.searchIndex.Search(ctx, query, f, pg)
+	if err != nil {
+		return nil, pkg.Pagination{}, err
+	}
+	if len(res) == 0 {
+		return res, pkg.Pagination{}, domain.ErrNotFound
+	}
+
+	return
+}
+
+// Reindex rebuilds the search index from every live product.
+func (p *productUsecase) Reindex(c context.Context) (total int, err error) {
+	if err = p.searchIndex.Reset(c); err != nil {
+		return
+	}
+
+	pg := pkg.Pagination{Limit: reindexBatchSize, Page: 1, Count: pkg.CountNone}
+	for {
+		ctx, cancel := context.WithTimeout(c, p.ctxTimeout)
+		list, _, err := p.productRepository.Fetch(ctx, domain.ProductFilter{}, pg)
+		cancel()
+		if err != nil {
+			return total, err
+		}
+		if len(list) == 0 {
+			return total, nil
+		}
+
+		if err = p.searchIndex.Index(c, list...); err != nil {
+			return total, err
+		}
+		total += len(list)
+
+		if len(list) < pg.Limit {
+			return total, nil
+		}
+		pg.Page++
+	}
+}
+
 func (p *productUsecase) Store(c context.Context, a *domain.Products) (err error) {
 	ctx, cancel := context.WithTimeout(c, p.ctxTimeout)
 	defer cancel()
@@ -66,22 +147,261 @@ func (p *productUsecase) Store(c context.Context, a *domain.Products) (err error
 	now := time.Now()
 	a.CreatedAt = now
 	a.UpdatedAt = now
+	if a.Status == "" {
+		a.Status = domain.ProductDraft
+	}
+	a.Tags = domain.NormalizeTags(a.Tags)
+	a.Slug = productSlug(a.Name)
+	if err = checkProduct(a); err != nil {
+		return
+	}
+	if err = checkLifecycle(a, now); err != nil {
+		return
+	}
+	// a new product has no category yet, only its product type applies
+	if err = checkAttributes(ctx, p.attributeRepository, "", a); err != nil {
+		return
+	}
+
+	if err = p.productRepository.Store(ctx, a); err != nil {
+		return
+	}
+	syncSearchIndex(ctx, p.searchIndex, *a)
 
-	return p.productRepository.Store(ctx, a)
+	return
 }
 
 func (p *productUsecase) Update(c context.Context, a *domain.Products) (err error) {
 	ctx, cancel := context.WithTimeout(c, p.ctxTimeout)
 	defer cancel()
 
+	prev, err := p.productRepository.GetByID(ctx, a.ID, domain.ProductFilter{})
+	if err != nil {
+		return
+	}
+
 	a.UpdatedAt = time.Now()
+	if err = checkProduct(a); err != nil {
+		return
+	}
+	// a write without status leaves the lifecycle alone
+	if a.Status == "" {
+		a.Status, a.PublishAt, a.UnpublishAt = prev.Status, prev.PublishAt, prev.UnpublishAt
+	}
+	if err = checkLifecycle(a, a.UpdatedAt); err != nil {
+		return
+	}
+	// a write without attributes or tags leaves them alone
+	if a.Attributes == nil {
+		a.Attributes = prev.Attributes
+	}
+	if a.Tags == nil {
+		a.Tags = prev.Tags
+	}
+	a.Tags = domain.NormalizeTags(a.Tags)
+	// the slug follows the name, an unchanged name keeps the slug
+	a.Slug = ""
+	if a.Name != prev.Name {
+		a.Slug = productSlug(a.Name)
+	}
+	if err = checkAttributes(ctx, p.attributeRepository, a.ID, a); err != nil {
+		return
+	}
+	if err = p.productRepository.Update(ctx, a, domain.RevisionUpdate); err != nil {
+		return
+	}
+	syncSearchIndex(ctx, p.searchIndex, *a)
+
+	return
+}
+
+func (p *productUsecase) Delete(c context.Context, id string, version int64) (err error) {
+	ctx, cancel := context.WithTimeout(c, p.ctxTimeout)
+	defer cancel()
+
+	deleted, err := p.productRepository.Delete(ctx, id, version)
+	if err != nil {
+		return
+	}
+	syncSearchIndex(ctx, p.searchIndex, deleted)
+
+	return
+}
 
-	return p.productRepository.Store(ctx, a)
+func (p *productUsecase) Restore(c context.Context, id string) (err error) {
+	ctx, cancel := context.WithTimeout(c, p.ctxTimeout)
+	defer cancel()
+
+	restored, err := p.productRepository.Restore(ctx, id)
+	if err != nil {
+		return
+	}
+	syncSearchIndex(ctx, p.searchIndex, restored)
+
+	return
+}
+
+// PurgeDeleted permanently removes the products that stayed in the trash
+// longer than the retention, along with the images nothing links to any
+// more. An image that fails to go is only logged, the products are gone.
+func (p *productUsecase) PurgeDeleted(c context.Context, retention time.Duration) (purged int64, err error) {
+	ctx, cancel := context.WithTimeout(c, p.ctxTimeout)
+	defer cancel()
+
+	now := time.Now()
+	res, images, err := p.productRepository.PurgeDeleted(ctx, now.Add(-retention), now)
+	if err != nil {
+		return
+	}
+	for _, src := range images {
+		if err := p.imageStore.Remove(ctx, src); err != nil {
+			logrus.Errorf("failed to remove the image %s of a purged product: %v", src, err)
+		}
+	}
+
+	return int64(len(res)), nil
 }
 
-func (p *productUsecase) Delete(c context.Context, id string) (err error) {
+// RunSchedule moves the products whose publish or unpublish time passed. A
+// product edited meanwhile fails its version check and is picked up again
+// on the next run.
+func (p *productUsecase) RunSchedule(c context.Context) (moved int64, err error) {
 	ctx, cancel := context.WithTimeout(c, p.ctxTimeout)
 	defer cancel()
+	ctx = context.WithValue(ctx, domain.ActorContextKey, schedulerActor)
 
-	return p.productRepository.Delete(ctx, id)
+	now := time.Now()
+	due, err := p.productRepository.FetchDue(ctx, now, scheduleBatchSize)
+	if err != nil {
+		return
+	}
+
+	for _, prev := range due {
+		next, action := prev, domain.RevisionPublish
+		if prev.Status == domain.ProductScheduled {
+			next.Status = domain.ProductPublished
+		} else {
+			next.Status, action = domain.ProductArchived, domain.RevisionUnpublish
+		}
+		next.UpdatedAt = now
+
+		if err = p.productRepository.Update(ctx, &next, action); err != nil {
+			logrus.Errorf("failed to move product %s to %s: %v", prev.ID, next.Status, err)
+			continue
+		}
+		moved++
+		syncSearchIndex(ctx, p.searchIndex, next)
+	}
+
+	return moved, nil
+}
+
+func (p *productUsecase) FetchTags(c context.Context) (res []domain.ProductTag, err error) {
+	ctx, cancel := context.WithTimeout(c, p.ctxTimeout)
+	defer cancel()
+
+	return p.productRepository.FetchTags(ctx)
+}
+
+// RenameTags renames the tags and re-indexes the live products it changed.
+func (p *productUsecase) RenameTags(c context.Context, r domain.TagRename) (renamed int64, err error) {
+	ctx, cancel := context.WithTimeout(c, p.ctxTimeout)
+	defer cancel()
+
+	to := domain.NormalizeTags([]string{r.To})
+	if len(to) == 0 {
+		return 0, domain.ErrBadParamInput
+	}
+	r.To = to[0]
+
+	from := make([]string, 0, len(r.From))
+	for _, t := range domain.NormalizeTags(r.From) {
+		if t != r.To {
+			from = append(from, t)
+		}
+	}
+	if len(from) == 0 {
+		return 0, domain.ErrBadParamInput
+	}
+	r.From = from
+
+	changed, err := p.productRepository.RenameTags(ctx, r, time.Now())
+	if err != nil {
+		return
+	}
+	// the trashed products are not in the index
+	live := make([]domain.Products, 0, len(changed))
+	for _, prd := range changed {
+		if prd.DeletedAt == nil {
+			live = append(live, prd)
+		}
+	}
+	if len(live) > 0 {
+		if err := p.searchIndex.Index(ctx, live...); err != nil {
+			logrus.Errorf("failed to index %d products with renamed tags: %v", len(live), err)
+		}
+	}
+
+	return int64(len(changed)), nil
+}
+
+// productSlug derives the slug of a product from its name, the repository
+// makes it unique.
+func productSlug(name string) string {
+	if slug := pkg.Slugify(name); slug != "" {
+		return slug
+	}
+	return "product"
+}
+
+// checkProduct validates the fields of a product against their validate
+// tags, like the import does for every row.
+func checkProduct(a *domain.Products) error {
+	if reasons := pkg.ValidateStruct(a); len(reasons) > 0 {
+		return fmt.Errorf("%w: %s", domain.ErrBadParamInput, strings.Join(reasons, ", "))
+	}
+	return nil
+}
+
+// checkLifecycle validates the status of a product and its schedule. A
+// product published without a publish time is published as of now.
+func checkLifecycle(a *domain.Products, now time.Time) error {
+	if !a.Status.Valid() {
+		return domain.ErrBadParamInput
+	}
+	if a.Status == domain.ProductScheduled && a.PublishAt == nil {
+		return domain.ErrBadParamInput
+	}
+	if a.Status == domain.ProductPublished && a.PublishAt == nil {
+		a.PublishAt = &now
+	}
+	if a.PublishAt != nil && a.UnpublishAt != nil && !a.UnpublishAt.After(*a.PublishAt) {
+		return domain.ErrBadParamInput
+	}
+	return nil
+}
+
+// checkAttributes validates the attributes of a product against the
+// definitions of its product type and categories.
+func checkAttributes(ctx context.Context, attributes domain.AttributeRepository, productID string, a *domain.Products) error {
+	a.ProductType = strings.TrimSpace(a.ProductType)
+	defs, err := attributes.FetchApplicable(ctx, productID, a.ProductType)
+	if err != nil {
+		return err
+	}
+	return domain.ValidateAttributes(defs, a.Attributes)
+}
+
+// syncSearchIndex mirrors a written product into the search index. A failure
+// is only logged, the write already happened and Reindex can catch up.
+func syncSearchIndex(ctx context.Context, idx domain.ProductSearchIndex, prd domain.Products) {
+	var err error
+	if prd.DeletedAt != nil {
+		err = idx.Remove(ctx, prd.ID)
+	} else {
+		err = idx.Index(ctx, prd)
+	}
+	if err != nil {
+		logrus.Errorf("failed to sync product %s to the search index: %v", prd.ID, err)
+	}
 }
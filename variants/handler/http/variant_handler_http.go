@@ -0,0 +1,153 @@
+package handler
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/fahmilukis/go-product-svc/domain"
+	"github.com/gofiber/fiber/v2"
+)
+
+type VariantHandler struct {
+	VariantUC domain.VariantUsecase
+}
+
+func VariantRoute(a *fiber.App, vuc domain.VariantUsecase) {
+	handler := &VariantHandler{
+		VariantUC: vuc,
+	}
+
+	route := a.Group("/api/v1")
+
+	route.Get("/product/:id/variants", handler.GetListVariants)
+	route.Post("/product/:id/variants", handler.CreateVariant)
+	route.Post("/product/:id/variants/generate", handler.GenerateVariants)
+	route.Get("/product/:id/variants/:variant_id", handler.GetVariantDetail)
+	route.Put("/product/:id/variants/:variant_id", handler.UpdateVariant)
+	route.Delete("/product/:id/variants/:variant_id", handler.DeleteVariant)
+}
+
+func (h *VariantHandler) GetListVariants(c *fiber.Ctx) error {
+	data, err := h.VariantUC.Fetch(c.Context(), c.Params("id"))
+	if err != nil {
+		return c.Status(domain.StatusCode(err)).JSON(fiber.Map{
+			"status": false,
+			"msg":    err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"status": true,
+		"msg":    "success get variant lists",
+		"data":   data,
+	})
+}
+
+func (h *VariantHandler) CreateVariant(c *fiber.Ctx) error {
+	v := &domain.Variant{}
+	if err := c.BodyParser(v); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"status": false,
+			"msg":    err.Error(),
+		})
+	}
+	now := time.Now()
+
+	v.ProductID = c.Params("id")
+	v.CreatedAt = now
+	v.UpdatedAt = now
+
+	if err := h.VariantUC.Store(c.Context(), v); err != nil {
+		return c.Status(domain.StatusCode(err)).JSON(fiber.Map{
+			"status": false,
+			"msg":    err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"status": true,
+		"msg":    "success add variant",
+		"data":   v,
+	})
+}
+
+// GenerateVariants creates the missing variants for every combination of the
+// option axes in the body.
+func (h *VariantHandler) GenerateVariants(c *fiber.Ctx) error {
+	t := domain.VariantTemplate{}
+	if err := c.BodyParser(&t); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"status": false,
+			"msg":    err.Error(),
+		})
+	}
+
+	data, err := h.VariantUC.Generate(c.Context(), c.Params("id"), t)
+	if err != nil {
+		return c.Status(domain.StatusCode(err)).JSON(fiber.Map{
+			"status": false,
+			"msg":    err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"status": true,
+		"msg":    "success generate variants",
+		"data":   data,
+	})
+}
+
+func (h *VariantHandler) GetVariantDetail(c *fiber.Ctx) error {
+	data, err := h.VariantUC.GetByID(c.Context(), c.Params("id"), c.Params("variant_id"))
+	if err != nil {
+		return c.Status(domain.StatusCode(err)).JSON(fiber.Map{
+			"status": false,
+			"msg":    err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"status": true,
+		"msg":    "success get variant",
+		"data":   data,
+	})
+}
+
+func (h *VariantHandler) UpdateVariant(c *fiber.Ctx) error {
+	v := &domain.Variant{}
+	if err := c.BodyParser(v); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"status": false,
+			"msg":    err.Error(),
+		})
+	}
+	v.ProductID = c.Params("id")
+	v.ID = c.Params("variant_id")
+
+	if err := h.VariantUC.Update(c.Context(), v); err != nil {
+		return c.Status(domain.StatusCode(err)).JSON(fiber.Map{
+			"status": false,
+			"msg":    err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"status": true,
+		"msg":    "success update variant",
+		"data":   v,
+	})
+}
+
+func (h *VariantHandler) DeleteVariant(c *fiber.Ctx) error {
+	if err := h.VariantUC.Delete(c.Context(), c.Params("id"), c.Params("variant_id")); err != nil {
+		return c.Status(domain.StatusCode(err)).JSON(fiber.Map{
+			"status": false,
+			"msg":    err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"status": true,
+		"msg":    "success delete variant",
+	})
+}
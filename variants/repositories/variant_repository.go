@@ -0,0 +1,194 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+
+	"github.com/fahmilukis/go-product-svc/domain"
+	pkg "github.com/fahmilukis/go-product-svc/pkg/utils"
+	"github.com/sirupsen/logrus"
+)
+
+// the stock of a variant is what its stock levels still have available
+const variantColumns = `id,product_id,sku,options,price,img_src,
+	COALESCE((SELECT sum(s.on_hand - s.reserved) FROM stock_levels s WHERE s.variant_id = product_variants.id), 0),
+	created_at,updated_at`
+
+type variantDBRepositories struct {
+	Conn *sql.DB
+}
+
+func NewVariantDBRepository(conn *sql.DB) *variantDBRepositories {
+	return &variantDBRepositories{Conn: conn}
+}
+
+// fetch to DB
+func (p *variantDBRepositories) fetch(ctx context.Context, query string, args ...interface{}) (res []domain.Variant, err error) {
+	rows, err := p.Conn.QueryContext(ctx, query, args...)
+	if err != nil {
+		logrus.Error(err)
+		return nil, err
+	}
+
+	defer func() {
+		errRow := rows.Close()
+		if errRow != nil {
+			logrus.Error(err)
+		}
+	}()
+
+	res = make([]domain.Variant, 0)
+	for rows.Next() {
+		v := domain.Variant{}
+		var options []byte
+		err = rows.Scan(
+			&v.ID,
+			&v.ProductID,
+			&v.SKU,
+			&options,
+			&v.Price,
+			&v.ImageSrc,
+			&v.Stock,
+			&v.CreatedAt,
+			&v.UpdatedAt,
+		)
+		if err != nil {
+			logrus.Error(err)
+			return nil, err
+		}
+		if err = json.Unmarshal(options, &v.Options); err != nil {
+			logrus.Error(err)
+			return nil, err
+		}
+		res = append(res, v)
+	}
+
+	return res, nil
+}
+
+func (p *variantDBRepositories) Fetch(ctx context.Context, productID string) (res []domain.Variant, err error) {
+	query := `SELECT ` + variantColumns + ` FROM product_variants WHERE product_id=$1 ORDER BY created_at, sku`
+	return p.fetch(ctx, query, productID)
+}
+
+func (p *variantDBRepositories) GetByID(ctx context.Context, productID, id string) (res domain.Variant, err error) {
+	query := `SELECT ` + variantColumns + ` FROM product_variants WHERE product_id=$1 AND id=$2`
+	list, err := p.fetch(ctx, query, productID, id)
+	if err != nil {
+		return domain.Variant{}, err
+	}
+
+	if len(list) > 0 {
+		res = list[0]
+	} else {
+		return res, domain.ErrNotFound
+	}
+
+	return
+}
+
+// Store inserts the variants in one transaction, a SKU or a combination of
+// options that is already taken fails the whole batch with ErrConflict.
+func (p *variantDBRepositories) Store(ctx context.Context, variants ...*domain.Variant) (err error) {
+	tx, err := p.Conn.BeginTx(ctx, nil)
+	if err != nil {
+		return
+	}
+	defer func() {
+		if err != nil {
+			if errRollback := tx.Rollback(); errRollback != nil {
+				logrus.Error(errRollback)
+			}
+		}
+	}()
+
+	stmt, err := tx.PrepareContext(ctx, `INSERT INTO product_variants (product_id,sku,options,price,img_src,created_at,updated_at)
+	VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING id`)
+	if err != nil {
+		return
+	}
+	defer stmt.Close()
+
+	stockStmt, err := tx.PrepareContext(ctx, `INSERT INTO stock_levels (warehouse_id,product_id,variant_id,on_hand,updated_at)
+	SELECT id, $1, $2, $3, $4 FROM warehouses WHERE code = $5`)
+	if err != nil {
+		return
+	}
+	defer stockStmt.Close()
+
+	for _, v := range variants {
+		options, errJSON := json.Marshal(v.Options)
+		if errJSON != nil {
+			return errJSON
+		}
+
+		err = stmt.QueryRowContext(ctx, v.ProductID, v.SKU, options, v.Price, v.ImageSrc, v.CreatedAt, v.UpdatedAt).Scan(&v.ID)
+		if err != nil {
+			return variantWriteError(err)
+		}
+
+		// the initial stock opens the stock level of the variant in the
+		// default warehouse
+		if _, err = stockStmt.ExecContext(ctx, v.ProductID, v.ID, v.Stock, v.UpdatedAt, domain.DefaultWarehouseCode); err != nil {
+			return
+		}
+	}
+
+	return tx.Commit()
+}
+
+// Update leaves the stock alone, it changes through the inventory.
+func (p *variantDBRepositories) Update(ctx context.Context, v *domain.Variant) (err error) {
+	query := `UPDATE product_variants SET sku=$1 , options=$2 , price=$3 , img_src=$4 , updated_at=$5 WHERE product_id=$6 AND id=$7`
+
+	options, err := json.Marshal(v.Options)
+	if err != nil {
+		return
+	}
+
+	res, err := p.Conn.ExecContext(ctx, query, v.SKU, options, v.Price, v.ImageSrc, v.UpdatedAt, v.ProductID, v.ID)
+	if err != nil {
+		return variantWriteError(err)
+	}
+
+	rowsAfected, err := res.RowsAffected()
+	if err != nil {
+		return
+	}
+	if rowsAfected == 0 {
+		return domain.ErrNotFound
+	}
+
+	return
+}
+
+func (p *variantDBRepositories) Delete(ctx context.Context, productID, id string) (err error) {
+	res, err := p.Conn.ExecContext(ctx, `DELETE FROM product_variants WHERE product_id = $1 AND id = $2`, productID, id)
+	if err != nil {
+		return
+	}
+
+	rowsAfected, err := res.RowsAffected()
+	if err != nil {
+		return
+	}
+	if rowsAfected == 0 {
+		return domain.ErrNotFound
+	}
+
+	return
+}
+
+// variantWriteError maps the constraint violations of a variant write to the
+// domain errors.
+func variantWriteError(err error) error {
+	switch {
+	case pkg.IsUniqueViolation(err):
+		return domain.ErrConflict
+	case pkg.IsForeignKeyViolation(err):
+		return domain.ErrNotFound
+	default:
+		return err
+	}
+}
@@ -0,0 +1,96 @@
+package repositories_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/fahmilukis/go-product-svc/domain"
+	"github.com/fahmilukis/go-product-svc/variants/repositories"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/DATA-DOG/go-sqlmock.v1"
+)
+
+func TestFetchRepositoryVariant(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	now := time.Now()
+
+	rows := sqlmock.NewRows([]string{"id", "product_id", "sku", "options", "price", "img_src", "stock", "created_at", "updated_at"}).
+		AddRow("v1", "p1", "TSHIRT-M-RED", []byte(`{"size":"M","color":"red"}`), 1500, "", 4, now, now)
+
+	mock.ExpectQuery(`FROM product_variants WHERE product_id=\$1`).WithArgs("p1").WillReturnRows(rows)
+
+	a := repositories.NewVariantDBRepository(db)
+	list, err := a.Fetch(context.TODO(), "p1")
+	assert.NoError(t, err)
+	assert.Len(t, list, 1)
+	assert.Equal(t, map[string]string{"size": "M", "color": "red"}, list[0].Options)
+	assert.Equal(t, int64(4), list[0].Stock)
+}
+
+func TestInsertRepositoryVariants(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	now := time.Now()
+	variants := []*domain.Variant{
+		{ProductID: "p1", SKU: "TSHIRT-S", Options: map[string]string{"size": "S"}, Price: 1500, CreatedAt: now, UpdatedAt: now},
+		{ProductID: "p1", SKU: "TSHIRT-M", Options: map[string]string{"size": "M"}, Price: 1500, CreatedAt: now, UpdatedAt: now},
+	}
+
+	mock.ExpectBegin()
+	prep := mock.ExpectPrepare(`INSERT INTO product_variants`)
+	stockPrep := mock.ExpectPrepare(`INSERT INTO stock_levels`)
+	prep.ExpectQuery().WithArgs("p1", "TSHIRT-S", []byte(`{"size":"S"}`), int64(1500), "", now, now).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow("v1"))
+	stockPrep.ExpectExec().WithArgs("p1", "v1", int64(0), now, domain.DefaultWarehouseCode).WillReturnResult(sqlmock.NewResult(0, 1))
+	prep.ExpectQuery().WithArgs("p1", "TSHIRT-M", []byte(`{"size":"M"}`), int64(1500), "", now, now).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow("v2"))
+	stockPrep.ExpectExec().WithArgs("p1", "v2", int64(0), now, domain.DefaultWarehouseCode).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	a := repositories.NewVariantDBRepository(db)
+	err = a.Store(context.TODO(), variants...)
+	assert.NoError(t, err)
+	assert.Equal(t, "v1", variants[0].ID)
+	assert.Equal(t, "v2", variants[1].ID)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestInsertRepositoryVariantDuplicateSKU(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	v := &domain.Variant{ProductID: "p1", SKU: "TSHIRT-S", Options: map[string]string{"size": "S"}}
+
+	mock.ExpectBegin()
+	prep := mock.ExpectPrepare(`INSERT INTO product_variants`)
+	mock.ExpectPrepare(`INSERT INTO stock_levels`)
+	prep.ExpectQuery().WillReturnError(&pq.Error{Code: "23505", Constraint: "product_variants_sku_key"})
+	mock.ExpectRollback()
+
+	a := repositories.NewVariantDBRepository(db)
+	err = a.Store(context.TODO(), v)
+	assert.Equal(t, domain.ErrConflict, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUpdateRepositoryVariantNotFound(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	v := &domain.Variant{ID: "missing", ProductID: "p1", SKU: "TSHIRT-S", Options: map[string]string{}}
+
+	mock.ExpectExec(`UPDATE product_variants SET sku=\$1`).WillReturnResult(sqlmock.NewResult(0, 0))
+
+	a := repositories.NewVariantDBRepository(db)
+	err = a.Update(context.TODO(), v)
+	assert.Equal(t, domain.ErrNotFound, err)
+}
@@ -0,0 +1,223 @@
+package usecases
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/fahmilukis/go-product-svc/domain"
+)
+
+// maxGeneratedVariants caps the combinations a single Generate call may
+// create, a few axes with many values grow quickly.
+const maxGeneratedVariants = 1000
+
+type variantUsecase struct {
+	productRepository domain.ProductRepository
+	variantRepository domain.VariantRepository
+	ctxTimeout        time.Duration
+}
+
+func NewVariantUsecase(p domain.ProductRepository, v domain.VariantRepository, to time.Duration) domain.VariantUsecase {
+	return &variantUsecase{
+		productRepository: p,
+		variantRepository: v,
+		ctxTimeout:        to,
+	}
+}
+
+func (u *variantUsecase) Fetch(c context.Context, productID string) (res []domain.Variant, err error) {
+	ctx, cancel := context.WithTimeout(c, u.ctxTimeout)
+	defer cancel()
+
+	if _, err = u.productRepository.GetByID(ctx, productID, domain.ProductFilter{}); err != nil {
+		return nil, err
+	}
+
+	return u.variantRepository.Fetch(ctx, productID)
+}
+
+func (u *variantUsecase) GetByID(c context.Context, productID, id string) (res domain.Variant, err error) {
+	ctx, cancel := context.WithTimeout(c, u.ctxTimeout)
+	defer cancel()
+
+	return u.variantRepository.GetByID(ctx, productID, id)
+}
+
+func (u *variantUsecase) Store(c context.Context, v *domain.Variant) (err error) {
+	ctx, cancel := context.WithTimeout(c, u.ctxTimeout)
+	defer cancel()
+
+	if err = validateVariant(v); err != nil {
+		return
+	}
+	if _, err = u.productRepository.GetByID(ctx, v.ProductID, domain.ProductFilter{}); err != nil {
+		return
+	}
+
+	return u.variantRepository.Store(ctx, v)
+}
+
+func (u *variantUsecase) Generate(c context.Context, productID string, t domain.VariantTemplate) (res []domain.Variant, err error) {
+	ctx, cancel := context.WithTimeout(c, u.ctxTimeout)
+	defer cancel()
+
+	if strings.TrimSpace(t.SKUPrefix) == "" || t.Price < 0 || t.Stock < 0 {
+		return nil, domain.ErrBadParamInput
+	}
+	combinations, err := cartesian(t.Axes)
+	if err != nil {
+		return nil, err
+	}
+	if _, err = u.productRepository.GetByID(ctx, productID, domain.ProductFilter{}); err != nil {
+		return nil, err
+	}
+
+	existing, err := u.variantRepository.Fetch(ctx, productID)
+	if err != nil {
+		return nil, err
+	}
+	taken := map[string]bool{}
+	for _, v := range existing {
+		taken[optionsKey(v.Options)] = true
+	}
+
+	now := time.Now()
+	created := make([]*domain.Variant, 0, len(combinations))
+	for _, options := range combinations {
+		if taken[optionsKey(options)] {
+			continue
+		}
+		created = append(created, &domain.Variant{
+			ProductID: productID,
+			SKU:       generateSKU(t.SKUPrefix, t.Axes, options),
+			Options:   options,
+			Price:     t.Price,
+			ImageSrc:  t.ImageSrc,
+			Stock:     t.Stock,
+			CreatedAt: now,
+			UpdatedAt: now,
+		})
+	}
+
+	res = make([]domain.Variant, 0, len(created))
+	if len(created) == 0 {
+		return res, nil
+	}
+	if err = u.variantRepository.Store(ctx, created...); err != nil {
+		return nil, err
+	}
+	for _, v := range created {
+		res = append(res, *v)
+	}
+
+	return res, nil
+}
+
+func (u *variantUsecase) Update(c context.Context, v *domain.Variant) (err error) {
+	ctx, cancel := context.WithTimeout(c, u.ctxTimeout)
+	defer cancel()
+
+	if err = validateVariant(v); err != nil {
+		return
+	}
+	v.UpdatedAt = time.Now()
+
+	return u.variantRepository.Update(ctx, v)
+}
+
+func (u *variantUsecase) Delete(c context.Context, productID, id string) (err error) {
+	ctx, cancel := context.WithTimeout(c, u.ctxTimeout)
+	defer cancel()
+
+	return u.variantRepository.Delete(ctx, productID, id)
+}
+
+func validateVariant(v *domain.Variant) error {
+	v.SKU = strings.TrimSpace(v.SKU)
+	if v.SKU == "" || v.Price < 0 || v.Stock < 0 {
+		return domain.ErrBadParamInput
+	}
+	if v.Options == nil {
+		v.Options = map[string]string{}
+	}
+	return nil
+}
+
+// cartesian expands the axes into every combination of their values.
+func cartesian(axes []domain.OptionAxis) ([]map[string]string, error) {
+	if len(axes) == 0 {
+		return nil, domain.ErrBadParamInput
+	}
+
+	total := 1
+	names := map[string]bool{}
+	for _, axis := range axes {
+		if axis.Name == "" || names[axis.Name] || len(axis.Values) == 0 || hasDuplicates(axis.Values) {
+			return nil, domain.ErrBadParamInput
+		}
+		names[axis.Name] = true
+		total *= len(axis.Values)
+		if total > maxGeneratedVariants {
+			return nil, domain.ErrBadParamInput
+		}
+	}
+
+	res := []map[string]string{{}}
+	for _, axis := range axes {
+		next := make([]map[string]string, 0, len(res)*len(axis.Values))
+		for _, partial := range res {
+			for _, value := range axis.Values {
+				options := make(map[string]string, len(partial)+1)
+				for k, v := range partial {
+					options[k] = v
+				}
+				options[axis.Name] = value
+				next = append(next, options)
+			}
+		}
+		res = next
+	}
+
+	return res, nil
+}
+
+func hasDuplicates(values []string) bool {
+	seen := map[string]bool{}
+	for _, v := range values {
+		if v == "" || seen[v] {
+			return true
+		}
+		seen[v] = true
+	}
+	return false
+}
+
+// optionsKey renders options in a canonical form so two equal maps compare
+// equal.
+func optionsKey(options map[string]string) string {
+	keys := make([]string, 0, len(options))
+	for k := range options {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var b strings.Builder
+	for _, k := range keys {
+		b.WriteString(k + "=" + options[k] + ";")
+	}
+	return b.String()
+}
+
+// generateSKU joins the prefix and the option values in axis order, e.g.
+// TSHIRT-M-RED.
+func generateSKU(prefix string, axes []domain.OptionAxis, options map[string]string) string {
+	parts := []string{prefix}
+	for _, axis := range axes {
+		parts = append(parts, options[axis.Name])
+	}
+
+	sku := strings.ToUpper(strings.Join(parts, "-"))
+	return strings.Join(strings.Fields(sku), "-")
+}